@@ -62,6 +62,26 @@ var producerListCmd = &cobra.Command{
 		fmt.Printf("%sAWS DynamoDB%s (--output = awsdynamodb)\n", Green, Reset)
 		fmt.Printf("%sWAMP Topic%s (--output = wamp)\n", Green, Reset)
 		fmt.Printf("%sWAMP RPC%s (--output = wamprpc)\n", Green, Reset)
+		fmt.Printf("%sFile%s (--output = file)\n", Green, Reset)
+		fmt.Printf("%sAvro File%s (--output = avro-file)\n", Green, Reset)
+		fmt.Printf("%sSQL%s (--output = sql)\n", Green, Reset)
+		fmt.Printf("%sSQL Script%s (--output = sql-script)\n", Green, Reset)
+		fmt.Printf("%sMQTT%s (--output = mqtt)\n", Green, Reset)
+		fmt.Printf("%sNATS%s (--output = nats)\n", Green, Reset)
+		fmt.Printf("%sRabbitMQ%s (--output = rabbitmq or amqp)\n", Green, Reset)
+		fmt.Printf("%sPulsar%s (--output = pulsar)\n", Green, Reset)
+		fmt.Printf("%sAWS Kinesis%s (--output = kinesis)\n", Green, Reset)
+		fmt.Printf("%sAWS Firehose%s (--output = firehose)\n", Green, Reset)
+		fmt.Printf("%sAWS SQS%s (--output = sqs)\n", Green, Reset)
+		fmt.Printf("%sAWS SNS%s (--output = sns)\n", Green, Reset)
+		fmt.Printf("%sGoogle Pub/Sub%s (--output = pubsub)\n", Green, Reset)
+		fmt.Printf("%sgRPC%s (--output = grpc)\n", Green, Reset)
+		fmt.Printf("%sWebSocket%s (--output = websocket)\n", Green, Reset)
+		fmt.Printf("%sSyslog%s (--output = syslog)\n", Green, Reset)
+		fmt.Printf("%sOpenTelemetry OTLP%s (--output = otlp)\n", Green, Reset)
+		fmt.Printf("%sClickHouse%s (--output = clickhouse)\n", Green, Reset)
+		fmt.Printf("%sInfluxDB%s (--output = influxdb)\n", Green, Reset)
+		fmt.Printf("%sPrometheus remote write%s (--output = prometheus)\n", Green, Reset)
 		fmt.Println()
 
 	},
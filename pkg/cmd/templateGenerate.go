@@ -0,0 +1,61 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/jrnd-io/jr/pkg/tpl"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var templateGenerateCmd = &cobra.Command{
+	Use:   "generate [schema]",
+	Short: "Generate a template from a JSON Schema",
+	Long: "Generate a template from a JSON Schema (draft-07 or 2020-12) file. Example usage:\n" +
+		"jr template generate person.schema.json > $JR_SYSTEM_DIR/templates/person.tpl",
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+
+		schema, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to ReadFile")
+		}
+
+		template, err := tpl.FromJSONSchema(schema)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to generate template from schema")
+		}
+
+		valid, err := isValidTemplate([]byte(template))
+		if !valid {
+			log.Fatal().Err(err).Msg("Generated template is not valid")
+		}
+
+		fmt.Print(template)
+	},
+}
+
+func init() {
+	templateCmd.AddCommand(templateGenerateCmd)
+}
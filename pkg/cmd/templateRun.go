@@ -62,6 +62,13 @@ jr template run --template "{{name}}"
 
 		csv, _ := cmd.Flags().GetString("csv")
 		geojson, _ := cmd.Flags().GetString("geojson")
+		protoFile, _ := cmd.Flags().GetString("protoFile")
+		protoMessage, _ := cmd.Flags().GetString("protoMessage")
+		schemaFile, _ := cmd.Flags().GetString("schemaFile")
+		validate, _ := cmd.Flags().GetString("validate")
+		validateSchema, _ := cmd.Flags().GetString("validateSchema")
+		onInvalid, _ := cmd.Flags().GetString("onInvalid")
+		dlq, _ := cmd.Flags().GetString("dlq")
 
 		if kcat {
 			oneline = true
@@ -130,6 +137,38 @@ jr template run --template "{{name}}"
 					configuration.GlobalCfg.WAMPConfig, _ = cmd.Flags().GetString(f.Name)
 				case "wampRpcConfig":
 					configuration.GlobalCfg.WAMPRPCConfig, _ = cmd.Flags().GetString(f.Name)
+				case "fileConfig":
+					configuration.GlobalCfg.FileConfig, _ = cmd.Flags().GetString(f.Name)
+				case "sqlConfig":
+					configuration.GlobalCfg.SQLConfig, _ = cmd.Flags().GetString(f.Name)
+				case "mqttConfig":
+					configuration.GlobalCfg.MQTTConfig, _ = cmd.Flags().GetString(f.Name)
+				case "natsConfig":
+					configuration.GlobalCfg.NATSConfig, _ = cmd.Flags().GetString(f.Name)
+				case "rabbitmqConfig":
+					configuration.GlobalCfg.RabbitMQConfig, _ = cmd.Flags().GetString(f.Name)
+				case "pulsarConfig":
+					configuration.GlobalCfg.PulsarConfig, _ = cmd.Flags().GetString(f.Name)
+				case "kinesisConfig":
+					configuration.GlobalCfg.KinesisConfig, _ = cmd.Flags().GetString(f.Name)
+				case "sqsConfig":
+					configuration.GlobalCfg.SQSConfig, _ = cmd.Flags().GetString(f.Name)
+				case "snsConfig":
+					configuration.GlobalCfg.SNSConfig, _ = cmd.Flags().GetString(f.Name)
+				case "pubsubConfig":
+					configuration.GlobalCfg.PubSubConfig, _ = cmd.Flags().GetString(f.Name)
+				case "grpcConfig":
+					configuration.GlobalCfg.GRPCConfig, _ = cmd.Flags().GetString(f.Name)
+				case "websocketConfig":
+					configuration.GlobalCfg.WebSocketConfig, _ = cmd.Flags().GetString(f.Name)
+				case "syslogConfig":
+					configuration.GlobalCfg.SyslogConfig, _ = cmd.Flags().GetString(f.Name)
+				case "otlpConfig":
+					configuration.GlobalCfg.OTLPConfig, _ = cmd.Flags().GetString(f.Name)
+				case "clickhouseConfig":
+					configuration.GlobalCfg.ClickHouseConfig, _ = cmd.Flags().GetString(f.Name)
+				case "tsdbConfig":
+					configuration.GlobalCfg.TSDBConfig, _ = cmd.Flags().GetString(f.Name)
 				case "autoRegisterSchemas":
 					configuration.GlobalCfg.AutoRegisterSchemas, _ = cmd.Flags().GetBool(f.Name)
 				}
@@ -153,6 +192,13 @@ jr template run --template "{{name}}"
 			Oneline:          oneline,
 			Csv:              csv,
 			GeoJson:          geojson,
+			ProtoFile:        protoFile,
+			ProtoMessage:     protoMessage,
+			SchemaFile:       schemaFile,
+			Validate:         validate,
+			ValidateSchema:   validateSchema,
+			OnInvalid:        onInvalid,
+			DLQ:              dlq,
 		}
 
 		functions.SetSeed(seed)
@@ -183,7 +229,7 @@ func init() {
 	templateRunCmd.Flags().StringP("topic", "t", constants.DEFAULT_TOPIC, "Kafka topic")
 
 	templateRunCmd.Flags().Bool("kcat", false, "If you want to pipe jr with kcat, use this flag: it is equivalent to --output stdout --outputTemplate '{{key}},{{value}}' --oneline")
-	templateRunCmd.Flags().StringP("output", "o", constants.DEFAULT_OUTPUT, "can be one of stdout, kafka, http, redis, mongo, elastic, s3, gcs, azblobstorage, azcosmosdb, cassandra, luascript, wasm, awsdynamodb")
+	templateRunCmd.Flags().StringP("output", "o", constants.DEFAULT_OUTPUT, "can be one of stdout, kafka, http, redis, mongo, elastic, s3, gcs, azblobstorage, azcosmosdb, cassandra, luascript, wasm, awsdynamodb, file, avro-file, sql, sql-script, mqtt, nats, rabbitmq, amqp, pulsar, kinesis, firehose, sqs, sns, pubsub, grpc, websocket, syslog, otlp, clickhouse, influxdb, prometheus")
 	templateRunCmd.Flags().String("outputTemplate", constants.DEFAULT_OUTPUT_TEMPLATE, "Formatting of K,V on standard output")
 	templateRunCmd.Flags().BoolP("oneline", "l", false, "strips /n from output, for example to be pipelined to tools like kcat")
 	templateRunCmd.Flags().BoolP("autocreate", "a", false, "if enabled, autocreate topics")
@@ -192,6 +238,13 @@ func init() {
 	templateRunCmd.Flags().BoolP("schemaRegistry", "s", false, "If you want to use Confluent Schema Registry")
 	templateRunCmd.Flags().String("serializer", "", "Type of serializer: json-schema, avro-generic, avro, protobuf")
 	templateRunCmd.Flags().Bool("autoRegisterSchemas", true, "Enable/disable auto-registration of schemas in Schema Registry")
+	templateRunCmd.Flags().String("protoFile", "", "If serializer is protobuf, path of the .proto file describing the template")
+	templateRunCmd.Flags().String("protoMessage", "", "If serializer is protobuf, name of the message in protoFile (defaults to the first one)")
+	templateRunCmd.Flags().String("schemaFile", "", "If serializer is avro, avro-generic or json-schema, path of the .avsc or JSON Schema file describing the template (defaults to the latest registered version). With avro-file output, the .avsc of the records")
+	templateRunCmd.Flags().String("validate", "", "Validate each value before producing it: json, avro, json-schema")
+	templateRunCmd.Flags().String("validateSchema", "", "Path of the .avsc or JSON Schema file used by --validate (defaults to --schemaFile)")
+	templateRunCmd.Flags().String("onInvalid", "drop", "What to do with invalid values: drop, dlq, fail")
+	templateRunCmd.Flags().String("dlq", "", "If onInvalid is dlq, path of the file where invalid values are appended")
 	templateRunCmd.Flags().Duration("redis.ttl", -1, "If output is redis, ttl of the object")
 	templateRunCmd.Flags().String("httpConfig", "", "HTTP configuration")
 	templateRunCmd.Flags().String("redisConfig", "", "Redis configuration")
@@ -207,5 +260,21 @@ func init() {
 	templateRunCmd.Flags().String("wasmConfig", "", "WASM configuration")
 	templateRunCmd.Flags().String("wampConfig", "", "WAMP configuration")
 	templateRunCmd.Flags().String("wampRpcConfig", "", "WAMP-RPC configuration")
+	templateRunCmd.Flags().String("fileConfig", "", "File configuration")
+	templateRunCmd.Flags().String("sqlConfig", "", "SQL configuration")
+	templateRunCmd.Flags().String("mqttConfig", "", "MQTT configuration")
+	templateRunCmd.Flags().String("natsConfig", "", "NATS configuration")
+	templateRunCmd.Flags().String("rabbitmqConfig", "", "RabbitMQ/AMQP configuration")
+	templateRunCmd.Flags().String("pulsarConfig", "", "Pulsar configuration")
+	templateRunCmd.Flags().String("kinesisConfig", "", "Kinesis and Firehose configuration")
+	templateRunCmd.Flags().String("sqsConfig", "", "AWS SQS configuration")
+	templateRunCmd.Flags().String("snsConfig", "", "AWS SNS configuration")
+	templateRunCmd.Flags().String("pubsubConfig", "", "Google Cloud Pub/Sub configuration")
+	templateRunCmd.Flags().String("grpcConfig", "", "gRPC configuration")
+	templateRunCmd.Flags().String("websocketConfig", "", "WebSocket configuration")
+	templateRunCmd.Flags().String("syslogConfig", "", "Syslog configuration")
+	templateRunCmd.Flags().String("otlpConfig", "", "OpenTelemetry OTLP configuration")
+	templateRunCmd.Flags().String("clickhouseConfig", "", "ClickHouse configuration")
+	templateRunCmd.Flags().String("tsdbConfig", "", "InfluxDB and Prometheus remote write configuration")
 
 }
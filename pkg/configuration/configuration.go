@@ -47,6 +47,22 @@ type GlobalConfiguration struct {
 	WASMConfig          string
 	WAMPConfig          string
 	WAMPRPCConfig       string
+	FileConfig          string
+	SQLConfig           string
+	MQTTConfig          string
+	NATSConfig          string
+	RabbitMQConfig      string
+	PulsarConfig        string
+	KinesisConfig       string
+	SQSConfig           string
+	SNSConfig           string
+	PubSubConfig        string
+	GRPCConfig          string
+	WebSocketConfig     string
+	SyslogConfig        string
+	OTLPConfig          string
+	ClickHouseConfig    string
+	TSDBConfig          string
 	Url                 string
 	EmbeddedTemplate    bool
 	FileNameTemplate    bool
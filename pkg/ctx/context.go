@@ -32,6 +32,7 @@ type Context struct {
 	StartTime                 time.Time
 	GeneratedObjects          int64
 	ExpectedObjects           int64
+	InvalidObjects            int64
 	GeneratedBytes            int64
 	Locale                    string
 	CtxCounters               map[string]int
@@ -36,19 +36,37 @@ import (
 	"github.com/jrnd-io/jr/pkg/producers/azblobstorage"
 	"github.com/jrnd-io/jr/pkg/producers/azcosmosdb"
 	"github.com/jrnd-io/jr/pkg/producers/cassandra"
+	"github.com/jrnd-io/jr/pkg/producers/clickhouse"
 	"github.com/jrnd-io/jr/pkg/producers/console"
 	"github.com/jrnd-io/jr/pkg/producers/elastic"
+	"github.com/jrnd-io/jr/pkg/producers/file"
+	"github.com/jrnd-io/jr/pkg/producers/format"
 	"github.com/jrnd-io/jr/pkg/producers/gcs"
+	"github.com/jrnd-io/jr/pkg/producers/grpc"
 	"github.com/jrnd-io/jr/pkg/producers/http"
 	"github.com/jrnd-io/jr/pkg/producers/kafka"
+	"github.com/jrnd-io/jr/pkg/producers/kinesis"
 	"github.com/jrnd-io/jr/pkg/producers/luascript"
 	"github.com/jrnd-io/jr/pkg/producers/mongodb"
+	"github.com/jrnd-io/jr/pkg/producers/mqtt"
+	"github.com/jrnd-io/jr/pkg/producers/nats"
+	"github.com/jrnd-io/jr/pkg/producers/otlp"
+	"github.com/jrnd-io/jr/pkg/producers/pubsub"
+	"github.com/jrnd-io/jr/pkg/producers/pulsar"
+	"github.com/jrnd-io/jr/pkg/producers/rabbitmq"
 	"github.com/jrnd-io/jr/pkg/producers/redis"
 	"github.com/jrnd-io/jr/pkg/producers/s3"
 	"github.com/jrnd-io/jr/pkg/producers/server"
+	"github.com/jrnd-io/jr/pkg/producers/sns"
+	"github.com/jrnd-io/jr/pkg/producers/sql"
+	"github.com/jrnd-io/jr/pkg/producers/sqs"
+	"github.com/jrnd-io/jr/pkg/producers/syslog"
+	"github.com/jrnd-io/jr/pkg/producers/tsdb"
 	"github.com/jrnd-io/jr/pkg/producers/wamp"
 	"github.com/jrnd-io/jr/pkg/producers/wamprpc"
+	"github.com/jrnd-io/jr/pkg/producers/websocket"
 	"github.com/jrnd-io/jr/pkg/tpl"
+	"github.com/jrnd-io/jr/pkg/types"
 	"github.com/rs/zerolog/log"
 )
 
@@ -69,9 +87,17 @@ type Emitter struct {
 	Oneline          bool          `mapstructure:"oneline"`
 	Csv              string        `mapstructure:"csv"`
 	GeoJson          string        `mapstructure:"geojson"`
+	ProtoFile        string        `mapstructure:"protoFile"`
+	ProtoMessage     string        `mapstructure:"protoMessage"`
+	SchemaFile       string        `mapstructure:"schemaFile"`
+	Validate         string        `mapstructure:"validate"`
+	ValidateSchema   string        `mapstructure:"validateSchema"`
+	OnInvalid        string        `mapstructure:"onInvalid"`
+	DLQ              string        `mapstructure:"dlq"`
 	Producer         Producer
 	KTpl             tpl.Tpl
 	VTpl             tpl.Tpl
+	validation       *validation
 }
 
 func (e *Emitter) Initialize(ctx context.Context, conf configuration.GlobalConfiguration) {
@@ -103,6 +129,8 @@ func (e *Emitter) Initialize(ctx context.Context, conf configuration.GlobalConfi
 	e.KTpl = keyTpl
 	e.VTpl = valueTpl
 
+	e.initializeValidation()
+
 	o, _ := tpl.NewTpl("out", e.OutputTemplate, functions.FunctionsMap(), nil)
 	if e.Output == "stdout" {
 		e.Producer = &console.Producer{OutputTpl: &o}
@@ -110,7 +138,7 @@ func (e *Emitter) Initialize(ctx context.Context, conf configuration.GlobalConfi
 	}
 
 	if e.Output == "kafka" {
-		e.Producer = createKafkaProducer(ctx, conf, e.Topic, templateName)
+		e.Producer = createKafkaProducer(ctx, conf, e, templateName)
 		return
 	}
 
@@ -188,6 +216,106 @@ func (e *Emitter) Initialize(ctx context.Context, conf configuration.GlobalConfi
 		return
 	}
 
+	if e.Output == "file" {
+		e.Producer = createFileProducer(ctx, conf.FileConfig)
+		return
+	}
+
+	if e.Output == "avro-file" {
+		e.Producer = createAvroFileProducer(ctx, conf.FileConfig, e.SchemaFile, templateName)
+		return
+	}
+
+	if e.Output == "sql" {
+		e.Producer = createSQLProducer(ctx, conf.SQLConfig)
+		return
+	}
+
+	if e.Output == "sql-script" {
+		e.Producer = createSQLScriptProducer(ctx, conf.SQLConfig, templateName)
+		return
+	}
+
+	if e.Output == "mqtt" {
+		e.Producer = createMQTTProducer(ctx, conf.MQTTConfig)
+		return
+	}
+
+	if e.Output == "nats" {
+		e.Producer = createNATSProducer(ctx, conf.NATSConfig)
+		return
+	}
+
+	if e.Output == "rabbitmq" || e.Output == "amqp" {
+		e.Producer = createRabbitMQProducer(ctx, conf.RabbitMQConfig)
+		return
+	}
+
+	if e.Output == "pulsar" {
+		e.Producer = createPulsarProducer(ctx, conf.PulsarConfig)
+		return
+	}
+
+	if e.Output == "kinesis" {
+		e.Producer = createKinesisProducer(ctx, conf.KinesisConfig, false)
+		return
+	}
+
+	if e.Output == "firehose" {
+		e.Producer = createKinesisProducer(ctx, conf.KinesisConfig, true)
+		return
+	}
+
+	if e.Output == "sqs" {
+		e.Producer = createSQSProducer(ctx, conf.SQSConfig)
+		return
+	}
+
+	if e.Output == "sns" {
+		e.Producer = createSNSProducer(ctx, conf.SNSConfig)
+		return
+	}
+
+	if e.Output == "pubsub" {
+		e.Producer = createPubSubProducer(ctx, conf.PubSubConfig)
+		return
+	}
+
+	if e.Output == "grpc" {
+		e.Producer = createGRPCProducer(ctx, conf.GRPCConfig)
+		return
+	}
+
+	if e.Output == "websocket" {
+		e.Producer = createWebSocketProducer(ctx, conf.WebSocketConfig)
+		return
+	}
+
+	if e.Output == "syslog" {
+		e.Producer = createSyslogProducer(ctx, conf.SyslogConfig)
+		return
+	}
+
+	if e.Output == "otlp" {
+		e.Producer = createOTLPProducer(ctx, conf.OTLPConfig)
+		return
+	}
+
+	if e.Output == "clickhouse" {
+		e.Producer = createClickHouseProducer(ctx, conf.ClickHouseConfig)
+		return
+	}
+
+	if e.Output == "influxdb" {
+		e.Producer = createTSDBProducer(ctx, conf.TSDBConfig, tsdb.InfluxDB)
+		return
+	}
+
+	if e.Output == "prometheus" {
+		e.Producer = createTSDBProducer(ctx, conf.TSDBConfig, tsdb.Prometheus)
+		return
+	}
+
 }
 
 func (e *Emitter) Run(ctx context.Context, num int, o any) {
@@ -197,12 +325,14 @@ func (e *Emitter) Run(ctx context.Context, num int, o any) {
 		k := e.KTpl.Execute()
 		v := e.VTpl.Execute()
 		kInValue := functions.GetV("KEY")
-
 		if kInValue != "" {
-			e.Producer.Produce(ctx, []byte(kInValue), []byte(v), o)
-		} else {
-			e.Producer.Produce(ctx, []byte(k), []byte(v), o)
+			k = kInValue
 		}
+
+		if !e.isValid([]byte(k), []byte(v)) {
+			continue
+		}
+		e.Producer.Produce(ctx, []byte(k), []byte(v), o)
 		jtctx.JrContext.GeneratedObjects++
 		jtctx.JrContext.GeneratedBytes += int64(len(v))
 
@@ -309,12 +439,154 @@ func createWAMPRPCProducer(ctx context.Context, config string) Producer {
 	return producer
 }
 
-func createKafkaProducer(ctx context.Context, conf configuration.GlobalConfiguration, topic string, templateType string) *kafka.Manager {
+func createFileProducer(_ context.Context, config string) Producer {
+	producer := &file.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createAvroFileProducer(_ context.Context, config string, schemaFile string, templateType string) Producer {
+	producer := &file.Producer{Format: format.Avro}
+
+	// the schema of the template comes from the generated types, unless given
+	if schemaFile != "" {
+		schema, err := os.ReadFile(schemaFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read schema file")
+		}
+		producer.Schema = string(schema)
+	} else if t, ok := types.GetType(templateType).(interface{ Schema() string }); ok {
+		producer.Schema = t.Schema()
+	}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createSQLProducer(_ context.Context, config string) Producer {
+	producer := &sql.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createSQLScriptProducer(_ context.Context, config string, templateName string) Producer {
+	// the table is named after the template, unless configured
+	producer := &sql.ScriptProducer{Table: templateName}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createMQTTProducer(ctx context.Context, config string) Producer {
+	producer := &mqtt.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createNATSProducer(ctx context.Context, config string) Producer {
+	producer := &nats.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createRabbitMQProducer(_ context.Context, config string) Producer {
+	producer := &rabbitmq.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createPulsarProducer(_ context.Context, config string) Producer {
+	producer := &pulsar.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createKinesisProducer(ctx context.Context, config string, firehose bool) Producer {
+	producer := &kinesis.Producer{Firehose: firehose}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createSQSProducer(ctx context.Context, config string) Producer {
+	producer := &sqs.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createSNSProducer(ctx context.Context, config string) Producer {
+	producer := &sns.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createPubSubProducer(ctx context.Context, config string) Producer {
+	producer := &pubsub.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createGRPCProducer(ctx context.Context, config string) Producer {
+	producer := &grpc.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createWebSocketProducer(ctx context.Context, config string) Producer {
+	producer := &websocket.Producer{}
+	producer.Initialize(ctx, config)
+
+	return producer
+}
+
+func createSyslogProducer(_ context.Context, config string) Producer {
+	producer := &syslog.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createOTLPProducer(_ context.Context, config string) Producer {
+	producer := &otlp.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createClickHouseProducer(_ context.Context, config string) Producer {
+	producer := &clickhouse.Producer{}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createTSDBProducer(_ context.Context, config string, format string) Producer {
+	producer := &tsdb.Producer{Format: format}
+	producer.Initialize(config)
+
+	return producer
+}
+
+func createKafkaProducer(ctx context.Context, conf configuration.GlobalConfiguration, e *Emitter, templateType string) *kafka.Manager {
 
+	topic := e.Topic
 	kManager := &kafka.Manager{
 		Serializer:   conf.Serializer,
 		Topic:        topic,
 		TemplateType: templateType,
+		ProtoFile:    e.ProtoFile,
+		ProtoMessage: e.ProtoMessage,
+		SchemaFile:   e.SchemaFile,
 	}
 
 	kManager.Initialize(conf.KafkaConfig)
@@ -137,12 +137,14 @@ func doTemplate(ctx context.Context, emitter Emitter) {
 			v = strings.ReplaceAll(v, "\n", "")
 		}
 		kInValue := functions.GetV("KEY")
+		if kInValue != "" {
+			k = kInValue
+		}
 
-		if (kInValue) != "" {
-			emitter.Producer.Produce(ctx, []byte(kInValue), []byte(v), nil)
-		} else {
-			emitter.Producer.Produce(ctx, []byte(k), []byte(v), nil)
+		if !emitter.isValid([]byte(k), []byte(v)) {
+			continue
 		}
+		emitter.Producer.Produce(ctx, []byte(k), []byte(v), nil)
 
 		jrctx.JrContext.GeneratedObjects++
 		jrctx.JrContext.GeneratedBytes += int64(len(v))
@@ -159,6 +161,9 @@ func CloseProducers(ctx context.Context, es map[string][]Emitter) {
 					fmt.Printf("Error in closing producers: %v\n", err)
 				}
 			}
+			if err := v[i].closeValidation(); err != nil {
+				fmt.Printf("Error in closing dlq: %v\n", err)
+			}
 		}
 	}
 	time.Sleep(100 * time.Millisecond)
@@ -182,10 +187,14 @@ func WriteStats() {
 	_, _ = fmt.Fprintf(os.Stderr, "Elapsed time: %v\n", elapsed.Round(1*time.Second))
 	_, _ = fmt.Fprintf(os.Stderr, "Data Generated (Objects): %d\n", jrctx.JrContext.GeneratedObjects)
 
-	ungenerated := jrctx.JrContext.ExpectedObjects - jrctx.JrContext.GeneratedObjects
+	// invalid objects are counted apart
+	ungenerated := jrctx.JrContext.ExpectedObjects - jrctx.JrContext.GeneratedObjects - jrctx.JrContext.InvalidObjects
 	if ungenerated > 0 {
 		_, _ = fmt.Fprintf(os.Stderr, "Data NOT Generated (Objects): %d\n", ungenerated)
 	}
+	if jrctx.JrContext.InvalidObjects > 0 {
+		_, _ = fmt.Fprintf(os.Stderr, "Invalid Data (Objects): %d\n", jrctx.JrContext.InvalidObjects)
+	}
 	_, _ = fmt.Fprintf(os.Stderr, "Data Generated (bytes): %d\n", jrctx.JrContext.GeneratedBytes)
 	_, _ = fmt.Fprintf(os.Stderr, "Throughput (bytes per second): %9.f\n", float64(jrctx.JrContext.GeneratedBytes)/elapsed.Seconds())
 	_, _ = fmt.Fprintln(os.Stderr)
@@ -0,0 +1,128 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package emitter
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+
+	jtctx "github.com/jrnd-io/jr/pkg/ctx"
+	"github.com/jrnd-io/jr/pkg/schema"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	OnInvalidDrop = "drop"
+	OnInvalidDLQ  = "dlq"
+	OnInvalidFail = "fail"
+)
+
+type validation struct {
+	validator schema.Validator
+	onInvalid string
+	dlq       *os.File
+	dlqLock   sync.Mutex
+}
+
+type deadLetter struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Error string `json:"error"`
+}
+
+func (e *Emitter) initializeValidation() {
+	if e.Validate == "" {
+		return
+	}
+
+	schemaFile := e.ValidateSchema
+	if schemaFile == "" {
+		schemaFile = e.SchemaFile
+	}
+	validator, err := schema.NewValidator(e.Validate, schemaFile)
+	if err != nil {
+		log.Fatal().Err(err).Str("emitter", e.Name).Msg("Failed to create validator")
+	}
+
+	v := &validation{validator: validator, onInvalid: e.OnInvalid}
+	switch e.OnInvalid {
+	case "":
+		v.onInvalid = OnInvalidDrop
+	case OnInvalidDrop, OnInvalidFail:
+	case OnInvalidDLQ:
+		if e.DLQ == "" {
+			log.Fatal().Str("emitter", e.Name).Msg("dlq file is mandatory when onInvalid is dlq")
+		}
+		v.dlq, err = os.OpenFile(e.DLQ, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatal().Err(err).Str("dlq", e.DLQ).Msg("Failed to open dlq file")
+		}
+	default:
+		log.Fatal().Str("onInvalid", e.OnInvalid).Msg("onInvalid must be one of drop, dlq, fail")
+	}
+	e.validation = v
+}
+
+// isValid validates the rendered value, handling invalid records as configured in onInvalid
+func (e *Emitter) isValid(key []byte, value []byte) bool {
+	if e.validation == nil {
+		return true
+	}
+	err := e.validation.validator.Validate(value)
+	if err == nil {
+		return true
+	}
+
+	jtctx.JrContext.InvalidObjects++
+
+	switch e.validation.onInvalid {
+	case OnInvalidFail:
+		log.Fatal().Err(err).Str("emitter", e.Name).Str("value", string(value)).Msg("Invalid record")
+	case OnInvalidDLQ:
+		log.Warn().Err(err).Str("emitter", e.Name).Msg("Invalid record sent to dlq")
+		e.validation.writeDeadLetter(key, value, err)
+	default:
+		log.Warn().Err(err).Str("emitter", e.Name).Msg("Invalid record dropped")
+	}
+	return false
+}
+
+func (v *validation) writeDeadLetter(key []byte, value []byte, cause error) {
+	line, err := json.Marshal(deadLetter{Key: string(key), Value: string(value), Error: cause.Error()})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to marshal dead letter")
+		return
+	}
+
+	v.dlqLock.Lock()
+	defer v.dlqLock.Unlock()
+	if _, err := v.dlq.Write(append(line, '\n')); err != nil {
+		log.Error().Err(err).Msg("Failed to write dead letter")
+	}
+}
+
+func (e *Emitter) closeValidation() error {
+	if e.validation == nil || e.validation.dlq == nil {
+		return nil
+	}
+	return e.validation.dlq.Close()
+}
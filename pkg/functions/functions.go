@@ -61,6 +61,7 @@ var fmap = map[string]interface{}{
 	"from_shuffle":             WordShuffle,
 	"from_n":                   WordShuffleN,
 	"join":                     strings.Join,
+	"json_string":              JSONString,
 	"len":                      Len,
 	"lower":                    strings.ToLower,
 	"lorem":                    Lorem,
@@ -220,6 +221,12 @@ var fmap = map[string]interface{}{
 	"fromcsv":                  FromCsv,
 }
 
+// JSONString returns s as a quoted JSON string
+func JSONString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
 func Atoi(s string) int {
 	if len(s) == 0 {
 		return 0
@@ -694,6 +694,16 @@ var funcDesc = map[string]FunctionDescription{
 		Example:     "jr template run --embedded '{{join \"hello,\" \"world\"}}'",
 		Output:      "hello,world",
 	},
+	"json_string": {
+		Name:        "json_string",
+		Category:    "text",
+		Description: "returns a string quoted and escaped as a JSON string",
+		Parameters:  "text string",
+		Localizable: false,
+		Return:      "string",
+		Example:     "jr template run --embedded '{{json_string \"say \\\"hi\\\"\"}}'",
+		Output:      "\"say \\\"hi\\\"\"",
+	},
 	"just_passed": {
 		Name:        "just_passed",
 		Category:    "time",
@@ -20,6 +20,8 @@
 
 package azblobstorage
 
+import "github.com/jrnd-io/jr/pkg/producers/format"
+
 type Container struct {
 	Name   string `json:"name"`
 	Create bool   `json:"create"`
@@ -28,4 +30,6 @@ type Config struct {
 	AccountName       string    `json:"account_name"`
 	PrimaryAccountKey string    `json:"primary_account_key"`
 	Container         Container `json:"container"`
+	// with a format, records are written in batches, one blob per batch
+	format.BatchConfig
 }
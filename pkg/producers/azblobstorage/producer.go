@@ -29,12 +29,14 @@ import (
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/google/uuid"
+	"github.com/jrnd-io/jr/pkg/producers/format"
 	"github.com/rs/zerolog/log"
 )
 
 type Producer struct {
 	configuration Config
 	client        *azblob.Client
+	batch         *format.Batch
 }
 
 func (p *Producer) Initialize(ctx context.Context, configFile string) {
@@ -80,10 +82,28 @@ func (p *Producer) Initialize(ctx context.Context, configFile string) {
 
 	p.client = client
 
+	if config.Format != "" {
+		p.batch, err = format.NewBatch(config.BatchConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid blob format")
+		}
+	}
 }
 
 func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
 
+	if p.batch != nil {
+		full, err := p.batch.Add(v)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to encode record")
+			return
+		}
+		if full {
+			p.flush(ctx)
+		}
+		return
+	}
+
 	var key string
 	if len(k) == 0 || strings.ToLower(string(k)) == "null" {
 		// generate a UUID as index
@@ -111,6 +131,26 @@ func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
 
 }
 
-func (p *Producer) Close(_ context.Context) error {
+func (p *Producer) flush(ctx context.Context) {
+	key, content, err := p.batch.Flush()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to encode batch")
+	}
+	if content == nil {
+		return
+	}
+
+	resp, err := p.client.UploadBuffer(ctx, p.configuration.Container.Name, key, content, nil)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to upload blob")
+	}
+
+	log.Trace().Str("key", key).Interface("upload_resp", resp).Msg("Uploaded blob")
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	if p.batch != nil {
+		p.flush(ctx)
+	}
 	return nil
 }
@@ -0,0 +1,165 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package batcher sends records in batches, for the producers of services with batch APIs.
+package batcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const DefaultBackoff = 100 * time.Millisecond
+
+// PutFunc sends a batch, returning the items that failed and can be sent again
+type PutFunc[T any] func(ctx context.Context, items []T) ([]T, error)
+
+type Config struct {
+	// MaxItems and MaxBytes are the limits of a batch
+	MaxItems int
+	MaxBytes int
+	// MaxRetries is the number of times failed items are sent again, with an exponential backoff
+	MaxRetries int
+	// Interval, when set, sends an incomplete batch when it is older than this duration
+	Interval time.Duration
+}
+
+// Batcher collects items and sends them in batches, sending again the items
+// that failed, e.g. throttled, with an exponential backoff
+type Batcher[T any] struct {
+	// Backoff is the wait before the first retry
+	Backoff time.Duration
+
+	put    PutFunc[T]
+	sizeOf func(T) int
+	config Config
+
+	lock     sync.Mutex
+	items    []T
+	bytes    int
+	oldest   time.Time
+	stopTick chan struct{}
+	tickDone chan struct{}
+}
+
+func New[T any](put PutFunc[T], sizeOf func(T) int, config Config) *Batcher[T] {
+	b := &Batcher[T]{
+		Backoff: DefaultBackoff,
+		put:     put,
+		sizeOf:  sizeOf,
+		config:  config,
+	}
+	if config.Interval > 0 {
+		b.stopTick = make(chan struct{})
+		b.tickDone = make(chan struct{})
+		go b.flushOnInterval()
+	}
+	return b
+}
+
+func (b *Batcher[T]) Add(ctx context.Context, item T) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	size := b.sizeOf(item)
+	if len(b.items) > 0 && b.config.MaxBytes > 0 && b.bytes+size > b.config.MaxBytes {
+		b.flush(ctx)
+	}
+	if len(b.items) == 0 {
+		b.oldest = time.Now()
+	}
+	b.items = append(b.items, item)
+	b.bytes += size
+	if len(b.items) >= b.config.MaxItems {
+		b.flush(ctx)
+	}
+}
+
+// Close sends the last batch, returning an error if any item of any batch could not be sent
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	if b.stopTick != nil {
+		close(b.stopTick)
+		<-b.tickDone
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if failed := b.flush(ctx); failed > 0 {
+		return fmt.Errorf("%d records not sent", failed)
+	}
+	return nil
+}
+
+func (b *Batcher[T]) flushOnInterval() {
+	defer close(b.tickDone)
+
+	// the ticker needs a positive period
+	ticker := time.NewTicker(max(b.config.Interval/2, time.Nanosecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stopTick:
+			return
+		case <-ticker.C:
+			b.lock.Lock()
+			if len(b.items) > 0 && time.Since(b.oldest) >= b.config.Interval {
+				b.flush(context.Background())
+			}
+			b.lock.Unlock()
+		}
+	}
+}
+
+// flush sends the current batch, returning the number of items that could not be sent
+func (b *Batcher[T]) flush(ctx context.Context) int {
+	items := b.items
+	b.items = nil
+	b.bytes = 0
+
+	backoff := b.Backoff
+	for attempt := 0; len(items) > 0; attempt++ {
+		failed, err := b.put(ctx, items)
+		if err != nil {
+			log.Error().Err(err).Int("records", len(items)).Msg("Failed to send records")
+			return len(items)
+		}
+		if len(failed) == 0 {
+			return 0
+		}
+		if attempt >= b.config.MaxRetries {
+			log.Error().Int("records", len(failed)).Int("retries", attempt).Msg("Failed to send records after retries")
+			return len(failed)
+		}
+
+		log.Debug().Int("records", len(failed)).Dur("backoff", backoff).Msg("Sending failed records again")
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return len(failed)
+		}
+		backoff *= 2
+		items = failed
+	}
+	return 0
+}
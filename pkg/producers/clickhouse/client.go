@@ -0,0 +1,223 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	ch "github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// client runs the queries of the producer on the native protocol or on the HTTP interface
+type client interface {
+	exec(ctx context.Context, query string) error
+	insert(ctx context.Context, table string, rows [][]byte) error
+	close() error
+}
+
+// nativeClient inserts the rows in columnar blocks, converting them to the types of the columns
+type nativeClient struct {
+	conn     driver.Conn
+	database string
+	columns  []column
+}
+
+func newNativeClient(config Config, settings map[string]string, tlsConfig *tls.Config, timeout time.Duration) (*nativeClient, error) {
+	options := &ch.Options{
+		Addr: strings.Split(config.Address, ","),
+		Auth: ch.Auth{
+			Database: config.Database,
+			Username: config.Username,
+			Password: config.Password,
+		},
+		TLS:         tlsConfig,
+		Settings:    ch.Settings{},
+		DialTimeout: timeout,
+		ReadTimeout: timeout,
+	}
+	for k, v := range settings {
+		options.Settings[k] = v
+	}
+	switch config.Compression {
+	case "", "none":
+	case "lz4":
+		options.Compression = &ch.Compression{Method: ch.CompressionLZ4}
+	case "zstd":
+		options.Compression = &ch.Compression{Method: ch.CompressionZSTD}
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", config.Compression)
+	}
+
+	conn, err := ch.Open(options)
+	if err != nil {
+		return nil, err
+	}
+	return &nativeClient{conn: conn, database: config.Database}, nil
+}
+
+func (c *nativeClient) exec(ctx context.Context, query string) error {
+	return c.conn.Exec(ctx, query)
+}
+
+func (c *nativeClient) insert(ctx context.Context, table string, rows [][]byte) error {
+	if c.columns == nil {
+		if err := c.loadColumns(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, len(c.columns))
+	for i, col := range c.columns {
+		names[i] = quote(col.name)
+	}
+	batch, err := c.conn.PrepareBatch(ctx, fmt.Sprintf("INSERT INTO %s (%s)", table, strings.Join(names, ", ")))
+	if err != nil {
+		return err
+	}
+	defer batch.Abort()
+
+	values := make([]any, len(c.columns))
+	for _, data := range rows {
+		r, err := parseRow(data)
+		if err != nil {
+			return fmt.Errorf("invalid record %s: %w", data, err)
+		}
+		for i, col := range c.columns {
+			if values[i], err = convert(col.kind, r.values[col.name]); err != nil {
+				return fmt.Errorf("column %s: %w", col.name, err)
+			}
+		}
+		if err := batch.Append(values...); err != nil {
+			return err
+		}
+	}
+	return batch.Send()
+}
+
+// loadColumns reads the columns of the table, except the ones that can't be inserted
+func (c *nativeClient) loadColumns(ctx context.Context, table string) error {
+	rows, err := c.conn.Query(ctx, fmt.Sprintf("DESCRIBE TABLE %s", table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name, kind, defaultType, defaultExpression, comment, codec, ttl string
+		if err := rows.Scan(&name, &kind, &defaultType, &defaultExpression, &comment, &codec, &ttl); err != nil {
+			return err
+		}
+		if defaultType == "MATERIALIZED" || defaultType == "ALIAS" {
+			continue
+		}
+		c.columns = append(c.columns, column{name: name, kind: kind})
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(c.columns) == 0 {
+		return fmt.Errorf("no columns found in %s", table)
+	}
+	return nil
+}
+
+func (c *nativeClient) close() error {
+	return c.conn.Close()
+}
+
+// httpClient posts the rows as they are, in the JSONEachRow format
+type httpClient struct {
+	client   *http.Client
+	address  string
+	params   url.Values
+	username string
+	password string
+}
+
+func newHTTPClient(config Config, settings map[string]string, tlsConfig *tls.Config, timeout time.Duration) *httpClient {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	params := url.Values{}
+	if config.Database != "" {
+		params.Set("database", config.Database)
+	}
+	for k, v := range settings {
+		params.Set(k, v)
+	}
+	return &httpClient{
+		client:   &http.Client{Transport: transport, Timeout: timeout},
+		address:  strings.TrimSuffix(config.Address, "/") + "/",
+		params:   params,
+		username: config.Username,
+		password: config.Password,
+	}
+}
+
+func (c *httpClient) exec(ctx context.Context, query string) error {
+	return c.post(ctx, query, nil)
+}
+
+func (c *httpClient) insert(ctx context.Context, table string, rows [][]byte) error {
+	body := bytes.Join(rows, []byte{'\n'})
+	return c.post(ctx, fmt.Sprintf("INSERT INTO %s FORMAT JSONEachRow", table), body)
+}
+
+func (c *httpClient) post(ctx context.Context, query string, body []byte) error {
+	params := url.Values{"query": {query}}
+	for k, v := range c.params {
+		params[k] = v
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.address+"?"+params.Encode(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if c.username != "" {
+		req.Header.Set("X-ClickHouse-User", c.username)
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (c *httpClient) close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
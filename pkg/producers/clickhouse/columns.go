@@ -0,0 +1,281 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type column struct {
+	name string
+	kind string
+}
+
+// row is a rendered value: its top-level fields in order, and their values
+type row struct {
+	names  []string
+	values map[string]any
+}
+
+// parseRow decodes a JSON object keeping the order of its fields, which gives
+// the order of the columns when the table is created
+func parseRow(data []byte) (row, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	r := row{values: make(map[string]any)}
+	t, err := dec.Token()
+	if err != nil {
+		return r, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return r, fmt.Errorf("expected a JSON object, got %v", t)
+	}
+	for dec.More() {
+		if t, err = dec.Token(); err != nil {
+			return r, err
+		}
+		name := t.(string)
+		var v any
+		if err = dec.Decode(&v); err != nil {
+			return r, fmt.Errorf("field %q: %w", name, err)
+		}
+		if _, ok := r.values[name]; !ok {
+			r.names = append(r.names, name)
+		}
+		r.values[name] = v
+	}
+	_, err = dec.Token()
+	return r, err
+}
+
+// inferColumns derives the columns from the fields of a record
+func inferColumns(r row) []column {
+	columns := make([]column, len(r.names))
+	for i, name := range r.names {
+		columns[i] = column{name: name, kind: kindOf(r.values[name])}
+	}
+	return columns
+}
+
+func kindOf(v any) string {
+	switch t := v.(type) {
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return "Int64"
+		}
+		return "Float64"
+	case bool:
+		return "Bool"
+	case string:
+		if _, err := time.Parse(time.RFC3339Nano, t); err == nil {
+			return "DateTime64(3)"
+		}
+		return "String"
+	case []any:
+		if len(t) > 0 {
+			if kind := kindOf(t[0]); kind != "String" || isString(t[0]) {
+				return "Array(" + kind + ")"
+			}
+		}
+		return "String"
+	case nil:
+		return "Nullable(String)"
+	default:
+		// nested objects are stored as JSON text
+		return "String"
+	}
+}
+
+func isString(v any) bool {
+	_, ok := v.(string)
+	return ok
+}
+
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+func createTableQuery(table string, columns []column, engine string, orderBy string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (", table)
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s %s", quote(c.name), c.kind)
+	}
+	fmt.Fprintf(&b, ") ENGINE = %s ORDER BY %s", engine, orderBy)
+	return b.String()
+}
+
+// unwrap removes a type modifier, e.g. Nullable(String) is String
+func unwrap(kind string, modifier string) (string, bool) {
+	if strings.HasPrefix(kind, modifier+"(") && strings.HasSuffix(kind, ")") {
+		return kind[len(modifier)+1 : len(kind)-1], true
+	}
+	return kind, false
+}
+
+// convert converts a decoded JSON value to a value that the native protocol
+// appends to a column of the given type
+func convert(kind string, v any) (any, error) {
+	kind, _ = unwrap(kind, "LowCardinality")
+	kind, nullable := unwrap(kind, "Nullable")
+	if v == nil {
+		if nullable {
+			return nil, nil
+		}
+		return zero(kind), nil
+	}
+
+	if inner, ok := unwrap(kind, "Array"); ok {
+		elements, ok := v.([]any)
+		if !ok {
+			return nil, fmt.Errorf("expected an array for %s, got %T", kind, v)
+		}
+		values := make([]any, len(elements))
+		for i, e := range elements {
+			var err error
+			if values[i], err = convert(inner, e); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+
+	switch {
+	case strings.HasPrefix(kind, "Int"), strings.HasPrefix(kind, "UInt"), strings.HasPrefix(kind, "Float"):
+		n, ok := v.(json.Number)
+		if !ok {
+			return nil, fmt.Errorf("expected a number for %s, got %T", kind, v)
+		}
+		return number(kind, n.String())
+	case strings.HasPrefix(kind, "Decimal"):
+		return decimal.NewFromString(fmt.Sprint(v))
+	case kind == "Bool":
+		b, ok := v.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean for %s, got %T", kind, v)
+		}
+		return b, nil
+	case strings.HasPrefix(kind, "Date"):
+		switch t := v.(type) {
+		case string:
+			return parseTime(t)
+		case json.Number:
+			// seconds since the epoch
+			seconds, err := t.Int64()
+			return time.Unix(seconds, 0).UTC(), err
+		}
+		return nil, fmt.Errorf("expected a date for %s, got %T", kind, v)
+	case strings.HasPrefix(kind, "String"), strings.HasPrefix(kind, "FixedString"),
+		strings.HasPrefix(kind, "UUID"), strings.HasPrefix(kind, "Enum"):
+		switch t := v.(type) {
+		case string:
+			return t, nil
+		case json.Number:
+			return t.String(), nil
+		case bool:
+			return fmt.Sprint(t), nil
+		}
+		b, err := json.Marshal(v)
+		return string(b), err
+	default:
+		return v, nil
+	}
+}
+
+// number parses a number as the Go type of the column, the native protocol
+// doesn't convert between integer widths
+func number(kind string, s string) (any, error) {
+	switch kind {
+	case "Int8":
+		n, err := strconv.ParseInt(s, 10, 8)
+		return int8(n), err
+	case "Int16":
+		n, err := strconv.ParseInt(s, 10, 16)
+		return int16(n), err
+	case "Int32":
+		n, err := strconv.ParseInt(s, 10, 32)
+		return int32(n), err
+	case "Int64":
+		return strconv.ParseInt(s, 10, 64)
+	case "UInt8":
+		n, err := strconv.ParseUint(s, 10, 8)
+		return uint8(n), err
+	case "UInt16":
+		n, err := strconv.ParseUint(s, 10, 16)
+		return uint16(n), err
+	case "UInt32":
+		n, err := strconv.ParseUint(s, 10, 32)
+		return uint32(n), err
+	case "UInt64":
+		return strconv.ParseUint(s, 10, 64)
+	case "Int128", "Int256", "UInt128", "UInt256":
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %q", kind, s)
+		}
+		return n, nil
+	case "Float32":
+		f, err := strconv.ParseFloat(s, 32)
+		return float32(f), err
+	case "Float64":
+		return strconv.ParseFloat(s, 64)
+	}
+	return nil, fmt.Errorf("unsupported type %s", kind)
+}
+
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", time.DateOnly} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+func zero(kind string) any {
+	switch {
+	case strings.HasPrefix(kind, "Int"), strings.HasPrefix(kind, "UInt"), strings.HasPrefix(kind, "Float"):
+		n, _ := number(kind, "0")
+		return n
+	case strings.HasPrefix(kind, "Decimal"):
+		return decimal.Zero
+	case kind == "Bool":
+		return false
+	case strings.HasPrefix(kind, "Date"):
+		return time.Unix(0, 0)
+	case strings.HasPrefix(kind, "Array"):
+		return []any{}
+	default:
+		return ""
+	}
+}
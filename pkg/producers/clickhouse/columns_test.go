@@ -0,0 +1,116 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestInferColumns(t *testing.T) {
+	r, err := parseRow([]byte(`{"id":1,"price":9.5,"name":"shoe","paid":true,"at":"2024-05-01T10:00:00Z","tags":["a"],"sizes":[41,42],"address":{"city":"Rome"},"note":null}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []column{
+		{"id", "Int64"},
+		{"price", "Float64"},
+		{"name", "String"},
+		{"paid", "Bool"},
+		{"at", "DateTime64(3)"},
+		{"tags", "Array(String)"},
+		{"sizes", "Array(Int64)"},
+		{"address", "String"},
+		{"note", "Nullable(String)"},
+	}
+	if got := inferColumns(r); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestCreateTableQuery(t *testing.T) {
+	got := createTableQuery("`db`.`orders`", []column{{"id", "Int64"}, {"name", "String"}}, "MergeTree", "id")
+	want := "CREATE TABLE IF NOT EXISTS `db`.`orders` (`id` Int64, `name` String) ENGINE = MergeTree ORDER BY id"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		kind  string
+		value any
+		want  any
+	}{
+		{name: "int8", kind: "Int8", value: json.Number("-3"), want: int8(-3)},
+		{name: "uint32", kind: "UInt32", value: json.Number("7"), want: uint32(7)},
+		{name: "uint64", kind: "UInt64", value: json.Number("18446744073709551615"), want: uint64(18446744073709551615)},
+		{name: "int128", kind: "Int128", value: json.Number("42"), want: big.NewInt(42)},
+		{name: "float32", kind: "Float32", value: json.Number("1.5"), want: float32(1.5)},
+		{name: "low cardinality", kind: "LowCardinality(String)", value: "IT", want: "IT"},
+		{name: "number as string", kind: "String", value: json.Number("12"), want: "12"},
+		{name: "object as string", kind: "String", value: map[string]any{"city": "Rome"}, want: `{"city":"Rome"}`},
+		{name: "datetime", kind: "DateTime64(3)", value: "2024-05-01T10:00:00Z", want: at},
+		{name: "epoch", kind: "DateTime", value: json.Number("1714557600"), want: at},
+		{name: "array", kind: "Array(UInt8)", value: []any{json.Number("1"), json.Number("2")}, want: []any{uint8(1), uint8(2)}},
+		{name: "null", kind: "Nullable(Int64)", value: nil, want: nil},
+		{name: "missing", kind: "Int16", value: nil, want: int16(0)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := convert(tc.kind, tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %#v, want %#v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	testCases := []struct {
+		name  string
+		kind  string
+		value any
+	}{
+		{name: "overflow", kind: "Int8", value: json.Number("300")},
+		{name: "string as number", kind: "Int64", value: "abc"},
+		{name: "invalid date", kind: "Date", value: "yesterday"},
+		{name: "not an array", kind: "Array(String)", value: "a"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := convert(tc.kind, tc.value); err == nil {
+				t.Errorf("expected an error converting %v to %s", tc.value, tc.kind)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clickhouse
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	Native = "native"
+	HTTP   = "http"
+
+	DefaultBatchSize     = 10000
+	DefaultBatchBytes    = 16 * 1024 * 1024
+	DefaultFlushInterval = "1s"
+	DefaultTimeout       = "30s"
+)
+
+type Config struct {
+	// Protocol is "native", the default, or "http"
+	Protocol string `json:"protocol"`
+	// Address is host:port for the native protocol, e.g. "localhost:9000", and
+	// the URL of the HTTP interface, e.g. "http://localhost:8123"
+	Address  string `json:"address"`
+	Database string `json:"database"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Table    string `json:"table"`
+
+	// CreateTable creates the table, if it doesn't exist, with the columns of the first record
+	CreateTable bool   `json:"create_table"`
+	Engine      string `json:"engine"`
+	OrderBy     string `json:"order_by"`
+
+	// Records are inserted in batches of BatchSize records or BatchBytes bytes,
+	// at least every FlushInterval
+	BatchSize     int    `json:"batch_size"`
+	BatchBytes    int    `json:"batch_bytes"`
+	FlushInterval string `json:"flush_interval"`
+
+	// AsyncInsert lets the server buffer the inserts; with WaitForAsyncInsert
+	// each insert waits for the buffer to be flushed
+	AsyncInsert        bool `json:"async_insert"`
+	WaitForAsyncInsert bool `json:"wait_for_async_insert"`
+	// Settings are added to each query, e.g. {"insert_quorum": "2"}
+	Settings map[string]string `json:"settings"`
+
+	// Compression of the native protocol is "lz4", "zstd" or "none", the default
+	Compression string           `json:"compression"`
+	Timeout     string           `json:"timeout"`
+	TLS         tlsconfig.Config `json:"tls"`
+}
@@ -0,0 +1,175 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer inserts records in a ClickHouse table in large batches
+type Producer struct {
+	configuration Config
+	table         string
+	timeout       time.Duration
+	client        client
+	batcher       *batcher.Batcher[[]byte]
+	created       bool
+}
+
+func (p *Producer) Initialize(configFile string) {
+	cfgBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config file")
+	}
+
+	config := Config{}
+	if err := json.Unmarshal(cfgBytes, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal config")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+	if config.Table == "" {
+		log.Fatal().Msg("table is mandatory")
+	}
+	p.table = quote(config.Table)
+	if config.Database != "" {
+		p.table = quote(config.Database) + "." + p.table
+	}
+
+	if config.Timeout == "" {
+		p.configuration.Timeout = DefaultTimeout
+	}
+	if p.timeout, err = time.ParseDuration(p.configuration.Timeout); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse timeout")
+	}
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	if config.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+	if config.BatchBytes <= 0 {
+		p.configuration.BatchBytes = DefaultBatchBytes
+	}
+	if config.Engine == "" {
+		p.configuration.Engine = "MergeTree"
+	}
+	if config.OrderBy == "" {
+		p.configuration.OrderBy = "tuple()"
+	}
+
+	settings := make(map[string]string, len(config.Settings)+2)
+	if config.AsyncInsert {
+		settings["async_insert"] = "1"
+		settings["wait_for_async_insert"] = "0"
+		if config.WaitForAsyncInsert {
+			settings["wait_for_async_insert"] = "1"
+		}
+	}
+	for k, v := range config.Settings {
+		settings[k] = v
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS.IsSet() {
+		if tlsConfig, err = config.TLS.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+
+	switch strings.ToLower(config.Protocol) {
+	case "", Native:
+		if p.configuration.Address == "" {
+			p.configuration.Address = "localhost:9000"
+		}
+		if p.client, err = newNativeClient(p.configuration, settings, tlsConfig, p.timeout); err != nil {
+			log.Fatal().Err(err).Msg("Failed to connect to ClickHouse")
+		}
+	case HTTP:
+		if p.configuration.Address == "" {
+			p.configuration.Address = "http://localhost:8123"
+		}
+		p.client = newHTTPClient(p.configuration, settings, tlsConfig, p.timeout)
+	default:
+		log.Fatal().Str("protocol", config.Protocol).Msg("protocol must be native or http")
+	}
+
+	p.batcher = batcher.New(p.insert, func(v []byte) int { return len(v) }, batcher.Config{
+		MaxItems: p.configuration.BatchSize,
+		MaxBytes: p.configuration.BatchBytes,
+		Interval: interval,
+	})
+}
+
+func (p *Producer) Produce(ctx context.Context, _ []byte, v []byte, _ any) {
+	p.batcher.Add(ctx, bytes.Clone(v))
+}
+
+// insert sends a batch in one insert, creating the table with the first record if needed
+func (p *Producer) insert(ctx context.Context, rows [][]byte) ([][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if p.configuration.CreateTable && !p.created {
+		r, err := parseRow(rows[0])
+		if err != nil {
+			return nil, err
+		}
+		query := createTableQuery(p.table, inferColumns(r), p.configuration.Engine, p.configuration.OrderBy)
+		if err := p.client.exec(ctx, query); err != nil {
+			return nil, err
+		}
+		p.created = true
+	}
+
+	start := time.Now()
+	if err := p.client.insert(ctx, p.table, rows); err != nil {
+		return nil, err
+	}
+	log.Debug().Int("rows", len(rows)).Dur("duration", time.Since(start)).Msg("Inserted batch")
+	return nil, nil
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if closeErr := p.client.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package clickhouse
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type request struct {
+	query    string
+	settings string
+	body     string
+}
+
+// fakeServer records the queries sent to the HTTP interface
+type fakeServer struct {
+	lock     sync.Mutex
+	requests []request
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	params := r.URL.Query()
+	if r.Header.Get("X-ClickHouse-User") != "jr" || r.Header.Get("X-ClickHouse-Key") != "secret" || params.Get("database") != "shop" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.requests = append(f.requests, request{
+		query:    params.Get("query"),
+		settings: params.Get("async_insert") + params.Get("wait_for_async_insert") + params.Get("insert_deduplicate"),
+		body:     string(body),
+	})
+}
+
+func TestHTTPProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{
+		Protocol:    HTTP,
+		Address:     server.URL,
+		Database:    "shop",
+		Username:    "jr",
+		Password:    "secret",
+		Table:       "orders",
+		CreateTable: true,
+		OrderBy:     "id",
+		BatchSize:   2,
+		AsyncInsert: true,
+		Settings:    map[string]string{"insert_deduplicate": "0"},
+	})
+	for i := 0; i < 3; i++ {
+		p.Produce(ctx, nil, []byte(fmt.Sprintf(`{"id":%d,"item":"shoe"}`, i)), nil)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	insert := "INSERT INTO `shop`.`orders` FORMAT JSONEachRow"
+	want := []request{
+		{query: "CREATE TABLE IF NOT EXISTS `shop`.`orders` (`id` Int64, `item` String) ENGINE = MergeTree ORDER BY id", settings: "100"},
+		{query: insert, settings: "100", body: `{"id":0,"item":"shoe"}` + "\n" + `{"id":1,"item":"shoe"}`},
+		{query: insert, settings: "100", body: `{"id":2,"item":"shoe"}`},
+	}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got %v, want %v", fake.requests, want)
+	}
+}
+
+func TestHTTPProducerError(t *testing.T) {
+	ctx := context.Background()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "Code: 60. DB::Exception: Table shop.orders does not exist", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{Protocol: HTTP, Address: server.URL, Table: "orders"})
+	client := p.client.(*httpClient)
+	err := client.insert(ctx, p.table, [][]byte{[]byte(`{}`)})
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Errorf("expected the server error, got %v", err)
+	}
+
+	p.Produce(ctx, nil, []byte(`{}`), nil)
+	if err := p.Close(ctx); err == nil {
+		t.Error("expected an error for the record not inserted")
+	}
+}
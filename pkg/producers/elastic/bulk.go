@@ -0,0 +1,110 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package elastic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// document is a bulk action and its source, compacted to a single line
+type document struct {
+	index  string
+	id     string
+	source []byte
+}
+
+func (d document) size() int {
+	return len(d.index) + len(d.id) + len(d.source) + 32
+}
+
+type bulkMetadata struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id,omitempty"`
+}
+
+// appendBulk appends the documents to a bulk request body, e.g.
+//
+//	{"create":{"_index":"logs-jr","_id":"1"}}
+//	{"@timestamp":"2024-05-01T10:00:00Z","message":"..."}
+func appendBulk(buf *bytes.Buffer, opType string, documents []document) error {
+	for _, d := range documents {
+		action, err := json.Marshal(map[string]bulkMetadata{opType: {Index: d.index, ID: d.id}})
+		if err != nil {
+			return err
+		}
+		buf.Write(action)
+		buf.WriteByte('\n')
+		buf.Write(d.source)
+		buf.WriteByte('\n')
+	}
+	return nil
+}
+
+type bulkResponse struct {
+	Errors bool                  `json:"errors"`
+	Items  []map[string]bulkItem `json:"items"`
+}
+
+type bulkItem struct {
+	Status int `json:"status"`
+	Error  *struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// retryableStatus is the status of a request, or of an item, rejected because the cluster is busy
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// results splits the documents of a bulk request in the ones to send again and the rejected ones,
+// with the first rejection
+func (r bulkResponse) results(documents []document) (retry []document, rejected int, reason error) {
+	if !r.Errors {
+		return nil, 0, nil
+	}
+	if len(r.Items) != len(documents) {
+		return nil, len(documents), fmt.Errorf("got %d items for %d documents", len(r.Items), len(documents))
+	}
+	for i, item := range r.Items {
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case retryableStatus(result.Status):
+				retry = append(retry, documents[i])
+			default:
+				rejected++
+				if reason == nil && result.Error != nil {
+					reason = fmt.Errorf("%s: %s", result.Error.Type, result.Error.Reason)
+				}
+			}
+		}
+	}
+	return retry, rejected, reason
+}
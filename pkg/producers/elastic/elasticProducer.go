@@ -21,31 +21,78 @@
 package elastic
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
-	"github.com/elastic/go-elasticsearch/v8/esapi"
-	"github.com/google/uuid"
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+	"github.com/opensearch-project/opensearch-go/v4"
 	"github.com/rs/zerolog/log"
 )
 
+const (
+	Elasticsearch = "elasticsearch"
+	OpenSearch    = "opensearch"
+
+	DefaultBatchSize     = 500
+	DefaultFlushInterval = "1s"
+	DefaultTimeout       = "30s"
+	DefaultRetries       = 3
+)
+
 type Config struct {
 	ElasticURI      string `json:"es_uri"`
 	ElasticIndex    string `json:"index"`
 	ElasticUsername string `json:"username"`
 	ElasticPassword string `json:"password"`
+
+	// Mode is "elasticsearch", the default, or "opensearch"
+	Mode string `json:"mode"`
+	// OpType is "index", the default, or "create", which data streams require
+	OpType string `json:"op_type"`
+	// Pipeline is the ingest pipeline of the documents
+	Pipeline string `json:"pipeline"`
+	// Refresh is "true", "false", the default, or "wait_for"
+	Refresh string `json:"refresh"`
+
+	// Documents are indexed with _bulk in batches of BatchSize, at least every FlushInterval;
+	// documents rejected because the cluster is busy are sent again up to MaxRetries times, -1 for none
+	BatchSize     int    `json:"batch_size"`
+	FlushInterval string `json:"flush_interval"`
+	MaxRetries    int    `json:"max_retries"`
+
+	Timeout string           `json:"timeout"`
+	TLS     tlsconfig.Config `json:"tls"`
+}
+
+// performer is the client of Elasticsearch or of OpenSearch, which both send
+// requests to the nodes of the cluster
+type performer interface {
+	Perform(req *http.Request) (*http.Response, error)
 }
 
 type Producer struct {
-	client *elasticsearch.Client
-	index  string
+	configuration Config
+	client        performer
+	index         *record.Template
+	bulkPath      string
+	timeout       time.Duration
+	batcher       *batcher.Batcher[document]
+	rejected      atomic.Int64
 }
 
 func (p *Producer) Initialize(configFile string) {
@@ -55,72 +102,169 @@ func (p *Producer) Initialize(configFile string) {
 		log.Fatal().Err(err).Msg("Failed to read configuration file")
 	}
 	err = json.Unmarshal(file, &config)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to ReadFile")
-	}
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
 	}
 
-	cfg := elasticsearch.Config{
-		Addresses: []string{config.ElasticURI},
-		Username:  config.ElasticUsername,
-		Password:  config.ElasticPassword,
-		Transport: &http.Transport{
-			MaxIdleConnsPerHost:   10,
-			ResponseHeaderTimeout: time.Second,
-			DialContext:           (&net.Dialer{Timeout: time.Second}).DialContext,
-			TLSClientConfig: &tls.Config{
-				MinVersion: tls.VersionTLS12,
-			},
-		},
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+
+	if p.index, err = record.NewTemplate("index", config.ElasticIndex); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse index template")
+	}
+	switch config.OpType {
+	case "":
+		p.configuration.OpType = "index"
+	case "index", "create":
+	default:
+		log.Fatal().Str("op_type", config.OpType).Msg("op_type must be index or create")
+	}
+
+	params := url.Values{}
+	if config.Pipeline != "" {
+		params.Set("pipeline", config.Pipeline)
+	}
+	if config.Refresh != "" {
+		params.Set("refresh", config.Refresh)
+	}
+	p.bulkPath = "/_bulk"
+	if len(params) > 0 {
+		p.bulkPath += "?" + params.Encode()
 	}
 
-	client, err := elasticsearch.NewClient(cfg)
+	if config.Timeout == "" {
+		p.configuration.Timeout = DefaultTimeout
+	}
+	if p.timeout, err = time.ParseDuration(p.configuration.Timeout); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse timeout")
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if config.TLS.IsSet() {
+		if tlsConfig, err = config.TLS.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+	transport := &http.Transport{
+		MaxIdleConnsPerHost:   10,
+		ResponseHeaderTimeout: p.timeout,
+		DialContext:           (&net.Dialer{Timeout: time.Second}).DialContext,
+		TLSClientConfig:       tlsConfig,
+	}
 
+	switch config.Mode {
+	case "", Elasticsearch:
+		p.client, err = elasticsearch.NewClient(elasticsearch.Config{
+			Addresses: []string{config.ElasticURI},
+			Username:  config.ElasticUsername,
+			Password:  config.ElasticPassword,
+			Transport: transport,
+		})
+	case OpenSearch:
+		p.client, err = opensearch.NewClient(opensearch.Config{
+			Addresses: []string{config.ElasticURI},
+			Username:  config.ElasticUsername,
+			Password:  config.ElasticPassword,
+			Transport: transport,
+		})
+	default:
+		log.Fatal().Str("mode", config.Mode).Msg("mode must be elasticsearch or opensearch")
+	}
 	if err != nil {
 		log.Fatal().Err(err).Msg("Can't connect to Elastic")
 	}
 
-	p.index = config.ElasticIndex
-	p.client = client
+	if config.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+	if config.MaxRetries == 0 {
+		p.configuration.MaxRetries = DefaultRetries
+	} else if config.MaxRetries < 0 {
+		p.configuration.MaxRetries = 0
+	}
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	p.batcher = batcher.New(p.bulk, document.size, batcher.Config{
+		MaxItems:   p.configuration.BatchSize,
+		MaxRetries: p.configuration.MaxRetries,
+		Interval:   interval,
+	})
 }
 
 func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
 
-	var req esapi.IndexRequest
+	// the source of a bulk action must be on a single line
+	var source bytes.Buffer
+	if err := json.Compact(&source, v); err != nil {
+		log.Error().Err(err).Msg("Failed to index document, invalid JSON")
+		return
+	}
+
+	d := document{index: p.index.Execute(k, v), source: source.Bytes()}
+	// without a key, the id is generated by the cluster
+	if len(k) > 0 && strings.ToLower(string(k)) != "null" {
+		d.id = string(k)
+	}
+	p.batcher.Add(ctx, d)
+}
 
-	if len(k) == 0 {
-		// generate a UUID as index
-		id := uuid.New()
+func (p *Producer) bulk(ctx context.Context, documents []document) ([]document, error) {
+	var body bytes.Buffer
+	if err := appendBulk(&body, p.configuration.OpType, documents); err != nil {
+		return nil, err
+	}
 
-		req = esapi.IndexRequest{
-			Index:      p.index,
-			DocumentID: id.String(),
-			Body:       strings.NewReader(string(v)),
-			Refresh:    "true",
-		}
-	} else {
-		req = esapi.IndexRequest{
-			Index:      p.index,
-			DocumentID: string(k),
-			Body:       strings.NewReader(string(v)),
-			Refresh:    "true",
-		}
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.bulkPath, &body)
+	if err != nil {
+		return nil, err
 	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
 
-	res, err := req.Do(ctx, p.client)
+	res, err := p.client.Perform(req)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to write data in Elastic")
+		return nil, err
 	}
 	defer res.Body.Close()
 
-	if res.IsError() {
-		log.Fatal().Str("response", res.String()).Msg("failed to index document")
+	if retryableStatus(res.StatusCode) {
+		_, _ = io.Copy(io.Discard, res.Body)
+		log.Warn().Int("statusCode", res.StatusCode).Msg("Bulk request rejected, retrying")
+		return documents, nil
 	}
+	if res.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return nil, fmt.Errorf("bulk request failed with status code %d: %s", res.StatusCode, message)
+	}
+
+	var response bulkResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	retry, rejected, reason := response.results(documents)
+	if rejected > 0 {
+		p.rejected.Add(int64(rejected))
+		log.Error().Err(reason).Int("documents", rejected).Msg("Failed to index documents")
+	}
+	return retry, nil
 }
 
-func (p *Producer) Close(_ context.Context) error {
-	log.Warn().Msg("elasticsearch Client doesn't provide a close method!")
-	return nil
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if rejected := p.rejected.Load(); rejected > 0 {
+		err = errors.Join(err, fmt.Errorf("%d documents rejected", rejected))
+	}
+	return err
 }
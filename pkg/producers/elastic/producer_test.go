@@ -0,0 +1,170 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package elastic
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCluster answers _bulk requests, with the status of each document
+// decided by status from its source
+type fakeCluster struct {
+	lock     sync.Mutex
+	product  string
+	status   func(source string) int
+	queries  []string
+	requests [][]string
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.product != "" {
+		w.Header().Set("X-Elastic-Product", f.product)
+	}
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var lines []string
+	response := bulkResponse{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		action := scanner.Text()
+		scanner.Scan()
+		source := scanner.Text()
+		lines = append(lines, action, source)
+
+		status := f.status(source)
+		item := bulkItem{Status: status}
+		if status >= 300 {
+			response.Errors = true
+			item.Error = &struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			}{Type: "mapper_parsing_exception", Reason: "failed to parse"}
+		}
+		response.Items = append(response.Items, map[string]bulkItem{"index": item})
+	}
+	f.queries = append(f.queries, r.URL.RawQuery)
+	f.requests = append(f.requests, lines)
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func TestBulk(t *testing.T) {
+	ctx := context.Background()
+	throttled := make(map[string]bool)
+	fake := &fakeCluster{
+		product: "Elasticsearch",
+		status: func(source string) int {
+			// the second document is throttled once
+			if strings.Contains(source, `"n":1`) && !throttled[source] {
+				throttled[source] = true
+				return http.StatusTooManyRequests
+			}
+			return http.StatusCreated
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{
+		ElasticURI:   server.URL,
+		ElasticIndex: "logs-{{.Value.app}}",
+		OpType:       "create",
+		Pipeline:     "enrich",
+		BatchSize:    2,
+	})
+	p.batcher.Backoff = time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf("{\n  \"app\": \"a%d\",\n  \"n\": %d\n}", i%2, i)), nil)
+	}
+	p.Produce(ctx, []byte("null"), []byte(`{"app":"a0","n":3}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]string{
+		{
+			`{"create":{"_index":"logs-a0","_id":"k0"}}`, `{"app":"a0","n":0}`,
+			`{"create":{"_index":"logs-a1","_id":"k1"}}`, `{"app":"a1","n":1}`,
+		},
+		{
+			`{"create":{"_index":"logs-a1","_id":"k1"}}`, `{"app":"a1","n":1}`,
+		},
+		{
+			`{"create":{"_index":"logs-a0","_id":"k2"}}`, `{"app":"a0","n":2}`,
+			`{"create":{"_index":"logs-a0"}}`, `{"app":"a0","n":3}`,
+		},
+	}
+	if !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got %v, want %v", fake.requests, want)
+	}
+	if fake.queries[0] != "pipeline=enrich" {
+		t.Errorf("got query %q", fake.queries[0])
+	}
+}
+
+func TestBulkOpenSearch(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeCluster{
+		status: func(source string) int {
+			if strings.Contains(source, "invalid") {
+				return http.StatusBadRequest
+			}
+			return http.StatusCreated
+		},
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{ElasticURI: server.URL, ElasticIndex: "jr", Mode: OpenSearch, Refresh: "wait_for"})
+	p.Produce(ctx, nil, []byte(`{"n":1}`), nil)
+	p.Produce(ctx, nil, []byte(`{"n":"invalid"}`), nil)
+	p.Produce(ctx, nil, []byte(`not json`), nil)
+
+	err := p.Close(ctx)
+	if err == nil || !strings.Contains(err.Error(), "1 documents rejected") {
+		t.Errorf("expected 1 rejected document, got %v", err)
+	}
+	if len(fake.requests) != 1 || len(fake.requests[0]) != 4 {
+		t.Errorf("expected a request with 2 documents, got %v", fake.requests)
+	}
+	if fake.queries[0] != "refresh=wait_for" {
+		t.Errorf("got query %q", fake.queries[0])
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import "github.com/jrnd-io/jr/pkg/producers/format"
+
+const DefaultFileName = "jr-{{.Timestamp}}-{{.Index}}"
+
+type Config struct {
+	// Directory where files are written, created if missing
+	Directory string `json:"directory"`
+	// FileName is a template evaluated at each rotation: .Index, .Time and .Timestamp are available,
+	// together with all the JR functions. Defaults to DefaultFileName with the extension of the format
+	FileName string `json:"file_name"`
+	// MaxRecords rolls the file after this number of records
+	MaxRecords int `json:"max_records"`
+	// MaxBytes rolls the file when the rendered records written reach this size
+	MaxBytes int64 `json:"max_bytes"`
+	// RollInterval rolls the file when it has been open for this duration, e.g. "5m"
+	RollInterval string `json:"roll_interval"`
+	// format, compression and the settings of the format
+	format.Config
+}
@@ -0,0 +1,276 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/functions"
+	"github.com/jrnd-io/jr/pkg/producers/format"
+	"github.com/jrnd-io/jr/pkg/tpl"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer writes records to rolling files. Each file is written under a temporary name
+// and renamed to its final name only when complete, so readers never see partial files.
+type Producer struct {
+	// Format, when set, overrides the format of the configuration
+	Format string
+	// Schema is the Avro schema used when the configuration has no schema file
+	Schema string
+
+	configuration Config
+	fileName      tpl.Tpl
+	rollInterval  time.Duration
+
+	lock       sync.Mutex
+	index      int
+	current    *rollingFile
+	stopTicker chan struct{}
+	tickerDone chan struct{}
+}
+
+type rollingFile struct {
+	file    *os.File
+	encoder format.Encoder
+	tmpPath string
+	path    string
+	opened  time.Time
+	records int
+	bytes   int64
+}
+
+type fileNameData struct {
+	Index     int
+	Time      time.Time
+	Timestamp string
+}
+
+// Initialize reads the configuration file; without a file, the defaults are used
+func (p *Producer) Initialize(configFile string) {
+	config := Config{}
+	if configFile != "" {
+		cfgBytes, err := os.ReadFile(configFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read config file")
+		}
+		if err := json.Unmarshal(cfgBytes, &config); err != nil {
+			log.Fatal().Err(err).Msg("Failed to unmarshal config")
+		}
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+	if p.Format != "" {
+		p.configuration.Format = p.Format
+	}
+	if p.configuration.SchemaFile == "" {
+		p.configuration.Schema = p.Schema
+	}
+
+	if p.configuration.Directory == "" {
+		p.configuration.Directory = "."
+	}
+	if err = os.MkdirAll(p.configuration.Directory, 0755); err != nil {
+		log.Fatal().Err(err).Str("directory", p.configuration.Directory).Msg("Failed to create directory")
+	}
+
+	if err = p.configuration.Validate(); err != nil {
+		log.Fatal().Err(err).Msg("Invalid file format")
+	}
+
+	if p.configuration.FileName == "" {
+		p.configuration.FileName = DefaultFileName + p.configuration.Extension()
+	}
+	p.fileName, err = tpl.NewTpl("file_name", p.configuration.FileName, functions.FunctionsMap(), nil)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse file_name template")
+	}
+
+	if p.configuration.RollInterval != "" {
+		p.rollInterval, err = time.ParseDuration(p.configuration.RollInterval)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse roll_interval")
+		}
+		if p.rollInterval <= 0 {
+			log.Fatal().Str("roll_interval", p.configuration.RollInterval).Msg("roll_interval must be positive")
+		}
+		p.stopTicker = make(chan struct{})
+		p.tickerDone = make(chan struct{})
+		go p.rollOnInterval()
+	}
+}
+
+func (p *Producer) Produce(_ context.Context, _ []byte, v []byte, _ any) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.current == nil {
+		if err := p.open(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to create file")
+		}
+	}
+
+	if err := p.current.write(v); err != nil {
+		log.Error().Err(err).Str("file", p.current.path).Msg("Failed to write record")
+		return
+	}
+
+	if p.shouldRoll() {
+		if err := p.roll(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to roll file")
+		}
+	}
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	if p.stopTicker != nil {
+		close(p.stopTicker)
+		<-p.tickerDone
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.roll()
+}
+
+func (p *Producer) shouldRoll() bool {
+	c := p.current
+	return (p.configuration.MaxRecords > 0 && c.records >= p.configuration.MaxRecords) ||
+		(p.configuration.MaxBytes > 0 && c.bytes >= p.configuration.MaxBytes) ||
+		(p.rollInterval > 0 && time.Since(c.opened) >= p.rollInterval)
+}
+
+func (p *Producer) rollOnInterval() {
+	defer close(p.tickerDone)
+
+	ticker := time.NewTicker(max(p.rollInterval/2, time.Nanosecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopTicker:
+			return
+		case <-ticker.C:
+			p.lock.Lock()
+			if p.current != nil && time.Since(p.current.opened) >= p.rollInterval {
+				if err := p.roll(); err != nil {
+					log.Error().Err(err).Msg("Failed to roll file")
+				}
+			}
+			p.lock.Unlock()
+		}
+	}
+}
+
+func (p *Producer) open() error {
+	now := time.Now().UTC()
+	name := p.fileName.ExecuteWith(fileNameData{
+		Index:     p.index,
+		Time:      now,
+		Timestamp: now.Format("20060102T150405Z"),
+	})
+	name = withExtension(name, p.configuration.CompressionExtension())
+	p.index++
+
+	path := filepath.Join(p.configuration.Directory, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		log.Warn().Str("file", path).Msg("File already exists and will be overwritten")
+	}
+
+	// the temporary file lives in the same directory so that the final rename is atomic
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// temporary files are created private
+	if err := f.Chmod(0644); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	encoder, err := format.NewEncoder(f, p.configuration.Config)
+	if err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	p.current = &rollingFile{file: f, encoder: encoder, tmpPath: f.Name(), path: path, opened: time.Now()}
+	return nil
+}
+
+// roll completes the current file, if any, and moves it to its final name
+func (p *Producer) roll() error {
+	c := p.current
+	if c == nil {
+		return nil
+	}
+	p.current = nil
+
+	if err := c.complete(); err != nil {
+		_ = os.Remove(c.tmpPath)
+		return fmt.Errorf("failed to complete %s: %w", c.path, err)
+	}
+	return os.Rename(c.tmpPath, c.path)
+}
+
+func (c *rollingFile) write(v []byte) error {
+	if err := c.encoder.Encode(v); err != nil {
+		return err
+	}
+	c.records++
+	c.bytes += int64(len(v))
+	return nil
+}
+
+func (c *rollingFile) complete() error {
+	if err := c.encoder.Close(); err != nil {
+		_ = c.file.Close()
+		return err
+	}
+	if err := c.file.Sync(); err != nil {
+		_ = c.file.Close()
+		return err
+	}
+	return c.file.Close()
+}
+
+func withExtension(name string, ext string) string {
+	if strings.HasSuffix(name, ext) {
+		return name
+	}
+	return name + ext
+}
@@ -0,0 +1,237 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package file_test
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/file"
+	"github.com/jrnd-io/jr/pkg/producers/format"
+	"github.com/klauspost/compress/zstd"
+	"github.com/parquet-go/parquet-go"
+)
+
+func produce(t *testing.T, p *file.Producer, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		p.Produce(context.Background(), nil, []byte(fmt.Sprintf(`{"n": %d}`, i)), nil)
+	}
+}
+
+func listFiles(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	case strings.HasSuffix(path, ".zst"):
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRotation(t *testing.T) {
+
+	testCases := []struct {
+		name      string
+		config    file.Config
+		records   int
+		wantFiles []string
+	}{
+		{
+			name:      "single_file",
+			config:    file.Config{FileName: "out-{{.Index}}.json"},
+			records:   5,
+			wantFiles: []string{"out-0.json"},
+		},
+		{
+			name:      "max_records",
+			config:    file.Config{FileName: "out-{{.Index}}.json", MaxRecords: 2},
+			records:   5,
+			wantFiles: []string{"out-0.json", "out-1.json", "out-2.json"},
+		},
+		{
+			// each record is 8 bytes
+			name:      "max_bytes",
+			config:    file.Config{FileName: "out-{{.Index}}.json", MaxBytes: 20},
+			records:   6,
+			wantFiles: []string{"out-0.json", "out-1.json"},
+		},
+		{
+			// the shortest interval rolls after each record
+			name:      "roll_interval",
+			config:    file.Config{FileName: "out-{{.Index}}.json", RollInterval: "1ns"},
+			records:   2,
+			wantFiles: []string{"out-0.json", "out-1.json"},
+		},
+		{
+			name:      "gzip",
+			config:    file.Config{FileName: "out-{{.Index}}.json", MaxRecords: 3, Config: format.Config{Compression: format.GzipCompression}},
+			records:   4,
+			wantFiles: []string{"out-0.json.gz", "out-1.json.gz"},
+		},
+		{
+			name:      "zstd_in_subdirectory",
+			config:    file.Config{FileName: "{{.Time.Format \"2006\"}}/out-{{.Index}}.json", Config: format.Config{Compression: format.ZstdCompression}},
+			records:   3,
+			wantFiles: []string{fmt.Sprintf("%d/out-0.json.zst", time.Now().UTC().Year())},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tc.config.Directory = dir
+
+			p := &file.Producer{}
+			p.InitializeFromConfig(tc.config)
+			produce(t, p, tc.records)
+			if err := p.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			files := listFiles(t, dir)
+			if strings.Join(files, ",") != strings.Join(tc.wantFiles, ",") {
+				t.Fatalf("expected files %v, got %v", tc.wantFiles, files)
+			}
+
+			var content strings.Builder
+			for _, f := range files {
+				content.WriteString(readFile(t, filepath.Join(dir, f)))
+			}
+			for i := 0; i < tc.records; i++ {
+				if !strings.Contains(content.String(), fmt.Sprintf("{\"n\": %d}\n", i)) {
+					t.Errorf("record %d is missing", i)
+				}
+			}
+		})
+	}
+}
+
+func TestAtomicWrite(t *testing.T) {
+
+	dir := t.TempDir()
+	delimiter := ","
+	p := &file.Producer{}
+	p.InitializeFromConfig(file.Config{Directory: dir, FileName: "out.json", RollInterval: "100ms", Config: format.Config{Delimiter: &delimiter}})
+
+	produce(t, p, 2)
+	files := listFiles(t, dir)
+	if len(files) != 1 || !strings.HasPrefix(files[0], ".out.json.") || !strings.HasSuffix(files[0], ".tmp") {
+		t.Fatalf("expected only a temporary file while writing, got %v", files)
+	}
+
+	// the interval roll completes the file even without new records
+	time.Sleep(300 * time.Millisecond)
+	files = listFiles(t, dir)
+	if len(files) != 1 || files[0] != "out.json" {
+		t.Fatalf("expected the completed file, got %v", files)
+	}
+	if got := readFile(t, filepath.Join(dir, "out.json")); got != `{"n": 0},{"n": 1},` {
+		t.Errorf("unexpected content %q", got)
+	}
+
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParquetFiles(t *testing.T) {
+
+	dir := t.TempDir()
+	p := &file.Producer{}
+	p.InitializeFromConfig(file.Config{Directory: dir, MaxRecords: 3, Config: format.Config{Format: format.Parquet}})
+	produce(t, p, 7)
+	if err := p.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	files := listFiles(t, dir)
+	if len(files) != 3 {
+		t.Fatalf("expected 3 files, got %v", files)
+	}
+	var rows int64
+	for _, name := range files {
+		if !strings.HasSuffix(name, ".parquet") {
+			t.Errorf("unexpected file name %s", name)
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, _ := f.Stat()
+		pf, err := parquet.OpenFile(f, info.Size())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows += pf.NumRows()
+		f.Close()
+	}
+	if rows != 7 {
+		t.Errorf("expected 7 rows, got %d", rows)
+	}
+}
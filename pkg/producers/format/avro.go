@@ -0,0 +1,80 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"io"
+
+	"github.com/hamba/avro/v2"
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/jrnd-io/jr/pkg/schema"
+)
+
+// avroEncoder writes records to an Avro Object Container File
+type avroEncoder struct {
+	schema  avro.Schema
+	encoder *ocf.Encoder
+}
+
+func newAvroEncoder(w io.Writer, config Config) (Encoder, error) {
+	var s avro.Schema
+	var err error
+	if config.SchemaFile != "" {
+		s, err = schema.LoadAvro(config.SchemaFile)
+	} else {
+		s, err = avro.Parse(config.Schema)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	codec := ocf.Deflate
+	switch config.Compression {
+	case NoCompression:
+		codec = ocf.Null
+	case SnappyCompression:
+		codec = ocf.Snappy
+	case ZstdCompression:
+		codec = ocf.ZStandard
+	}
+
+	options := []ocf.EncoderFunc{ocf.WithCodec(codec)}
+	if config.BlockSize > 0 {
+		options = append(options, ocf.WithBlockLength(config.BlockSize))
+	}
+	encoder, err := ocf.NewEncoderWithSchema(s, w, options...)
+	if err != nil {
+		return nil, err
+	}
+	return &avroEncoder{schema: s, encoder: encoder}, nil
+}
+
+func (e *avroEncoder) Encode(value []byte) error {
+	v, err := schema.AvroNative(e.schema, value)
+	if err != nil {
+		return err
+	}
+	return e.encoder.Encode(v)
+}
+
+func (e *avroEncoder) Close() error {
+	return e.encoder.Close()
+}
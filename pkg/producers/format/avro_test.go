@@ -0,0 +1,97 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hamba/avro/v2/ocf"
+	"github.com/jrnd-io/jr/pkg/producers/format"
+)
+
+func TestAvroContainerFile(t *testing.T) {
+
+	testCases := []struct {
+		name        string
+		compression string
+		wantCodec   string
+	}{
+		{name: "default", compression: "", wantCodec: "deflate"},
+		{name: "none", compression: format.NoCompression, wantCodec: "null"},
+		{name: "snappy", compression: format.SnappyCompression, wantCodec: "snappy"},
+		{name: "zstd", compression: format.ZstdCompression, wantCodec: "zstandard"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config := format.Config{Format: format.Avro, Compression: tc.compression, Schema: orderSchema, BlockSize: 2}
+			if err := config.Validate(); err != nil {
+				t.Fatal(err)
+			}
+
+			var buf bytes.Buffer
+			e, err := format.NewEncoder(&buf, config)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, o := range orders {
+				if err := e.Encode([]byte(o)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := e.Encode([]byte(`{"id": "o-4", "status": "LOST"}`)); err == nil {
+				t.Error("expected an error for an invalid record")
+			}
+			if err := e.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			dec, err := ocf.NewDecoder(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if codec := string(dec.Metadata()["avro.codec"]); codec != tc.wantCodec {
+				t.Errorf("expected codec %s, got %s", tc.wantCodec, codec)
+			}
+
+			var ids []string
+			for dec.HasNext() {
+				var record map[string]any
+				if err := dec.Decode(&record); err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, record["id"].(string))
+			}
+			if err := dec.Error(); err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Join(ids, ","); got != "o-1,o-2,o-3" {
+				t.Errorf("unexpected records %s", got)
+			}
+		})
+	}
+
+	if err := (&format.Config{Format: format.Avro}).Validate(); err == nil {
+		t.Error("expected an error without schema")
+	}
+}
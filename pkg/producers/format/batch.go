@@ -0,0 +1,91 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const DefaultBatchRecords = 10000
+
+// BatchConfig configures the object store producers writing batches of records as objects
+type BatchConfig struct {
+	Config
+	// MaxRecords is the number of records of each object
+	MaxRecords int `json:"max_records"`
+	// Prefix is prepended to the generated object names
+	Prefix string `json:"prefix"`
+}
+
+// Batch encodes records in memory until a batch is complete
+type Batch struct {
+	config  BatchConfig
+	buffer  bytes.Buffer
+	encoder Encoder
+	records int
+}
+
+func NewBatch(config BatchConfig) (*Batch, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.MaxRecords <= 0 {
+		config.MaxRecords = DefaultBatchRecords
+	}
+	return &Batch{config: config}, nil
+}
+
+// Add encodes a record, returning true when the batch is full and must be flushed
+func (b *Batch) Add(value []byte) (bool, error) {
+	if b.encoder == nil {
+		var err error
+		b.buffer.Reset()
+		b.encoder, err = NewEncoder(&b.buffer, b.config.Config)
+		if err != nil {
+			return false, err
+		}
+	}
+	if err := b.encoder.Encode(value); err != nil {
+		return false, err
+	}
+	b.records++
+	return b.records >= b.config.MaxRecords, nil
+}
+
+// Flush completes the batch, returning the object name and content. The content is nil if the batch is empty.
+func (b *Batch) Flush() (string, []byte, error) {
+	if b.encoder == nil {
+		return "", nil, nil
+	}
+	err := b.encoder.Close()
+	b.encoder = nil
+	b.records = 0
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := b.config.Prefix + time.Now().UTC().Format("20060102T150405Z") + "-" + uuid.New().String() + b.config.Extension()
+	content := bytes.Clone(b.buffer.Bytes())
+	return name, content, nil
+}
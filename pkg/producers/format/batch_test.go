@@ -0,0 +1,76 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/jrnd-io/jr/pkg/producers/format"
+)
+
+func TestBatch(t *testing.T) {
+
+	b, err := format.NewBatch(format.BatchConfig{
+		Config:     format.Config{Format: format.Lines, Compression: format.GzipCompression},
+		MaxRecords: 2,
+		Prefix:     "shoes/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []bool{false, true} {
+		full, err := b.Add([]byte(`{"n": 1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if full != want {
+			t.Errorf("record %d: expected full %v", i, want)
+		}
+	}
+
+	name, content, err := b.Flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(name, "shoes/") || !strings.HasSuffix(name, ".json.gz") {
+		t.Errorf("unexpected object name %s", name)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "{\"n\": 1}\n{\"n\": 1}\n" {
+		t.Errorf("unexpected content %q", decoded)
+	}
+
+	if _, content, _ := b.Flush(); content != nil {
+		t.Error("expected no content for an empty batch")
+	}
+}
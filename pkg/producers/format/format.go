@@ -0,0 +1,185 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package format encodes rendered records into the file formats written by the file and object store producers.
+package format
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	Lines   = "lines"
+	Parquet = "parquet"
+	Avro    = "avro"
+
+	NoCompression      = "none"
+	GzipCompression    = "gzip"
+	ZstdCompression    = "zstd"
+	SnappyCompression  = "snappy"
+	DeflateCompression = "deflate"
+
+	DefaultDelimiter    = "\n"
+	DefaultRowGroupSize = 10000
+)
+
+type Config struct {
+	// Format is one of lines (the default), parquet, avro
+	Format string `json:"format"`
+	// Compression is none, gzip or zstd for lines, none, snappy (the default), gzip or zstd for parquet
+	// and none, deflate (the default), snappy or zstd for avro
+	Compression string `json:"compression"`
+	// Delimiter is written after each record in lines format
+	Delimiter *string `json:"delimiter"`
+	// SchemaFile is the .avsc describing the records: for parquet the schema is inferred from the first record when empty
+	SchemaFile string `json:"schema_file"`
+	// Schema is the Avro schema itself, used when SchemaFile is empty
+	Schema string `json:"-"`
+	// RowGroupSize is the max number of rows of parquet row groups
+	RowGroupSize int64 `json:"row_group_size"`
+	// BlockSize is the number of records of each Avro block
+	BlockSize int `json:"block_size"`
+}
+
+// Encoder writes records to an output
+type Encoder interface {
+	Encode(value []byte) error
+	// Close completes the output, without closing the underlying writer
+	Close() error
+}
+
+// Validate checks the configuration, setting the defaults
+func (c *Config) Validate() error {
+	if c.Format == "" {
+		c.Format = Lines
+	}
+
+	var compressions []string
+	switch c.Format {
+	case Lines:
+		compressions = []string{NoCompression, GzipCompression, ZstdCompression}
+	case Parquet:
+		compressions = []string{SnappyCompression, NoCompression, GzipCompression, ZstdCompression}
+	case Avro:
+		if c.SchemaFile == "" && c.Schema == "" {
+			return errors.New("avro format requires a schema file")
+		}
+		compressions = []string{DeflateCompression, NoCompression, SnappyCompression, ZstdCompression}
+	default:
+		return fmt.Errorf("unknown format %q, must be one of %s, %s, %s", c.Format, Lines, Parquet, Avro)
+	}
+
+	if c.Compression == "" {
+		c.Compression = compressions[0]
+	}
+	for _, compression := range compressions {
+		if c.Compression == compression {
+			return nil
+		}
+	}
+	return fmt.Errorf("compression %q not supported with %s format, must be one of %v", c.Compression, c.Format, compressions)
+}
+
+// Extension is the file extension matching the format and compression
+func (c *Config) Extension() string {
+	switch c.Format {
+	case Parquet:
+		return ".parquet"
+	case Avro:
+		return ".avro"
+	default:
+		return ".json" + c.CompressionExtension()
+	}
+}
+
+// CompressionExtension is the extension added by the compression of the lines format
+func (c *Config) CompressionExtension() string {
+	if c.Format != Lines && c.Format != "" {
+		return ""
+	}
+	switch c.Compression {
+	case GzipCompression:
+		return ".gz"
+	case ZstdCompression:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+// NewEncoder creates an encoder writing to w. The configuration must have been validated.
+func NewEncoder(w io.Writer, config Config) (Encoder, error) {
+	switch config.Format {
+	case Parquet:
+		return newParquetEncoder(w, config)
+	case Avro:
+		return newAvroEncoder(w, config)
+	default:
+		return newLinesEncoder(w, config)
+	}
+}
+
+type linesEncoder struct {
+	writer     io.Writer
+	compressor io.WriteCloser
+	delimiter  []byte
+}
+
+func newLinesEncoder(w io.Writer, config Config) (Encoder, error) {
+	e := &linesEncoder{writer: w, delimiter: []byte(DefaultDelimiter)}
+	if config.Delimiter != nil {
+		e.delimiter = []byte(*config.Delimiter)
+	}
+
+	switch config.Compression {
+	case GzipCompression:
+		e.compressor = gzip.NewWriter(w)
+	case ZstdCompression:
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		e.compressor = zw
+	}
+	if e.compressor != nil {
+		e.writer = e.compressor
+	}
+	return e, nil
+}
+
+func (e *linesEncoder) Encode(value []byte) error {
+	if _, err := e.writer.Write(value); err != nil {
+		return err
+	}
+	_, err := e.writer.Write(e.delimiter)
+	return err
+}
+
+func (e *linesEncoder) Close() error {
+	if e.compressor != nil {
+		return e.compressor.Close()
+	}
+	return nil
+}
@@ -0,0 +1,613 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/jrnd-io/jr/pkg/schema"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+)
+
+// parquetEncoder writes records as rows of a parquet file. Without a schema file,
+// the schema is inferred from the first record, with all the columns optional.
+type parquetEncoder struct {
+	output  io.Writer
+	options []parquet.WriterOption
+	schema  *parquet.Schema
+	writer  *parquet.Writer
+	columns [][]parquet.Value
+}
+
+func newParquetEncoder(w io.Writer, config Config) (Encoder, error) {
+	var codec compress.Codec
+	switch config.Compression {
+	case NoCompression:
+		codec = &parquet.Uncompressed
+	case GzipCompression:
+		codec = &parquet.Gzip
+	case ZstdCompression:
+		codec = &parquet.Zstd
+	default:
+		codec = &parquet.Snappy
+	}
+
+	rowGroupSize := config.RowGroupSize
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	e := &parquetEncoder{
+		output: w,
+		options: []parquet.WriterOption{
+			parquet.Compression(codec),
+			parquet.MaxRowsPerRowGroup(rowGroupSize),
+			parquet.CreatedBy("jr", "", ""),
+		},
+	}
+
+	if config.SchemaFile != "" {
+		s, err := schema.LoadAvro(config.SchemaFile)
+		if err != nil {
+			return nil, err
+		}
+		e.schema, err = ParquetSchemaOf(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func (e *parquetEncoder) Encode(value []byte) error {
+	var v any
+	if err := schema.DecodeJSON(value, &v); err != nil {
+		return err
+	}
+
+	if e.writer == nil {
+		if e.schema == nil {
+			record, ok := v.(map[string]any)
+			if !ok {
+				return fmt.Errorf("cannot infer a parquet schema from %s, records must be JSON objects", jsonKind(v))
+			}
+			e.schema = parquet.NewSchema("record", inferGroup(record))
+		}
+		e.writer = parquet.NewWriter(e.output, append(e.options, e.schema)...)
+		e.columns = make([][]parquet.Value, len(e.schema.Columns()))
+	}
+
+	for i := range e.columns {
+		e.columns[i] = e.columns[i][:0]
+	}
+	if _, err := e.deconstruct(e.schema, v, "", 0, 0, 0, 0); err != nil {
+		return err
+	}
+
+	row := make(parquet.Row, 0, len(e.columns))
+	for _, values := range e.columns {
+		row = append(row, values...)
+	}
+	_, err := e.writer.WriteRows([]parquet.Row{row})
+	return err
+}
+
+func (e *parquetEncoder) Close() error {
+	if e.writer == nil {
+		return nil
+	}
+	return e.writer.Close()
+}
+
+// deconstruct appends to the columns the values of v, computing repetition and definition levels.
+// It returns the index of the column following the leaves of node.
+func (e *parquetEncoder) deconstruct(node parquet.Node, v any, path string, column int, rep int, def int, depth int) (int, error) {
+	switch {
+	case node.Optional():
+		if v == nil {
+			return e.nulls(node, column, rep, def), nil
+		}
+		return e.deconstruct(parquet.Required(node), v, path, column, rep, def+1, depth)
+
+	case isLogicalList(node):
+		items, ok := v.([]any)
+		if !ok {
+			return 0, fmt.Errorf("%s: expected array, got %s", fieldName(path), jsonKind(v))
+		}
+		if len(items) == 0 {
+			return e.nulls(node, column, rep, def), nil
+		}
+		element := node.Fields()[0].Fields()[0]
+		for i, item := range items {
+			r := rep
+			if i > 0 {
+				r = depth + 1
+			}
+			if _, err := e.deconstruct(element, item, fmt.Sprintf("%s[%d]", path, i), column, r, def+1, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return column + leaves(node), nil
+
+	case isLogicalMap(node):
+		m, ok := v.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("%s: expected object, got %s", fieldName(path), jsonKind(v))
+		}
+		if len(m) == 0 {
+			return e.nulls(node, column, rep, def), nil
+		}
+		keyValue := node.Fields()[0].Fields()
+		keys := make([]string, 0, len(m))
+		for k := range m {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for i, k := range keys {
+			r := rep
+			if i > 0 {
+				r = depth + 1
+			}
+			next, err := e.deconstruct(keyValue[0], k, path, column, r, def+1, depth+1)
+			if err != nil {
+				return 0, err
+			}
+			if _, err := e.deconstruct(keyValue[1], m[k], joinPath(path, k), next, r, def+1, depth+1); err != nil {
+				return 0, err
+			}
+		}
+		return column + leaves(node), nil
+
+	case !node.Leaf():
+		m, ok := v.(map[string]any)
+		if !ok {
+			return 0, fmt.Errorf("%s: expected object, got %s", fieldName(path), jsonKind(v))
+		}
+		var err error
+		for _, field := range node.Fields() {
+			column, err = e.deconstruct(field, m[field.Name()], joinPath(path, field.Name()), column, rep, def, depth)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return column, nil
+	}
+
+	if v == nil {
+		return 0, fmt.Errorf("%s: missing required field", fieldName(path))
+	}
+	value, err := leafValue(node.Type(), v)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", fieldName(path), err)
+	}
+	e.columns[column] = append(e.columns[column], value.Level(rep, def, column))
+	return column + 1, nil
+}
+
+func (e *parquetEncoder) nulls(node parquet.Node, column int, rep int, def int) int {
+	n := leaves(node)
+	for i := column; i < column+n; i++ {
+		e.columns[i] = append(e.columns[i], parquet.NullValue().Level(rep, def, i))
+	}
+	return column + n
+}
+
+func leaves(node parquet.Node) int {
+	if node.Leaf() {
+		return 1
+	}
+	n := 0
+	for _, field := range node.Fields() {
+		n += leaves(field)
+	}
+	return n
+}
+
+func isLogicalList(node parquet.Node) bool {
+	lt := node.Type().LogicalType()
+	return !node.Leaf() && lt != nil && lt.List != nil
+}
+
+func isLogicalMap(node parquet.Node) bool {
+	lt := node.Type().LogicalType()
+	return !node.Leaf() && lt != nil && lt.Map != nil
+}
+
+func leafValue(t parquet.Type, v any) (parquet.Value, error) {
+	lt := t.LogicalType()
+
+	switch t.Kind() {
+	case parquet.Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return parquet.Value{}, fmt.Errorf("expected boolean, got %s", jsonKind(v))
+		}
+		return parquet.BooleanValue(b), nil
+
+	case parquet.Int32:
+		if str, ok := v.(string); ok && lt != nil && lt.Date != nil {
+			d, err := time.Parse(time.DateOnly, str)
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			return parquet.Int32Value(int32(d.Unix() / 86400)), nil
+		}
+		i, err := toInt64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return parquet.Value{}, fmt.Errorf("%d overflows int32", i)
+		}
+		return parquet.Int32Value(int32(i)), nil
+
+	case parquet.Int64:
+		if str, ok := v.(string); ok && lt != nil && lt.Timestamp != nil {
+			ts, err := schema.ParseTimestamp(str)
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			switch {
+			case lt.Timestamp.Unit.Micros != nil:
+				return parquet.Int64Value(ts.UnixMicro()), nil
+			case lt.Timestamp.Unit.Nanos != nil:
+				return parquet.Int64Value(ts.UnixNano()), nil
+			default:
+				return parquet.Int64Value(ts.UnixMilli()), nil
+			}
+		}
+		i, err := toInt64(v)
+		if err != nil {
+			return parquet.Value{}, err
+		}
+		return parquet.Int64Value(i), nil
+
+	case parquet.Float:
+		f, err := toFloat64(v)
+		return parquet.FloatValue(float32(f)), err
+
+	case parquet.Double:
+		f, err := toFloat64(v)
+		return parquet.DoubleValue(f), err
+
+	case parquet.ByteArray:
+		return parquet.ByteArrayValue(toBytes(v)), nil
+
+	case parquet.FixedLenByteArray:
+		if lt != nil && lt.Decimal != nil {
+			unscaled, err := toUnscaled(v, int(lt.Decimal.Scale))
+			if err != nil {
+				return parquet.Value{}, err
+			}
+			b := twosComplement(unscaled, t.Length())
+			if len(b) > t.Length() {
+				return parquet.Value{}, fmt.Errorf("%s overflows fixed[%d]", unscaled, t.Length())
+			}
+			return parquet.FixedLenByteArrayValue(b), nil
+		}
+		b := toBytes(v)
+		if len(b) != t.Length() {
+			return parquet.Value{}, fmt.Errorf("expected %d bytes, got %d", t.Length(), len(b))
+		}
+		return parquet.FixedLenByteArrayValue(b), nil
+	}
+
+	return parquet.Value{}, fmt.Errorf("unsupported parquet type %s", t)
+}
+
+func toInt64(v any) (int64, error) {
+	var n json.Number
+	switch value := v.(type) {
+	case json.Number:
+		n = value
+	case string:
+		n = json.Number(value)
+	default:
+		return 0, fmt.Errorf("expected integer, got %s", jsonKind(v))
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil || f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected integer, got %s", jsonKind(v))
+	}
+	return int64(f), nil
+}
+
+func toFloat64(v any) (float64, error) {
+	switch value := v.(type) {
+	case json.Number:
+		return value.Float64()
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected number, got %s", jsonKind(v))
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("expected number, got %s", jsonKind(v))
+	}
+}
+
+// toBytes returns strings as they are, and the JSON text of any other value
+func toBytes(v any) []byte {
+	switch value := v.(type) {
+	case string:
+		return []byte(value)
+	case json.Number:
+		return []byte(value.String())
+	default:
+		b, _ := json.Marshal(value)
+		return b
+	}
+}
+
+func toUnscaled(v any, scale int) (*big.Int, error) {
+	var str string
+	switch value := v.(type) {
+	case json.Number:
+		str = value.String()
+	case string:
+		str = value
+	default:
+		return nil, fmt.Errorf("expected decimal, got %s", jsonKind(v))
+	}
+	r, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return nil, fmt.Errorf("expected decimal, got %q", str)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !r.IsInt() {
+		return nil, fmt.Errorf("%s has more than %d decimal digits", str, scale)
+	}
+	return r.Num(), nil
+}
+
+// twosComplement returns the big-endian two's complement of i, sign extended to size bytes
+func twosComplement(i *big.Int, size int) []byte {
+	n := i.BitLen()/8 + 1
+	if n < size {
+		n = size
+	}
+	b := make([]byte, n)
+	if i.Sign() >= 0 {
+		i.FillBytes(b)
+		return b
+	}
+	// 2^(8n) + i
+	c := new(big.Int).Add(new(big.Int).Lsh(big.NewInt(1), uint(8*n)), i)
+	c.FillBytes(b)
+	return b
+}
+
+func inferGroup(record map[string]any) parquet.Group {
+	group := parquet.Group{}
+	for name, value := range record {
+		group[name] = parquet.Optional(inferNode(value))
+	}
+	return group
+}
+
+func inferNode(v any) parquet.Node {
+	switch value := v.(type) {
+	case bool:
+		return parquet.Leaf(parquet.BooleanType)
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return parquet.Int(64)
+		}
+		return parquet.Leaf(parquet.DoubleType)
+	case map[string]any:
+		if len(value) > 0 {
+			return inferGroup(value)
+		}
+	case []any:
+		if len(value) > 0 {
+			return parquet.List(parquet.Optional(inferNode(value[0])))
+		}
+	}
+	// strings, and values whose type cannot be inferred, are written as text
+	return parquet.String()
+}
+
+// ParquetSchemaOf converts an Avro record schema to a parquet schema
+func ParquetSchemaOf(s avro.Schema) (*parquet.Schema, error) {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+	rs, ok := s.(*avro.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("parquet schema must be an Avro record, got %s", s.Type())
+	}
+	node, err := parquetNodeOf(rs, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	return parquet.NewSchema(rs.Name(), node), nil
+}
+
+func parquetNodeOf(s avro.Schema, visiting map[string]bool) (parquet.Node, error) {
+	switch s.Type() {
+	case avro.Ref:
+		return parquetNodeOf(s.(*avro.RefSchema).Schema(), visiting)
+
+	case avro.Record:
+		rs := s.(*avro.RecordSchema)
+		if visiting[rs.FullName()] {
+			return nil, fmt.Errorf("recursive record %s not supported in parquet", rs.FullName())
+		}
+		visiting[rs.FullName()] = true
+		defer delete(visiting, rs.FullName())
+
+		group := parquet.Group{}
+		for _, f := range rs.Fields() {
+			node, err := parquetNodeOf(f.Type(), visiting)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Name(), err)
+			}
+			// missing fields with a default are written as null
+			if f.HasDefault() && !node.Optional() {
+				node = parquet.Optional(node)
+			}
+			group[f.Name()] = node
+		}
+		return group, nil
+
+	case avro.Union:
+		us := s.(*avro.UnionSchema)
+		types := us.Types()
+		if !us.Nullable() || len(types) != 2 {
+			return nil, fmt.Errorf("only unions of null and another type are supported in parquet")
+		}
+		other := types[0]
+		if other.Type() == avro.Null {
+			other = types[1]
+		}
+		node, err := parquetNodeOf(other, visiting)
+		if err != nil {
+			return nil, err
+		}
+		return parquet.Optional(node), nil
+
+	case avro.Array:
+		node, err := parquetNodeOf(s.(*avro.ArraySchema).Items(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return parquet.List(node), nil
+
+	case avro.Map:
+		node, err := parquetNodeOf(s.(*avro.MapSchema).Values(), visiting)
+		if err != nil {
+			return nil, err
+		}
+		return parquet.Map(parquet.String(), node), nil
+
+	case avro.Enum:
+		return parquet.Enum(), nil
+
+	case avro.String:
+		return parquet.String(), nil
+
+	case avro.Bytes:
+		if d, ok := avroDecimal(s); ok {
+			// parquet decimals are not annotated on variable length binaries: use the smallest fixed size for the precision
+			size := int(math.Ceil((float64(d.Precision())*math.Log2(10) + 1) / 8))
+			return parquet.Decimal(d.Scale(), d.Precision(), parquet.FixedLenByteArrayType(size)), nil
+		}
+		return parquet.Leaf(parquet.ByteArrayType), nil
+
+	case avro.Fixed:
+		fs := s.(*avro.FixedSchema)
+		if d, ok := avroDecimal(s); ok {
+			return parquet.Decimal(d.Scale(), d.Precision(), parquet.FixedLenByteArrayType(fs.Size())), nil
+		}
+		return parquet.Leaf(parquet.FixedLenByteArrayType(fs.Size())), nil
+
+	case avro.Boolean:
+		return parquet.Leaf(parquet.BooleanType), nil
+
+	case avro.Int:
+		if avroLogicalType(s) == avro.Date {
+			return parquet.Date(), nil
+		}
+		return parquet.Int(32), nil
+
+	case avro.Long:
+		switch avroLogicalType(s) {
+		case avro.TimestampMillis:
+			return parquet.Timestamp(parquet.Millisecond), nil
+		case avro.TimestampMicros:
+			return parquet.Timestamp(parquet.Microsecond), nil
+		case avro.LocalTimestampMillis:
+			return parquet.TimestampAdjusted(parquet.Millisecond, false), nil
+		case avro.LocalTimestampMicros:
+			return parquet.TimestampAdjusted(parquet.Microsecond, false), nil
+		}
+		return parquet.Int(64), nil
+
+	case avro.Float:
+		return parquet.Leaf(parquet.FloatType), nil
+
+	case avro.Double:
+		return parquet.Leaf(parquet.DoubleType), nil
+	}
+
+	return nil, fmt.Errorf("Avro type %s not supported in parquet", s.Type())
+}
+
+func avroLogicalType(s avro.Schema) avro.LogicalType {
+	if lts, ok := s.(avro.LogicalTypeSchema); ok && lts.Logical() != nil {
+		return lts.Logical().Type()
+	}
+	return ""
+}
+
+func avroDecimal(s avro.Schema) (*avro.DecimalLogicalSchema, bool) {
+	if lts, ok := s.(avro.LogicalTypeSchema); ok && lts.Logical() != nil {
+		d, ok := lts.Logical().(*avro.DecimalLogicalSchema)
+		return d, ok
+	}
+	return nil, false
+}
+
+func jsonKind(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number " + value.String()
+	case string:
+		return strconv.Quote(value)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func fieldName(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return fmt.Sprintf("field %q", path)
+}
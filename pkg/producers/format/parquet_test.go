@@ -0,0 +1,197 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package format_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrnd-io/jr/pkg/producers/format"
+	"github.com/parquet-go/parquet-go"
+)
+
+const orderSchema = `{
+  "type": "record",
+  "name": "Order",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "SHIPPED"]}},
+    {"name": "total", "type": {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}},
+    {"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "note", "type": ["null", "string"], "default": null},
+    {"name": "lines", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "Line",
+      "fields": [
+        {"name": "sku", "type": "string"},
+        {"name": "qty", "type": "int"}
+      ]
+    }}},
+    {"name": "tags", "type": {"type": "map", "values": "long"}, "default": {}}
+  ]
+}`
+
+var orders = []string{
+	`{"id": "o-1", "status": "NEW", "total": "21.50", "created_at": "2024-05-01T10:00:00Z", "note": "fragile", "lines": [{"sku": "A", "qty": 1}, {"sku": "B", "qty": 2}], "tags": {"x": 1}}`,
+	`{"id": "o-2", "status": "SHIPPED", "total": -3.1, "created_at": 1714557600000, "lines": []}`,
+	`{"id": "o-3", "status": "NEW", "total": "0", "created_at": 1714557600000, "note": null, "lines": [{"sku": "C", "qty": 3}]}`,
+}
+
+func encodeParquet(t *testing.T, config format.Config, records []string) *parquet.File {
+	t.Helper()
+	config.Format = format.Parquet
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	e, err := format.NewEncoder(&buf, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range records {
+		if err := e.Encode([]byte(r)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func readRows(t *testing.T, f *parquet.File) []string {
+	t.Helper()
+	r := parquet.NewReader(f)
+	defer r.Close()
+
+	var rows []string
+	for {
+		m := map[string]any{}
+		if err := r.Read(&m); err != nil {
+			if err == io.EOF {
+				return rows
+			}
+			t.Fatal(err)
+		}
+		rows = append(rows, fmt.Sprint(m))
+	}
+}
+
+func TestParquetWithSchema(t *testing.T) {
+
+	schemaFile := filepath.Join(t.TempDir(), "order.avsc")
+	if err := os.WriteFile(schemaFile, []byte(orderSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := encodeParquet(t, format.Config{SchemaFile: schemaFile, Compression: format.ZstdCompression, RowGroupSize: 2}, orders)
+
+	if f.NumRows() != 3 || len(f.RowGroups()) != 2 {
+		t.Fatalf("expected 3 rows in 2 row groups, got %d rows in %d", f.NumRows(), len(f.RowGroups()))
+	}
+	if len(f.Schema().Fields()) != 7 {
+		t.Errorf("unexpected schema %s", f.Schema())
+	}
+
+	rows := readRows(t, f)
+	if !strings.Contains(rows[0], "sku:B") || !strings.Contains(rows[0], "note:fragile") {
+		t.Errorf("unexpected row %s", rows[0])
+	}
+	if !strings.Contains(rows[0], "created_at:1714557600000") || !strings.Contains(rows[1], "note:<nil>") {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestParquetInferredSchema(t *testing.T) {
+
+	records := []string{
+		`{"name": "a", "n": 1, "price": 1.5, "ok": true, "addr": {"city": "Rome"}, "tags": ["x", "y"]}`,
+		`{"name": "b", "n": 2, "addr": null}`,
+	}
+	f := encodeParquet(t, format.Config{}, records)
+
+	if f.NumRows() != 2 {
+		t.Fatalf("expected 2 rows, got %d", f.NumRows())
+	}
+	var columns []string
+	for _, field := range f.Schema().Fields() {
+		if !field.Optional() {
+			t.Errorf("inferred column %s must be optional", field.Name())
+		}
+		columns = append(columns, field.Name())
+	}
+	if got := strings.Join(columns, ","); got != "addr,n,name,ok,price,tags" {
+		t.Errorf("unexpected columns %s", got)
+	}
+	rows := readRows(t, f)
+	if !strings.Contains(rows[0], "city:Rome") || !strings.Contains(rows[1], "name:b") {
+		t.Errorf("unexpected rows %v", rows)
+	}
+}
+
+func TestParquetErrors(t *testing.T) {
+
+	schemaFile := filepath.Join(t.TempDir(), "order.avsc")
+	if err := os.WriteFile(schemaFile, []byte(orderSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := format.Config{Format: format.Parquet, SchemaFile: schemaFile}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	e, err := format.NewEncoder(io.Discard, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{name: "missing", value: `{"status": "NEW", "total": 1, "created_at": 1, "lines": []}`, wantErr: `field "id": missing required field`},
+		{name: "nested_type", value: `{"id": "o", "status": "NEW", "total": 1, "created_at": 1, "lines": [{"sku": "A", "qty": "many"}]}`, wantErr: `field "lines[0].qty": expected integer`},
+		{name: "scale", value: `{"id": "o", "status": "NEW", "total": 1.234, "created_at": 1, "lines": []}`, wantErr: `more than 2 decimal digits`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := e.Encode([]byte(tc.value))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	if err := (&format.Config{Format: format.Parquet, Compression: "lz4"}).Validate(); err == nil {
+		t.Error("expected an error for an unsupported compression")
+	}
+}
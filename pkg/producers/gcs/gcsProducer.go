@@ -29,16 +29,20 @@ import (
 	"os"
 	"strings"
 
+	"github.com/jrnd-io/jr/pkg/producers/format"
 	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
 	Bucket string `json:"bucket_name"`
+	// with a format, records are written in batches, one object per batch
+	format.BatchConfig
 }
 
 type Producer struct {
 	client storage.Client
 	bucket string
+	batch  *format.Batch
 }
 
 func (p *Producer) Initialize(ctx context.Context, configFile string) {
@@ -63,9 +67,28 @@ func (p *Producer) Initialize(ctx context.Context, configFile string) {
 
 	p.client = *client
 	p.bucket = config.Bucket
+
+	if config.Format != "" {
+		p.batch, err = format.NewBatch(config.BatchConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid object format")
+		}
+	}
 }
 
 func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	if p.batch != nil {
+		full, err := p.batch.Add(v)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to encode record")
+			return
+		}
+		if full {
+			p.flush(ctx)
+		}
+		return
+	}
+
 	bucket := p.bucket
 	var key string
 
@@ -89,7 +112,28 @@ func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
 
 }
 
-func (p *Producer) Close(_ context.Context) error {
+func (p *Producer) flush(ctx context.Context) {
+	key, content, err := p.batch.Flush()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to encode batch")
+	}
+	if content == nil {
+		return
+	}
+
+	writer := p.client.Bucket(p.bucket).Object(key).NewWriter(ctx)
+	if _, err := writer.Write(content); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write to GCS")
+	}
+	if err := writer.Close(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to write to GCS")
+	}
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	if p.batch != nil {
+		p.flush(ctx)
+	}
 	p.client.Close()
 	return nil
 }
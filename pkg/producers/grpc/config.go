@@ -0,0 +1,56 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	DefaultTimeout        = "10s"
+	DefaultStreamMessages = 100
+	DefaultFlushInterval  = "1s"
+)
+
+type ErrorHandling struct {
+	// ExpectStatusCode is the name of the expected status code, "OK" by default
+	ExpectStatusCode string `json:"expect_status_code"`
+	IgnoreStatusCode bool   `json:"ignore_status_code"`
+}
+
+type Config struct {
+	// Target is the address of the server, e.g. "localhost:50051"
+	Target string `json:"target"`
+	// Method is the full name of the method, e.g. "orders.OrderService/CreateOrder"
+	Method string `json:"method"`
+	// ProtoFiles declare the service; without them, it is resolved with server reflection.
+	// Imports are resolved in ImportPaths, by default the directories of the files
+	ProtoFiles  []string `json:"proto_files"`
+	ImportPaths []string `json:"import_paths"`
+	// Timeout is the deadline of each unary call and of the response of each client stream
+	Timeout string `json:"timeout"`
+	// StreamMessages is the number of records sent on each client stream; a stream is
+	// closed earlier when its first record is older than FlushInterval
+	StreamMessages int    `json:"stream_messages"`
+	FlushInterval  string `json:"flush_interval"`
+	// Headers values are evaluated for each call and sent as metadata
+	Headers       map[string]string `json:"headers"`
+	ErrorHandling ErrorHandling     `json:"error_handling"`
+	TLS           tlsconfig.Config  `json:"tls"`
+}
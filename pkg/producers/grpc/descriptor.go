@@ -0,0 +1,115 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/jhump/protoreflect/grpcreflect"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// splitMethod splits "package.Service/Method", or "package.Service.Method", in
+// the names of the service and of the method
+func splitMethod(method string) (string, string, error) {
+	method = strings.TrimPrefix(method, "/")
+	i := strings.LastIndex(method, "/")
+	if i < 0 {
+		i = strings.LastIndex(method, ".")
+	}
+	if i <= 0 || i == len(method)-1 {
+		return "", "", fmt.Errorf("invalid method %q, expected package.Service/Method", method)
+	}
+	return method[:i], method[i+1:], nil
+}
+
+// loadMethod finds the descriptor of the method in the .proto files of the
+// configuration or, without them, with the server reflection of conn
+func loadMethod(ctx context.Context, config Config, conn grpcgo.ClientConnInterface) (protoreflect.MethodDescriptor, error) {
+	serviceName, methodName, err := splitMethod(config.Method)
+	if err != nil {
+		return nil, err
+	}
+
+	var service protoreflect.ServiceDescriptor
+	if len(config.ProtoFiles) > 0 {
+		service, err = compileService(ctx, config, serviceName)
+	} else {
+		service, err = reflectService(ctx, conn, serviceName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, fmt.Errorf("method %s not found in service %s", methodName, serviceName)
+	}
+	return method, nil
+}
+
+func compileService(ctx context.Context, config Config, serviceName string) (protoreflect.ServiceDescriptor, error) {
+	importPaths := config.ImportPaths
+	names := config.ProtoFiles
+	if len(importPaths) == 0 {
+		names = make([]string, len(config.ProtoFiles))
+		for i, f := range config.ProtoFiles {
+			importPaths = append(importPaths, filepath.Dir(f))
+			names[i] = filepath.Base(f)
+		}
+	}
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: importPaths,
+		}),
+	}
+	files, err := compiler.Compile(ctx, names...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", strings.Join(config.ProtoFiles, ", "), err)
+	}
+
+	d, err := files.AsResolver().FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, fmt.Errorf("service %s not found: %w", serviceName, err)
+	}
+	service, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a service", serviceName)
+	}
+	return service, nil
+}
+
+func reflectService(ctx context.Context, conn grpcgo.ClientConnInterface, serviceName string) (protoreflect.ServiceDescriptor, error) {
+	client := grpcreflect.NewClientAuto(ctx, conn)
+	defer client.Reset()
+
+	service, err := client.ResolveService(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve service %s with server reflection: %w", serviceName, err)
+	}
+	return service.UnwrapService(), nil
+}
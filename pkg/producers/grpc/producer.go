@@ -0,0 +1,279 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Producer calls a gRPC method with each record, mapped from JSON to the request
+// message of the method. Client streaming methods get StreamMessages records per stream.
+type Producer struct {
+	configuration Config
+
+	conn       *grpcgo.ClientConn
+	method     protoreflect.MethodDescriptor
+	fullMethod string
+	timeout    time.Duration
+	expect     codes.Code
+	headers    map[string]*record.Template
+
+	// the client stream is closed by Produce when full, or by flushOnInterval
+	lock          sync.Mutex
+	stream        grpcgo.ClientStream
+	cancel        context.CancelFunc
+	sent          int
+	oldest        time.Time
+	flushInterval time.Duration
+	stopTick      chan struct{}
+	tickDone      chan struct{}
+
+	// statuses counts the responses by status code
+	statuses map[codes.Code]int
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	cfgBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config file")
+	}
+
+	config := Config{}
+	if err := json.Unmarshal(cfgBytes, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal config")
+	}
+
+	p.InitializeFromConfig(ctx, config)
+}
+
+func (p *Producer) InitializeFromConfig(ctx context.Context, config Config) {
+	var err error
+	p.configuration = config
+	if p.configuration.Target == "" || p.configuration.Method == "" {
+		log.Fatal().Msg("target and method are mandatory")
+	}
+	if p.configuration.Timeout == "" {
+		p.configuration.Timeout = DefaultTimeout
+	}
+	p.timeout, err = time.ParseDuration(p.configuration.Timeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse timeout")
+	}
+	if p.configuration.StreamMessages <= 0 {
+		p.configuration.StreamMessages = DefaultStreamMessages
+	}
+	if p.configuration.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	p.flushInterval, err = time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	if p.flushInterval <= 0 {
+		log.Fatal().Str("flush_interval", p.configuration.FlushInterval).Msg("flush_interval must be positive")
+	}
+
+	p.expect = codes.OK
+	if p.configuration.ErrorHandling.ExpectStatusCode != "" {
+		if err := p.expect.UnmarshalJSON([]byte(strconv.Quote(p.configuration.ErrorHandling.ExpectStatusCode))); err != nil {
+			log.Fatal().Err(err).Msg("Invalid expect_status_code")
+		}
+	}
+
+	transport := insecure.NewCredentials()
+	if p.configuration.TLS.IsSet() {
+		tlsConfig, err := p.configuration.TLS.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+		transport = credentials.NewTLS(tlsConfig)
+	}
+	p.conn, err = grpcgo.NewClient(p.configuration.Target, grpcgo.WithTransportCredentials(transport))
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create client")
+	}
+
+	loadCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	p.method, err = loadMethod(loadCtx, p.configuration, p.conn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load method")
+	}
+	if p.method.IsStreamingServer() {
+		log.Fatal().Str("method", p.configuration.Method).Msg("Only unary and client streaming methods are supported")
+	}
+	p.fullMethod = "/" + string(p.method.Parent().FullName()) + "/" + string(p.method.Name())
+
+	p.headers = make(map[string]*record.Template, len(p.configuration.Headers))
+	for name, value := range p.configuration.Headers {
+		t, err := record.NewTemplate(name, value)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Failed to parse header %s", name)
+		}
+		p.headers[name] = t
+	}
+	p.statuses = make(map[codes.Code]int)
+
+	if p.method.IsStreamingClient() {
+		p.stopTick = make(chan struct{})
+		p.tickDone = make(chan struct{})
+		go p.flushOnInterval()
+	}
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	request := dynamicpb.NewMessage(p.method.Input())
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(v, request); err != nil {
+		log.Error().Err(err).Str("message", string(p.method.Input().FullName())).Msg("Failed to map record to request")
+		return
+	}
+
+	if !p.method.IsStreamingClient() {
+		callCtx, cancel := context.WithTimeout(p.outgoingContext(ctx, k, v), p.timeout)
+		defer cancel()
+		err := p.conn.Invoke(callCtx, p.fullMethod, request, dynamicpb.NewMessage(p.method.Output()))
+		p.account(err)
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	// records come at the pace of the emitter, so the stream itself has no deadline
+	if p.stream == nil {
+		var streamCtx context.Context
+		streamCtx, p.cancel = context.WithCancel(p.outgoingContext(ctx, k, v))
+		stream, err := p.conn.NewStream(streamCtx, &grpcgo.StreamDesc{ClientStreams: true}, p.fullMethod)
+		if err != nil {
+			p.cancel()
+			p.account(err)
+			return
+		}
+		p.stream = stream
+		p.sent = 0
+		p.oldest = time.Now()
+	}
+
+	// a failed send ends the stream, its status is returned by closeStream
+	if err := p.stream.SendMsg(request); err != nil {
+		p.closeStream()
+		return
+	}
+	p.sent++
+	if p.sent >= p.configuration.StreamMessages {
+		p.closeStream()
+	}
+}
+
+// outgoingContext adds the headers, evaluated with the record, as metadata;
+// the headers of a client stream are evaluated with its first record
+func (p *Producer) outgoingContext(ctx context.Context, k []byte, v []byte) context.Context {
+	if len(p.headers) == 0 {
+		return ctx
+	}
+	data := record.NewData(k, v)
+	md := metadata.MD{}
+	for name, value := range p.headers {
+		md.Set(name, value.ExecuteWith(data))
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (p *Producer) flushOnInterval() {
+	defer close(p.tickDone)
+
+	ticker := time.NewTicker(max(p.flushInterval/2, time.Millisecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopTick:
+			return
+		case <-ticker.C:
+			p.lock.Lock()
+			if p.stream != nil && time.Since(p.oldest) >= p.flushInterval {
+				p.closeStream()
+			}
+			p.lock.Unlock()
+		}
+	}
+}
+
+// closeStream ends the client stream, waiting up to the timeout for its response
+func (p *Producer) closeStream() {
+	defer p.cancel()
+	timer := time.AfterFunc(p.timeout, p.cancel)
+	err := p.stream.CloseSend()
+	if err == nil {
+		err = p.stream.RecvMsg(dynamicpb.NewMessage(p.method.Output()))
+	}
+	if !timer.Stop() && err != nil {
+		err = status.Errorf(codes.DeadlineExceeded, "no response to the client stream within %s", p.timeout)
+	}
+	p.stream = nil
+	p.account(err)
+}
+
+func (p *Producer) account(err error) {
+	code := status.Code(err)
+	p.statuses[code]++
+	if code != p.expect && !p.configuration.ErrorHandling.IgnoreStatusCode {
+		log.Fatal().Err(err).Str("statusCode", code.String()).Msg("Unexpected status code")
+	}
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	if p.stopTick != nil {
+		close(p.stopTick)
+		<-p.tickDone
+	}
+	if p.stream != nil {
+		p.closeStream()
+	}
+
+	received := make([]codes.Code, 0, len(p.statuses))
+	for code := range p.statuses {
+		received = append(received, code)
+	}
+	slices.Sort(received)
+	for _, code := range received {
+		log.Info().Str("statusCode", code.String()).Int("count", p.statuses[code]).Msg("gRPC responses")
+	}
+
+	return p.conn.Close()
+}
@@ -0,0 +1,271 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/protocompile"
+	"github.com/bufbuild/protocompile/linker"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcreflection "google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+type call struct {
+	method    string
+	requestID []string
+	orders    []string
+}
+
+// orderServer implements orders.OrderService with dynamic messages,
+// rejecting the orders without an id
+type orderServer struct {
+	files linker.Files
+	lock  sync.Mutex
+	calls []call
+}
+
+func (s *orderServer) GetServiceInfo() map[string]grpcgo.ServiceInfo {
+	return map[string]grpcgo.ServiceInfo{"orders.OrderService": {}}
+}
+
+func (s *orderServer) handle(_ any, stream grpcgo.ServerStream) error {
+	method, _ := grpcgo.MethodFromServerStream(stream)
+	md, _ := metadata.FromIncomingContext(stream.Context())
+	c := call{method: method, requestID: md.Get("x-request-id")}
+
+	order := s.message("orders.Order")
+	for {
+		msg := dynamicpb.NewMessage(order)
+		err := stream.RecvMsg(msg)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		fields := order.Fields()
+		createdAt := msg.Get(fields.ByName("created_at")).Message()
+		c.orders = append(c.orders, fmt.Sprintf("%s %d %d",
+			msg.Get(fields.ByName("id")).String(),
+			msg.Get(fields.ByName("quantity")).Int(),
+			createdAt.Get(createdAt.Descriptor().Fields().ByName("seconds")).Int()))
+	}
+
+	s.lock.Lock()
+	s.calls = append(s.calls, c)
+	s.lock.Unlock()
+
+	for _, o := range c.orders {
+		if o == " 0 0" {
+			return status.Error(codes.InvalidArgument, "missing id")
+		}
+	}
+	ack := dynamicpb.NewMessage(s.message("orders.Ack"))
+	ack.Set(ack.Descriptor().Fields().ByName("count"), protoreflect.ValueOfInt32(int32(len(c.orders))))
+	return stream.SendMsg(ack)
+}
+
+func (s *orderServer) message(name protoreflect.FullName) protoreflect.MessageDescriptor {
+	d, _ := s.files.AsResolver().FindDescriptorByName(name)
+	return d.(protoreflect.MessageDescriptor)
+}
+
+func startServer(t *testing.T) (*orderServer, string) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{ImportPaths: []string{"testdata"}}),
+	}
+	files, err := compiler.Compile(context.Background(), "orders.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &orderServer{files: files}
+	server := grpcgo.NewServer(grpcgo.UnknownServiceHandler(s.handle))
+	reflectionv1.RegisterServerReflectionServer(server, grpcreflection.NewServerV1(grpcreflection.ServerOptions{
+		Services:           s,
+		DescriptorResolver: files.AsResolver(),
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return s, listener.Addr().String()
+}
+
+func TestSplitMethod(t *testing.T) {
+	testCases := []struct {
+		name    string
+		method  string
+		service string
+		rpc     string
+		err     bool
+	}{
+		{name: "slash", method: "orders.OrderService/CreateOrder", service: "orders.OrderService", rpc: "CreateOrder"},
+		{name: "leading slash", method: "/orders.OrderService/CreateOrder", service: "orders.OrderService", rpc: "CreateOrder"},
+		{name: "dot", method: "orders.OrderService.CreateOrder", service: "orders.OrderService", rpc: "CreateOrder"},
+		{name: "no service", method: "CreateOrder", err: true},
+		{name: "no method", method: "orders.OrderService/", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, rpc, err := splitMethod(tc.method)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v", err)
+			}
+			if service != tc.service || rpc != tc.rpc {
+				t.Errorf("got %s %s, want %s %s", service, rpc, tc.service, tc.rpc)
+			}
+		})
+	}
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name       string
+		config     Config
+		wantCalls  []call
+		wantStatus map[codes.Code]int
+	}{
+		{
+			name: "unary with proto files",
+			config: Config{
+				Method:     "orders.OrderService/CreateOrder",
+				ProtoFiles: []string{"testdata/orders.proto"},
+				Headers:    map[string]string{"x-request-id": "req-{{.K}}"},
+				ErrorHandling: ErrorHandling{
+					IgnoreStatusCode: true,
+				},
+			},
+			wantCalls: []call{
+				{method: "/orders.OrderService/CreateOrder", requestID: []string{"req-k0"}, orders: []string{"o0 1 1704067200"}},
+				{method: "/orders.OrderService/CreateOrder", requestID: []string{"req-k1"}, orders: []string{"o1 2 1704067200"}},
+				{method: "/orders.OrderService/CreateOrder", requestID: []string{"req-k2"}, orders: []string{" 0 0"}},
+			},
+			wantStatus: map[codes.Code]int{codes.OK: 2, codes.InvalidArgument: 1},
+		},
+		{
+			name: "client stream with reflection",
+			config: Config{
+				Method:         "orders.OrderService.UploadOrders",
+				StreamMessages: 2,
+				Headers:        map[string]string{"x-request-id": "req-{{.K}}"},
+				ErrorHandling: ErrorHandling{
+					IgnoreStatusCode: true,
+				},
+			},
+			wantCalls: []call{
+				{method: "/orders.OrderService/UploadOrders", requestID: []string{"req-k0"}, orders: []string{
+					"o0 1 1704067200",
+					"o1 2 1704067200",
+				}},
+				{method: "/orders.OrderService/UploadOrders", requestID: []string{"req-k2"}, orders: []string{" 0 0"}},
+			},
+			wantStatus: map[codes.Code]int{codes.OK: 1, codes.InvalidArgument: 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server, addr := startServer(t)
+			tc.config.Target = addr
+
+			p := &Producer{}
+			p.InitializeFromConfig(ctx, tc.config)
+			for i := 0; i < 3; i++ {
+				v := fmt.Sprintf(`{"id":"o%d","quantity":%d,"created_at":"2024-01-01T00:00:00Z","unknown":true}`, i, i+1)
+				if i == 2 {
+					v = `{"quantity":0}`
+				}
+				p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(v), nil)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(server.calls, tc.wantCalls) {
+				t.Errorf("got calls %v, want %v", server.calls, tc.wantCalls)
+			}
+			if !reflect.DeepEqual(p.statuses, tc.wantStatus) {
+				t.Errorf("got statuses %v, want %v", p.statuses, tc.wantStatus)
+			}
+		})
+	}
+}
+
+func TestProducerSlowClientStream(t *testing.T) {
+	ctx := context.Background()
+	server, addr := startServer(t)
+
+	// records come slower than the timeout: each stream is closed after flush_interval
+	p := &Producer{}
+	p.InitializeFromConfig(ctx, Config{
+		Target:        addr,
+		Method:        "orders.OrderService.UploadOrders",
+		Timeout:       "50ms",
+		FlushInterval: "100ms",
+		ErrorHandling: ErrorHandling{IgnoreStatusCode: true},
+	})
+	for i := 0; i < 3; i++ {
+		p.Produce(ctx, nil, []byte(fmt.Sprintf(`{"id":"o%d","quantity":1}`, i)), nil)
+		time.Sleep(80 * time.Millisecond)
+	}
+
+	server.lock.Lock()
+	streams := len(server.calls)
+	server.lock.Unlock()
+	if streams == 0 {
+		t.Error("no stream closed before Close")
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	orders := 0
+	for _, c := range server.calls {
+		orders += len(c.orders)
+	}
+	if orders != 3 {
+		t.Errorf("got %d orders, want 3", orders)
+	}
+	if want := map[codes.Code]int{codes.OK: len(server.calls)}; !reflect.DeepEqual(p.statuses, want) {
+		t.Errorf("got statuses %v, want %v", p.statuses, want)
+	}
+}
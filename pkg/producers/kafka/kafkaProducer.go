@@ -44,7 +44,10 @@ import (
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avrov2"
 	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/jsonschema"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/protobuf"
+	"github.com/hamba/avro/v2"
 	"github.com/jrnd-io/jr/pkg/types"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/rs/zerolog/log"
 )
@@ -57,6 +60,13 @@ type Manager struct {
 	Topic               string
 	Serializer          string
 	TemplateType        string
+	ProtoFile           string
+	ProtoMessage        string
+	SchemaFile          string
+	protoDescriptor     protoreflect.MessageDescriptor
+	runtimeSchema       bool
+	schemaID            int
+	avroSchema          avro.Schema
 	fleEnabled          bool
 	autoRegisterSchemas bool
 }
@@ -89,12 +99,29 @@ func (k *Manager) InitializeSchemaRegistry(configFile string) {
 		log.Fatal().Err(err).Msg("Failed to create schema registry client")
 	}
 
+	k.autoRegisterSchemas = true // auto register schemas by default
+
 	if k.Serializer == "avro" || k.Serializer == "avro-generic" {
 		verifyCSFLE(conf, k)
 	}
 
+	if k.Serializer == "protobuf" {
+		if k.ProtoFile == "" {
+			log.Fatal().Msg("protoFile is mandatory with protobuf serializer")
+		}
+		k.protoDescriptor, err = loadProtoDescriptor(context.Background(), k.ProtoFile, k.ProtoMessage)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load protobuf schema")
+		}
+	}
+
+	if k.usesRuntimeSchema() {
+		if err = k.loadRuntimeSchema(); err != nil {
+			log.Fatal().Err(err).Str("schemaFile", k.SchemaFile).Msg("Failed to load schema")
+		}
+	}
+
 	k.schemaRegistry = true
-	k.autoRegisterSchemas = true // auto register schemas by default
 }
 
 func verifyCSFLE(conf map[string]string, k *Manager) {
@@ -194,49 +221,22 @@ func (k *Manager) Produce(_ context.Context, key []byte, data []byte, _ any) {
 
 	go listenToEventsFrom(k.producer, k.Topic)
 
-	var ser serde.Serializer
-
 	if k.schemaRegistry {
-		var err error
-
-		if k.Serializer == "avro" || k.Serializer == "avro-generic" {
-			serConfig := avrov2.NewSerializerConfig()
-			// CSFLE requires auto register to false
-			if k.fleEnabled || !k.autoRegisterSchemas {
-				serConfig.AutoRegisterSchemas = false
-				serConfig.UseLatestVersion = true
-			}
-			ser, err = avrov2.NewSerializer(k.schema, serde.ValueSerde, serConfig)
-		} else if k.Serializer == "protobuf" {
-			// ser, err = protobuf.NewSerializer(k.schema, serde.ValueSerde, protobuf.NewSerializerConfig())
-			log.Fatal().Msg("Protobuf not yet implemented")
-		} else if k.Serializer == "json-schema" {
-			serConfig := jsonschema.NewSerializerConfig()
-			serConfig.AutoRegisterSchemas = k.autoRegisterSchemas
-			serConfig.UseLatestVersion = !k.autoRegisterSchemas
-			ser, err = jsonschema.NewSerializer(k.schema, serde.ValueSerde, serConfig)
-		} else {
-			log.Fatal().Str("serializer", k.Serializer).Msg("Serializer not supported")
-		}
+		ser, err := k.newSerializer()
 		if err != nil {
 			log.Fatal().Err(err).Msg("Error creating serializer")
-		} else {
-
-			t := types.GetType(k.TemplateType)
-			err := json.Unmarshal(data, &t)
-
-			if err != nil {
-				log.Fatal().Err(err).Msg("Failed to unmarshal data")
-			}
+		}
 
-			payload, err := ser.Serialize(k.Topic, t)
-			if err != nil {
-				log.Fatal().Err(err).Msg("Failed to serialize payload")
-			} else {
-				data = payload
-			}
+		t, err := k.newValue(data)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to unmarshal data")
+		}
 
+		payload, err := ser.Serialize(k.Topic, t)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to serialize payload")
 		}
+		data = payload
 	}
 
 	if strings.ToLower(string(key)) == "null" {
@@ -262,6 +262,52 @@ func (k *Manager) Produce(_ context.Context, key []byte, data []byte, _ any) {
 
 }
 
+func (k *Manager) newSerializer() (serde.Serializer, error) {
+	switch k.Serializer {
+	case "avro", "avro-generic":
+		serConfig := avrov2.NewSerializerConfig()
+		// CSFLE requires auto register to false
+		if k.fleEnabled || !k.autoRegisterSchemas {
+			serConfig.AutoRegisterSchemas = false
+			serConfig.UseLatestVersion = true
+		}
+		if k.runtimeSchema {
+			serConfig.AutoRegisterSchemas = false
+			serConfig.UseLatestVersion = false
+			serConfig.UseSchemaID = k.schemaID
+		}
+		return avrov2.NewSerializer(k.schema, serde.ValueSerde, serConfig)
+	case "protobuf":
+		serConfig := protobuf.NewSerializerConfig()
+		serConfig.AutoRegisterSchemas = k.autoRegisterSchemas
+		serConfig.UseLatestVersion = !k.autoRegisterSchemas
+		return protobuf.NewSerializer(k.schema, serde.ValueSerde, serConfig)
+	case "json-schema":
+		serConfig := jsonschema.NewSerializerConfig()
+		serConfig.AutoRegisterSchemas = k.autoRegisterSchemas
+		serConfig.UseLatestVersion = !k.autoRegisterSchemas
+		if k.runtimeSchema {
+			serConfig.AutoRegisterSchemas = false
+			serConfig.UseLatestVersion = false
+			serConfig.UseSchemaID = k.schemaID
+		}
+		return jsonschema.NewSerializer(k.schema, serde.ValueSerde, serConfig)
+	}
+	return nil, fmt.Errorf("serializer %s not supported", k.Serializer)
+}
+
+func (k *Manager) newValue(data []byte) (any, error) {
+	if k.Serializer == "protobuf" {
+		return newProtoMessage(k.protoDescriptor, data)
+	}
+	if k.runtimeSchema {
+		return k.newRuntimeValue(data)
+	}
+	t := types.GetType(k.TemplateType)
+	err := json.Unmarshal(data, &t)
+	return t, err
+}
+
 func (k *Manager) CreateTopic(ctx context.Context, topic string) {
 	k.CreateTopicFull(ctx, topic, 6, 3)
 }
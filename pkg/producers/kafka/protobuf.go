@@ -0,0 +1,78 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// loadProtoDescriptor compiles a .proto file at runtime and returns the descriptor of the given message.
+// If messageName is empty, the first message declared in the file is used.
+// Imports are resolved relative to the directory of the file, well-known types are always available.
+func loadProtoDescriptor(ctx context.Context, protoFile string, messageName string) (protoreflect.MessageDescriptor, error) {
+
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{filepath.Dir(protoFile)},
+		}),
+	}
+
+	files, err := compiler.Compile(ctx, filepath.Base(protoFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile %s: %w", protoFile, err)
+	}
+	fd := files[0]
+
+	if messageName == "" {
+		if fd.Messages().Len() == 0 {
+			return nil, fmt.Errorf("no message declared in %s", protoFile)
+		}
+		return fd.Messages().Get(0), nil
+	}
+
+	md := fd.Messages().ByName(protoreflect.Name(messageName))
+	if md == nil {
+		d, err := files.AsResolver().FindDescriptorByName(protoreflect.FullName(messageName))
+		if err != nil {
+			return nil, fmt.Errorf("message %s not found in %s: %w", messageName, protoFile, err)
+		}
+		var ok bool
+		if md, ok = d.(protoreflect.MessageDescriptor); !ok {
+			return nil, fmt.Errorf("%s is not a message", messageName)
+		}
+	}
+	return md, nil
+}
+
+// newProtoMessage maps a rendered JSON value into a dynamic message described by md
+func newProtoMessage(md protoreflect.MessageDescriptor, data []byte) (proto.Message, error) {
+	msg := dynamicpb.NewMessage(md)
+	err := protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, msg)
+	return msg, err
+}
@@ -0,0 +1,121 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/protobuf"
+)
+
+const protoFile = "testdata/shoestore_shoe.proto"
+
+func TestLoadProtoDescriptor(t *testing.T) {
+
+	testCases := []struct {
+		name    string
+		message string
+		wantErr bool
+	}{
+		{name: "first_message", message: ""},
+		{name: "short_name", message: "ShoestoreShoe"},
+		{name: "full_name", message: "shoestore.ShoestoreShoe"},
+		{name: "missing_message", message: "Missing", wantErr: true},
+		{name: "not_a_message", message: "shoestore", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			md, err := loadProtoDescriptor(context.Background(), protoFile, tc.message)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %s", md.FullName())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if md.FullName() != "shoestore.ShoestoreShoe" {
+				t.Errorf("unexpected message %s", md.FullName())
+			}
+		})
+	}
+}
+
+func TestProtobufSerialization(t *testing.T) {
+
+	md, err := loadProtoDescriptor(context.Background(), protoFile, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := []byte(`{
+  "id": "a7c4f6ae-d8b3-4d1a-9b5e-6e1a4d3c2b10",
+  "sale_price": "1170.00",
+  "brand": "Nike",
+  "name": "Air Swift 12",
+  "rating": 4.25,
+  "created_at": "2024-05-01T10:00:00Z",
+  "unknown": "ignored"
+}`)
+
+	msg, err := newProtoMessage(md, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.ProtoReflect().Get(md.Fields().ByName("brand")).String(); got != "Nike" {
+		t.Errorf("unexpected brand %s", got)
+	}
+
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser, err := protobuf.NewSerializer(client, serde.ValueSerde, protobuf.NewSerializerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := ser.Serialize("shoes", msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// magic byte, 4 bytes of schema id and message indexes come first
+	if len(payload) < 6 || payload[0] != 0 {
+		t.Fatalf("unexpected wire format %v", payload)
+	}
+
+	subjects, err := client.GetAllSubjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subjects) != 1 || subjects[0] != "shoes-value" {
+		t.Errorf("unexpected subjects %v", subjects)
+	}
+
+	if _, err := newProtoMessage(md, []byte(`{"id": 1}`)); err == nil {
+		t.Error("expected an error for a mistyped field")
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kafka
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/hamba/avro/v2"
+	"github.com/jrnd-io/jr/pkg/schema"
+	"github.com/jrnd-io/jr/pkg/types"
+)
+
+// usesRuntimeSchema tells if the value schema is loaded at runtime instead of coming from the generated types:
+// this happens when a schema file is given or when the template has no generated type
+func (k *Manager) usesRuntimeSchema() bool {
+	if k.Serializer != "avro" && k.Serializer != "avro-generic" && k.Serializer != "json-schema" {
+		return false
+	}
+	return k.SchemaFile != "" || types.GetType(k.TemplateType) == nil
+}
+
+// loadRuntimeSchema resolves the schema ID used to serialize values.
+// With a schema file the schema is registered (or looked up) under the topic value subject,
+// otherwise the latest version of the subject is fetched from the registry.
+func (k *Manager) loadRuntimeSchema() error {
+	subject := k.Topic + "-value"
+
+	var info schemaregistry.SchemaInfo
+	if k.SchemaFile != "" {
+		content, err := os.ReadFile(k.SchemaFile)
+		if err != nil {
+			return err
+		}
+		info = schemaregistry.SchemaInfo{Schema: string(content), SchemaType: k.schemaType()}
+
+		if k.autoRegisterSchemas {
+			k.schemaID, err = k.schema.Register(subject, info, true)
+		} else {
+			k.schemaID, err = k.schema.GetID(subject, info, true)
+		}
+		if err != nil {
+			return fmt.Errorf("schema %s not available for subject %s: %w", k.SchemaFile, subject, err)
+		}
+	} else {
+		metadata, err := k.schema.GetLatestSchemaMetadata(subject)
+		if err != nil {
+			return fmt.Errorf("no schema file given and no schema registered for subject %s: %w", subject, err)
+		}
+		// the registry omits the type of AVRO schemas
+		schemaType := metadata.SchemaType
+		if schemaType == "" {
+			schemaType = "AVRO"
+		}
+		if schemaType != k.schemaType() {
+			return fmt.Errorf("subject %s has a %s schema, not usable with the %s serializer", subject, schemaType, k.Serializer)
+		}
+		info = metadata.SchemaInfo
+		k.schemaID = metadata.ID
+	}
+
+	if k.Serializer != "json-schema" {
+		s, err := avro.Parse(info.Schema)
+		if err != nil {
+			return err
+		}
+		k.avroSchema = s
+	}
+
+	k.runtimeSchema = true
+	return nil
+}
+
+func (k *Manager) schemaType() string {
+	if k.Serializer == "json-schema" {
+		return "JSON"
+	}
+	return "AVRO"
+}
+
+// newRuntimeValue converts rendered data to the generic value the serializer expects for the runtime schema
+func (k *Manager) newRuntimeValue(data []byte) (any, error) {
+	if k.avroSchema != nil {
+		v, err := schema.AvroNative(k.avroSchema, data)
+		if err != nil {
+			return nil, err
+		}
+		return &v, nil
+	}
+
+	var v any
+	err := json.Unmarshal(data, &v)
+	return v, err
+}
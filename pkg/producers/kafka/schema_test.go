@@ -0,0 +1,135 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kafka
+
+import (
+	"testing"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde"
+	"github.com/confluentinc/confluent-kafka-go/v2/schemaregistry/serde/avrov2"
+)
+
+const orderValue = `{
+  "id": "o-1",
+  "status": "NEW",
+  "total": "21.50",
+  "created_at": 1714557600000,
+  "note": "leave at the door",
+  "lines": [{"sku": "A-1", "qty": 2, "price": 10.75}]
+}`
+
+func newMockManager(t *testing.T, serializer string, schemaFile string) *Manager {
+	t.Helper()
+	client, err := schemaregistry.NewClient(schemaregistry.NewConfig("mock://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Manager{
+		schema:              client,
+		schemaRegistry:      true,
+		Topic:               "orders",
+		Serializer:          serializer,
+		TemplateType:        "my_order",
+		SchemaFile:          schemaFile,
+		autoRegisterSchemas: true,
+	}
+}
+
+func serializeValue(t *testing.T, k *Manager, data string) []byte {
+	t.Helper()
+	ser, err := k.newSerializer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := k.newValue([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	payload, err := ser.Serialize(k.Topic, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestRuntimeAvroSchema(t *testing.T) {
+
+	k := newMockManager(t, "avro-generic", "testdata/order.avsc")
+	if !k.usesRuntimeSchema() {
+		t.Fatal("expected a runtime schema")
+	}
+	if err := k.loadRuntimeSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := serializeValue(t, k, orderValue)
+
+	deser, err := avrov2.NewDeserializer(k.schema, serde.ValueSerde, avrov2.NewDeserializerConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := deser.DeserializeInto(k.Topic, payload, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["id"] != "o-1" || got["status"] != "NEW" {
+		t.Errorf("unexpected record %v", got)
+	}
+
+	if _, err := k.newValue([]byte(`{"id": "o-2", "status": "LOST"}`)); err == nil {
+		t.Error("expected an error for an unknown enum symbol")
+	}
+}
+
+func TestRuntimeSchemaFromRegistry(t *testing.T) {
+
+	registered := newMockManager(t, "json-schema", "testdata/order.schema.json")
+	if err := registered.loadRuntimeSchema(); err != nil {
+		t.Fatal(err)
+	}
+
+	// same registry, no schema file: the latest registered version is used
+	k := newMockManager(t, "json-schema", "")
+	k.schema = registered.schema
+	if err := k.loadRuntimeSchema(); err != nil {
+		t.Fatal(err)
+	}
+	if k.schemaID != registered.schemaID {
+		t.Errorf("expected schema id %d, got %d", registered.schemaID, k.schemaID)
+	}
+
+	payload := serializeValue(t, k, `{"id": "o-1", "qty": 3}`)
+	if len(payload) < 5 || payload[0] != 0 {
+		t.Fatalf("unexpected wire format %v", payload)
+	}
+
+	avroManager := newMockManager(t, "avro", "")
+	avroManager.schema = registered.schema
+	if err := avroManager.loadRuntimeSchema(); err == nil {
+		t.Error("expected an error using a JSON schema with the avro serializer")
+	}
+
+	missing := newMockManager(t, "avro", "")
+	if err := missing.loadRuntimeSchema(); err == nil {
+		t.Error("expected an error without schema file nor registered subject")
+	}
+}
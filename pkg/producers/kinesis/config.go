@@ -0,0 +1,45 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kinesis
+
+import "github.com/jrnd-io/jr/pkg/producers/awscfg"
+
+const (
+	// MaxBatchRecords is the maximum number of records of PutRecords and PutRecordBatch
+	MaxBatchRecords      = 500
+	DefaultRetries       = 5
+	DefaultFlushInterval = "1s"
+)
+
+type Config struct {
+	// Stream is the name of the data stream, or of the Firehose delivery stream
+	Stream string `json:"stream"`
+	// BatchSize is the number of records of each request, up to 500
+	BatchSize int `json:"batch_size"`
+	// FlushInterval sends an incomplete batch when it is older than this duration, 1s by default
+	FlushInterval string `json:"flush_interval"`
+	// MaxRetries is the number of times throttled or failed records are sent again, -1 for none
+	MaxRetries int `json:"max_retries"`
+	// AppendNewline adds a newline to each Firehose record, so that records delivered to S3 are one per line
+	AppendNewline bool `json:"append_newline"`
+	// region, endpoint and profile
+	awscfg.Config
+}
@@ -0,0 +1,208 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kinesis
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehosetypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/google/uuid"
+	"github.com/jrnd-io/jr/pkg/producers/awscfg"
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	maxStreamsBytes  = 5 * 1024 * 1024
+	maxFirehoseBytes = 4 * 1024 * 1024
+)
+
+type kinesisAPI interface {
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+}
+
+type firehoseAPI interface {
+	PutRecordBatch(ctx context.Context, params *firehose.PutRecordBatchInput, optFns ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error)
+}
+
+// Producer sends records to a Kinesis data stream with PutRecords, or to a Firehose
+// delivery stream with PutRecordBatch when Firehose is set
+type Producer struct {
+	Firehose bool
+
+	configuration Config
+	kinesis       kinesisAPI
+	firehose      firehoseAPI
+	batcher       *batcher.Batcher[entry]
+}
+
+type entry struct {
+	key  string
+	data []byte
+}
+
+func (e entry) size() int {
+	return len(e.key) + len(e.data)
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	awsConfig, err := awscfg.Load(ctx, config.Config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load default AWS config")
+	}
+	if p.Firehose {
+		p.firehose = firehose.NewFromConfig(awsConfig)
+	} else {
+		p.kinesis = kinesis.NewFromConfig(awsConfig)
+	}
+
+	p.initialize(config)
+}
+
+func (p *Producer) initialize(config Config) {
+	p.configuration = config
+	if config.Stream == "" {
+		log.Fatal().Msg("Stream is mandatory")
+	}
+	if config.BatchSize <= 0 || config.BatchSize > MaxBatchRecords {
+		p.configuration.BatchSize = MaxBatchRecords
+	}
+	if config.MaxRetries == 0 {
+		p.configuration.MaxRetries = DefaultRetries
+	} else if config.MaxRetries < 0 {
+		p.configuration.MaxRetries = 0
+	}
+
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+
+	batchConfig := batcher.Config{
+		MaxItems:   p.configuration.BatchSize,
+		MaxBytes:   maxStreamsBytes,
+		MaxRetries: p.configuration.MaxRetries,
+		Interval:   interval,
+	}
+	if p.Firehose {
+		batchConfig.MaxBytes = maxFirehoseBytes
+		p.batcher = batcher.New(p.putRecordBatch, entry.size, batchConfig)
+	} else {
+		p.batcher = batcher.New(p.putRecords, entry.size, batchConfig)
+	}
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	// a partition key is mandatory, and the default "null" key would send all the records to one shard
+	key := string(k)
+	if key == "" || strings.ToLower(key) == "null" {
+		key = uuid.NewString()
+	}
+	data := v
+	if p.Firehose {
+		key = ""
+		if p.configuration.AppendNewline {
+			data = append(append(make([]byte, 0, len(v)+1), v...), '\n')
+		}
+	}
+	p.batcher.Add(ctx, entry{key: key, data: data})
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("stream", p.configuration.Stream).Msg("Failed to send records")
+	}
+	return err
+}
+
+func (p *Producer) putRecords(ctx context.Context, entries []entry) ([]entry, error) {
+	records := make([]types.PutRecordsRequestEntry, len(entries))
+	for i, e := range entries {
+		records[i] = types.PutRecordsRequestEntry{Data: e.data, PartitionKey: aws.String(e.key)}
+	}
+	out, err := p.kinesis.PutRecords(ctx, &kinesis.PutRecordsInput{
+		StreamName: aws.String(p.configuration.Stream),
+		Records:    records,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToInt32(out.FailedRecordCount) == 0 {
+		return nil, nil
+	}
+
+	// results are in the order of the records
+	var failed []entry
+	for i, r := range out.Records {
+		if r.ErrorCode != nil {
+			log.Debug().Str("error", aws.ToString(r.ErrorCode)).Str("message", aws.ToString(r.ErrorMessage)).Msg("Record not sent")
+			failed = append(failed, entries[i])
+		}
+	}
+	return failed, nil
+}
+
+func (p *Producer) putRecordBatch(ctx context.Context, entries []entry) ([]entry, error) {
+	records := make([]firehosetypes.Record, len(entries))
+	for i, e := range entries {
+		records[i] = firehosetypes.Record{Data: e.data}
+	}
+	out, err := p.firehose.PutRecordBatch(ctx, &firehose.PutRecordBatchInput{
+		DeliveryStreamName: aws.String(p.configuration.Stream),
+		Records:            records,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToInt32(out.FailedPutCount) == 0 {
+		return nil, nil
+	}
+
+	var failed []entry
+	for i, r := range out.RequestResponses {
+		if r.ErrorCode != nil {
+			log.Debug().Str("error", aws.ToString(r.ErrorCode)).Str("message", aws.ToString(r.ErrorMessage)).Msg("Record not sent")
+			failed = append(failed, entries[i])
+		}
+	}
+	return failed, nil
+}
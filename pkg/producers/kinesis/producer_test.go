@@ -0,0 +1,190 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kinesis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/firehose"
+	firehosetypes "github.com/aws/aws-sdk-go-v2/service/firehose/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// fakeKinesis throttles each record the first time it is sent
+type fakeKinesis struct {
+	seen     map[string]bool
+	requests []int
+	received []string
+}
+
+func (f *fakeKinesis) PutRecords(_ context.Context, in *kinesis.PutRecordsInput, _ ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	f.requests = append(f.requests, len(in.Records))
+	out := &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(0)}
+	for _, r := range in.Records {
+		id := aws.ToString(r.PartitionKey) + " " + string(r.Data)
+		if !f.seen[id] {
+			f.seen[id] = true
+			*out.FailedRecordCount++
+			out.Records = append(out.Records, types.PutRecordsResultEntry{ErrorCode: aws.String("ProvisionedThroughputExceededException")})
+			continue
+		}
+		f.received = append(f.received, id)
+		out.Records = append(out.Records, types.PutRecordsResultEntry{SequenceNumber: aws.String("1")})
+	}
+	return out, nil
+}
+
+type fakeFirehose struct {
+	lock     sync.Mutex
+	received []string
+}
+
+func (f *fakeFirehose) PutRecordBatch(_ context.Context, in *firehose.PutRecordBatchInput, _ ...func(*firehose.Options)) (*firehose.PutRecordBatchOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	out := &firehose.PutRecordBatchOutput{FailedPutCount: aws.Int32(0)}
+	for _, r := range in.Records {
+		f.received = append(f.received, string(r.Data))
+		out.RequestResponses = append(out.RequestResponses, firehosetypes.PutRecordBatchResponseEntry{RecordId: aws.String("1")})
+	}
+	return out, nil
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeKinesis{seen: make(map[string]bool)}
+	p := &Producer{kinesis: fake}
+	p.initialize(Config{Stream: "jr", BatchSize: 2})
+	p.batcher.Backoff = time.Millisecond
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf(`{"n":%d}`, i)), nil)
+		want = append(want, fmt.Sprintf(`k%d {"n":%d}`, i, i))
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(fake.received, want) {
+		t.Errorf("got %v, want %v", fake.received, want)
+	}
+	// each batch is sent twice, since all the records are throttled once
+	if wantRequests := []int{2, 2, 2, 2, 1, 1}; !reflect.DeepEqual(fake.requests, wantRequests) {
+		t.Errorf("got requests %v, want %v", fake.requests, wantRequests)
+	}
+}
+
+func TestProducerRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeKinesis{seen: make(map[string]bool)}
+	p := &Producer{kinesis: fake}
+	p.initialize(Config{Stream: "jr", MaxRetries: -1})
+
+	p.Produce(ctx, nil, []byte(`{}`), nil)
+	if err := p.Close(ctx); err == nil {
+		t.Error("expected an error for the throttled record")
+	}
+}
+
+func TestFirehoseProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeFirehose{}
+	p := &Producer{Firehose: true, firehose: fake}
+	p.initialize(Config{Stream: "jr", AppendNewline: true, FlushInterval: "10ms"})
+
+	p.Produce(ctx, []byte("k"), []byte(`{"n":1}`), nil)
+	time.Sleep(50 * time.Millisecond)
+	fake.lock.Lock()
+	received := fake.received
+	fake.lock.Unlock()
+	if want := []string{"{\"n\":1}\n"}; !reflect.DeepEqual(received, want) {
+		t.Errorf("got %q before close, want %q", received, want)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProducerDefaultKey(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeKinesis{seen: make(map[string]bool)}
+	p := &Producer{kinesis: fake}
+	p.initialize(Config{Stream: "jr"})
+	p.batcher.Backoff = time.Millisecond
+
+	// the default "null" key is replaced by random partition keys
+	p.Produce(ctx, []byte("null"), []byte(`{"n":1}`), nil)
+	p.Produce(ctx, []byte("null"), []byte(`{"n":2}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := make(map[string]bool)
+	for _, r := range fake.received {
+		key, _, _ := strings.Cut(r, " ")
+		if key == "null" {
+			t.Errorf("got partition key %q", key)
+		}
+		keys[key] = true
+	}
+	if len(keys) != 2 {
+		t.Errorf("got partition keys %v, want 2 random keys", keys)
+	}
+}
+
+func TestProducerFlushInterval(t *testing.T) {
+	for _, interval := range []string{"", "1ns"} {
+		t.Run("interval "+interval, func(t *testing.T) {
+			ctx := context.Background()
+			fake := &fakeFirehose{}
+			p := &Producer{Firehose: true, firehose: fake}
+			p.initialize(Config{Stream: "jr", FlushInterval: interval})
+
+			// an incomplete batch is sent without waiting for Close
+			p.Produce(ctx, nil, []byte(`{"n":1}`), nil)
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				fake.lock.Lock()
+				n := len(fake.received)
+				fake.lock.Unlock()
+				if n == 1 {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("batch not sent before close")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+const (
+	Insert  = "insert"
+	Replace = "replace"
+	Update  = "update"
+
+	DefaultBatchSize     = 1000
+	DefaultFlushInterval = "1s"
+)
+
+type IndexConfig struct {
+	// Keys are the fields of the index, a "-" prefix sorts a field in descending order
+	Keys   []string `json:"keys"`
+	Name   string   `json:"name"`
+	Unique bool     `json:"unique"`
+	// ExpireAfter makes a TTL index on a date field, e.g. "24h"
+	ExpireAfter string `json:"expire_after"`
+}
+
+type Config struct {
+	MongoURI string `json:"mongo_uri"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+	// Collection is evaluated for each record, e.g. "orders_{{.Value.country}}"
+	Collection string `json:"collection"`
+
+	// WriteMode is "insert", the default, "replace", which replaces the document
+	// with the JR key as _id, or "update", which sets its fields; both upsert
+	WriteMode string `json:"write_mode"`
+
+	// Records are written with BulkWrite in batches of BatchSize, at least every
+	// FlushInterval; an ordered batch stops at the first failed write
+	BatchSize     int    `json:"batch_size"`
+	FlushInterval string `json:"flush_interval"`
+	Ordered       bool   `json:"ordered"`
+
+	// Indexes are created at startup, or when a templated collection is first written
+	Indexes []IndexConfig `json:"indexes"`
+}
@@ -23,25 +23,28 @@ package mongodb
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"sync/atomic"
+	"time"
 
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/jrnd-io/jr/pkg/producers/record"
 	"github.com/rs/zerolog/log"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-type Config struct {
-	MongoURI   string `json:"mongo_uri"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	Database   string `json:"database"`
-	Collection string `json:"collection"`
-}
-
 type MongoProducer struct {
-	client     mongo.Client
-	database   string
-	collection string
+	configuration Config
+	client        *mongo.Client
+	database      *mongo.Database
+	collection    *record.Template
+	indexes       []mongo.IndexModel
+	indexed       map[string]bool
+	batcher       *batcher.Batcher[write]
+	failed        atomic.Int64
 }
 
 func (p *MongoProducer) Initialize(ctx context.Context, configFile string) {
@@ -63,42 +66,133 @@ func (p *MongoProducer) Initialize(ctx context.Context, configFile string) {
 		}
 	}
 
-	p.collection = config.Collection
-	p.database = config.Database
-
 	client, err := mongo.Connect(ctx, clientOptions)
 
 	if err != nil {
 		log.Fatal().Err(err).Msg("Can't connect to Mongo")
 	}
 
-	p.client = *client
+	p.InitializeFromClient(ctx, client, config)
 }
 
-func (p *MongoProducer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+func (p *MongoProducer) InitializeFromClient(ctx context.Context, client *mongo.Client, config Config) {
+	var err error
+	p.configuration = config
+	p.client = client
+	p.database = client.Database(config.Database)
 
-	collection := p.client.Database(p.database).Collection(p.collection)
+	if p.collection, err = record.NewTemplate("collection", config.Collection); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse collection template")
+	}
+	switch config.WriteMode {
+	case "":
+		p.configuration.WriteMode = Insert
+	case Insert, Replace, Update:
+	default:
+		log.Fatal().Str("write_mode", config.WriteMode).Msg("write_mode must be insert, replace or update")
+	}
+
+	if p.indexes, err = indexModels(config.Indexes); err != nil {
+		log.Fatal().Err(err).Msg("Invalid index configuration")
+	}
+	p.indexed = make(map[string]bool)
+	if p.collection.Static() {
+		if err := p.createIndexes(ctx, config.Collection); err != nil {
+			log.Fatal().Err(err).Msg("Failed to create indexes")
+		}
+	}
 
-	var dev map[string]interface{}
-	err := json.Unmarshal(v, &dev)
+	if config.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to unmarshal json")
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
 	}
+	p.batcher = batcher.New(p.bulkWrite, write.size, batcher.Config{
+		MaxItems: p.configuration.BatchSize,
+		Interval: interval,
+	})
+}
 
-	if len(k) == 0 {
-		dev["_id"] = string(k)
+func (p *MongoProducer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+
+	doc, err := parseDocument(v)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal json")
 	}
 
-	_, err = collection.InsertOne(ctx, dev)
+	model, err := writeModel(p.configuration.WriteMode, string(k), doc)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to write data in Mongo")
 	}
+
+	p.batcher.Add(ctx, write{
+		collection: p.collection.Execute(k, v),
+		model:      model,
+		bytes:      len(k) + len(v),
+	})
+}
+
+// bulkWrite writes a batch with a BulkWrite for each collection
+func (p *MongoProducer) bulkWrite(ctx context.Context, writes []write) ([]write, error) {
+	var collections []string
+	models := make(map[string][]mongo.WriteModel)
+	for _, w := range writes {
+		if _, ok := models[w.collection]; !ok {
+			collections = append(collections, w.collection)
+		}
+		models[w.collection] = append(models[w.collection], w.model)
+	}
+
+	opts := options.BulkWrite().SetOrdered(p.configuration.Ordered)
+	for _, name := range collections {
+		if err := p.createIndexes(ctx, name); err != nil {
+			return nil, fmt.Errorf("failed to create indexes on %s: %w", name, err)
+		}
+
+		_, err := p.database.Collection(name).BulkWrite(ctx, models[name], opts)
+		if err == nil {
+			continue
+		}
+		failed := len(models[name])
+		var bulkErr mongo.BulkWriteException
+		if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+			// an ordered bulk write stops at the first error, an unordered one tries every write
+			failed = len(bulkErr.WriteErrors)
+			if p.configuration.Ordered {
+				failed = len(models[name]) - bulkErr.WriteErrors[0].Index
+			}
+		}
+		p.failed.Add(int64(failed))
+		log.Error().Err(err).Str("collection", name).Int("documents", failed).Msg("Failed to write data in Mongo")
+	}
+	return nil, nil
+}
+
+// createIndexes creates the configured indexes the first time a collection is written
+func (p *MongoProducer) createIndexes(ctx context.Context, collection string) error {
+	if len(p.indexes) == 0 || p.indexed[collection] {
+		return nil
+	}
+	if _, err := p.database.Collection(collection).Indexes().CreateMany(ctx, p.indexes); err != nil {
+		return err
+	}
+	p.indexed[collection] = true
+	return nil
 }
 
 func (p *MongoProducer) Close(ctx context.Context) error {
-	err := p.client.Disconnect(ctx)
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to close Mongo connection")
+	err := p.batcher.Close(ctx)
+	if failed := p.failed.Load(); failed > 0 {
+		err = errors.Join(err, fmt.Errorf("%d documents not written", failed))
+	}
+	if disconnectErr := p.client.Disconnect(ctx); disconnectErr != nil {
+		log.Warn().Err(disconnectErr).Msg("Failed to close Mongo connection")
+		err = errors.Join(err, disconnectErr)
 	}
 	return err
 }
@@ -0,0 +1,98 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestBulkWrite(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("update templated collections", func(mt *mtest.T) {
+		ctx := context.Background()
+		p := &MongoProducer{}
+		p.InitializeFromClient(ctx, mt.Client, Config{
+			Database:   "jr",
+			Collection: "orders_{{.Value.country}}",
+			WriteMode:  Update,
+			BatchSize:  3,
+			Indexes:    []IndexConfig{{Keys: []string{"-ts"}}},
+		})
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+		)
+		for i, country := range []string{"it", "us", "it"} {
+			p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf(`{"country":"%s","ts":%d}`, country, i)), nil)
+		}
+		err := p.Close(ctx)
+		// the producer disconnects the client, which the mock deployment allows only once
+		mt.Client = nil
+		if err != nil {
+			mt.Fatal(err)
+		}
+
+		var commands []string
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			collection, ok := e.Command.Lookup(e.CommandName).StringValueOK()
+			if !ok {
+				continue
+			}
+			commands = append(commands, e.CommandName+" "+collection)
+			if e.CommandName == "update" && collection == "orders_us" {
+				update := e.Command.Lookup("updates").Array().Index(0).Value().Document()
+				if got := update.String(); got != `{"q": {"_id": "k1"},"u": {"$set": {"country": "us","ts": {"$numberInt":"1"}}},"upsert": true}` {
+					mt.Errorf("unexpected update %s", got)
+				}
+			}
+		}
+		want := "createIndexes orders_it,update orders_it,createIndexes orders_us,update orders_us"
+		if got := strings.Join(commands, ","); got != want {
+			mt.Errorf("got commands %s, want %s", got, want)
+		}
+	})
+
+	mt.Run("write errors", func(mt *mtest.T) {
+		ctx := context.Background()
+		p := &MongoProducer{}
+		p.InitializeFromClient(ctx, mt.Client, Config{Database: "jr", Collection: "orders"})
+
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 1, Code: 11000, Message: "duplicate key"}))
+		for i := 0; i < 3; i++ {
+			p.Produce(ctx, nil, []byte(fmt.Sprintf(`{"n":%d}`, i)), nil)
+		}
+		err := p.Close(ctx)
+		mt.Client = nil
+		if err == nil || !strings.Contains(err.Error(), "1 documents not written") {
+			mt.Errorf("expected 1 document not written, got %v", err)
+		}
+	})
+}
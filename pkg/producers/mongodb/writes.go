@@ -0,0 +1,115 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// write is a write model for a collection
+type write struct {
+	collection string
+	model      mongo.WriteModel
+	bytes      int
+}
+
+func (w write) size() int {
+	return w.bytes
+}
+
+// parseDocument decodes a record as relaxed extended JSON, keeping the order
+// of the fields and the integer types, e.g. {"ts": {"$date": "2024-05-01T10:00:00Z"}}
+func parseDocument(v []byte) (bson.D, error) {
+	var doc bson.D
+	err := bson.UnmarshalExtJSON(v, false, &doc)
+	return doc, err
+}
+
+// writeModel returns the write of a document, with the key as _id when replacing or updating
+func writeModel(mode string, key string, doc bson.D) (mongo.WriteModel, error) {
+	if mode == Insert {
+		return mongo.NewInsertOneModel().SetDocument(doc), nil
+	}
+
+	if key == "" || strings.ToLower(key) == "null" {
+		return nil, fmt.Errorf("a key is needed to %s documents", mode)
+	}
+	fields := make(bson.D, 0, len(doc))
+	for _, e := range doc {
+		if e.Key != "_id" {
+			fields = append(fields, e)
+		}
+	}
+	filter := bson.D{{Key: "_id", Value: key}}
+
+	switch mode {
+	case Replace:
+		return mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(fields).SetUpsert(true), nil
+	case Update:
+		return mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.D{{Key: "$set", Value: fields}}).SetUpsert(true), nil
+	}
+	return nil, fmt.Errorf("unsupported write mode %q", mode)
+}
+
+// indexModels converts the configuration of the indexes to index models
+func indexModels(configs []IndexConfig) ([]mongo.IndexModel, error) {
+	models := make([]mongo.IndexModel, 0, len(configs))
+	for _, c := range configs {
+		if len(c.Keys) == 0 {
+			return nil, errors.New("an index needs at least a key")
+		}
+		keys := make(bson.D, len(c.Keys))
+		for i, k := range c.Keys {
+			if field, ok := strings.CutPrefix(k, "-"); ok {
+				keys[i] = bson.E{Key: field, Value: -1}
+			} else {
+				keys[i] = bson.E{Key: k, Value: 1}
+			}
+		}
+
+		opts := options.Index()
+		if c.Name != "" {
+			opts.SetName(c.Name)
+		}
+		if c.Unique {
+			opts.SetUnique(true)
+		}
+		if c.ExpireAfter != "" {
+			ttl, err := time.ParseDuration(c.ExpireAfter)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expire_after: %w", err)
+			}
+			if len(c.Keys) != 1 {
+				return nil, errors.New("a TTL index must have a single key")
+			}
+			opts.SetExpireAfterSeconds(int32(ttl.Seconds()))
+		}
+		models = append(models, mongo.IndexModel{Keys: keys, Options: opts})
+	}
+	return models, nil
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mongodb
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestParseDocument(t *testing.T) {
+	doc, err := parseDocument([]byte(`{"id": 1, "price": 9.5, "name": "shoe"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bson.D{{Key: "id", Value: int32(1)}, {Key: "price", Value: 9.5}, {Key: "name", Value: "shoe"}}
+	if !reflect.DeepEqual(doc, want) {
+		t.Errorf("got %v, want %v", doc, want)
+	}
+}
+
+func TestWriteModel(t *testing.T) {
+	doc := bson.D{{Key: "_id", Value: "x"}, {Key: "n", Value: int32(1)}}
+	filter := bson.D{{Key: "_id", Value: "k"}}
+	fields := bson.D{{Key: "n", Value: int32(1)}}
+
+	testCases := []struct {
+		name string
+		mode string
+		key  string
+		want mongo.WriteModel
+	}{
+		{name: "insert", mode: Insert, key: "k", want: mongo.NewInsertOneModel().SetDocument(doc)},
+		{name: "insert without key", mode: Insert, key: "null", want: mongo.NewInsertOneModel().SetDocument(doc)},
+		{name: "replace", mode: Replace, key: "k", want: mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(fields).SetUpsert(true)},
+		{name: "update", mode: Update, key: "k", want: mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(bson.D{{Key: "$set", Value: fields}}).SetUpsert(true)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := writeModel(tc.mode, tc.key, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+
+	for _, key := range []string{"", "null"} {
+		if _, err := writeModel(Update, key, doc); err == nil {
+			t.Errorf("expected an error updating without a key %q", key)
+		}
+	}
+}
+
+func TestIndexModels(t *testing.T) {
+	models, err := indexModels([]IndexConfig{
+		{Keys: []string{"customer_id", "-ts"}, Name: "by_customer", Unique: true},
+		{Keys: []string{"created_at"}, ExpireAfter: "24h"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) != 2 {
+		t.Fatalf("got %d models, want 2", len(models))
+	}
+
+	wantKeys := bson.D{{Key: "customer_id", Value: 1}, {Key: "ts", Value: -1}}
+	if !reflect.DeepEqual(models[0].Keys, wantKeys) {
+		t.Errorf("got keys %v, want %v", models[0].Keys, wantKeys)
+	}
+	if *models[0].Options.Name != "by_customer" || !*models[0].Options.Unique {
+		t.Errorf("unexpected options %+v", models[0].Options)
+	}
+	if *models[1].Options.ExpireAfterSeconds != 86400 {
+		t.Errorf("got expire after %d, want 86400", *models[1].Options.ExpireAfterSeconds)
+	}
+
+	invalid := [][]IndexConfig{
+		{{}},
+		{{Keys: []string{"a", "b"}, ExpireAfter: "1h"}},
+		{{Keys: []string{"a"}, ExpireAfter: "soon"}},
+	}
+	for _, configs := range invalid {
+		if _, err := indexModels(configs); err == nil {
+			t.Errorf("expected an error for %+v", configs)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/eclipse/paho.golang/autopaho"
+	"github.com/eclipse/paho.golang/paho"
+	pahov3 "github.com/eclipse/paho.mqtt.golang"
+	"github.com/rs/zerolog/log"
+)
+
+// client is a connection to the broker with one client ID
+type client interface {
+	publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte) error
+	disconnect(ctx context.Context) error
+}
+
+type settings struct {
+	broker         string
+	clientID       string
+	username       string
+	password       string
+	tls            *tls.Config
+	keepAlive      time.Duration
+	connectTimeout time.Duration
+}
+
+// v3Client speaks MQTT 3.1.1
+type v3Client struct {
+	client pahov3.Client
+}
+
+func connectV3(s settings) (client, error) {
+	opts := pahov3.NewClientOptions().
+		AddBroker(s.broker).
+		SetClientID(s.clientID).
+		SetProtocolVersion(4).
+		SetKeepAlive(s.keepAlive).
+		SetConnectTimeout(s.connectTimeout).
+		SetAutoReconnect(true)
+	if s.username != "" {
+		opts.SetUsername(s.username)
+		opts.SetPassword(s.password)
+	}
+	if s.tls != nil {
+		opts.SetTLSConfig(s.tls)
+	}
+
+	c := pahov3.NewClient(opts)
+	token := c.Connect()
+	if !token.WaitTimeout(s.connectTimeout) {
+		return nil, errors.New("timeout connecting to broker")
+	}
+	if err := token.Error(); err != nil {
+		return nil, err
+	}
+	return &v3Client{client: c}, nil
+}
+
+func (c *v3Client) publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte) error {
+	token := c.client.Publish(topic, qos, retain, payload)
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *v3Client) disconnect(_ context.Context) error {
+	c.client.Disconnect(250)
+	return nil
+}
+
+// v5Client speaks MQTT 5
+type v5Client struct {
+	cm *autopaho.ConnectionManager
+}
+
+func connectV5(ctx context.Context, s settings) (client, error) {
+	broker, err := url.Parse(s.broker)
+	if err != nil {
+		return nil, err
+	}
+
+	config := autopaho.ClientConfig{
+		ServerUrls:                    []*url.URL{broker},
+		TlsCfg:                        s.tls,
+		KeepAlive:                     uint16(s.keepAlive.Seconds()),
+		CleanStartOnInitialConnection: true,
+		ConnectTimeout:                s.connectTimeout,
+		ConnectUsername:               s.username,
+		ConnectPassword:               []byte(s.password),
+		OnConnectError: func(err error) {
+			log.Warn().Err(err).Str("client_id", s.clientID).Msg("MQTT connection failed")
+		},
+		ClientConfig: paho.ClientConfig{ClientID: s.clientID},
+	}
+
+	// the connection outlives the context of the first record
+	cm, err := autopaho.NewConnection(context.Background(), config)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.connectTimeout)
+	defer cancel()
+	if err = cm.AwaitConnection(ctx); err != nil {
+		_ = cm.Disconnect(context.Background())
+		return nil, err
+	}
+	return &v5Client{cm: cm}, nil
+}
+
+func (c *v5Client) publish(ctx context.Context, topic string, qos byte, retain bool, payload []byte) error {
+	_, err := c.cm.Publish(ctx, &paho.Publish{
+		Topic:   topic,
+		QoS:     qos,
+		Retain:  retain,
+		Payload: payload,
+	})
+	return err
+}
+
+func (c *v5Client) disconnect(ctx context.Context) error {
+	err := c.cm.Disconnect(ctx)
+	<-c.cm.Done()
+	return err
+}
@@ -0,0 +1,51 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mqtt
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	Version311 = "3.1.1"
+	Version5   = "5"
+
+	DefaultKeepAlive      = "30s"
+	DefaultConnectTimeout = "10s"
+)
+
+type Config struct {
+	// Broker is the server URL, e.g. tcp://localhost:1883, ssl://localhost:8883 or ws://localhost:8080
+	Broker string `json:"broker"`
+	// Version is the protocol version: 3.1.1 (the default) or 5
+	Version string `json:"version"`
+	// Topic is evaluated for each record, e.g. "devices/{{.Value.device_id}}/telemetry"
+	Topic  string `json:"topic"`
+	QoS    byte   `json:"qos"`
+	Retain bool   `json:"retain"`
+	// ClientID is evaluated for each record: records with different client IDs are published
+	// by different connections, e.g. "{{.Value.device_id}}" for one connection per device.
+	// Defaults to a random "jr-" client ID
+	ClientID       string           `json:"client_id"`
+	Username       string           `json:"username"`
+	Password       string           `json:"password"`
+	KeepAlive      string           `json:"keep_alive"`
+	ConnectTimeout string           `json:"connect_timeout"`
+	TLS            tlsconfig.Config `json:"tls"`
+}
@@ -0,0 +1,182 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer publishes records to an MQTT broker. When the client ID depends on the
+// record, each client ID gets its own connection, so that devices connect as themselves.
+type Producer struct {
+	configuration Config
+	topic         *record.Template
+	clientID      *record.Template
+	settings      settings
+
+	lock    sync.Mutex
+	clients map[string]client
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	p.InitializeFromConfig(ctx, config)
+}
+
+func (p *Producer) InitializeFromConfig(ctx context.Context, config Config) {
+	var err error
+	p.configuration = config
+	if config.Broker == "" {
+		log.Fatal().Msg("Broker is mandatory")
+	}
+	if config.QoS > 2 {
+		log.Fatal().Uint8("qos", config.QoS).Msg("QoS must be 0, 1 or 2")
+	}
+	switch config.Version {
+	case "":
+		p.configuration.Version = Version311
+	case Version311, Version5:
+	default:
+		log.Fatal().Str("version", config.Version).Msg("MQTT version must be 3.1.1 or 5")
+	}
+	if p.configuration.ClientID == "" {
+		p.configuration.ClientID = "jr-" + uuid.NewString()[:8]
+	}
+	if p.configuration.KeepAlive == "" {
+		p.configuration.KeepAlive = DefaultKeepAlive
+	}
+	if p.configuration.ConnectTimeout == "" {
+		p.configuration.ConnectTimeout = DefaultConnectTimeout
+	}
+
+	p.topic, err = record.NewTemplate("topic", config.Topic)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse topic template")
+	}
+	p.clientID, err = record.NewTemplate("client_id", p.configuration.ClientID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse client_id template")
+	}
+
+	p.settings = settings{
+		broker:   config.Broker,
+		username: config.Username,
+		password: config.Password,
+	}
+	p.settings.keepAlive, err = time.ParseDuration(p.configuration.KeepAlive)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse keep_alive")
+	}
+	p.settings.connectTimeout, err = time.ParseDuration(p.configuration.ConnectTimeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse connect_timeout")
+	}
+	if config.TLS.IsSet() {
+		p.settings.tls, err = config.TLS.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+
+	p.clients = make(map[string]client)
+	// a single client connects immediately, to fail fast on a wrong configuration
+	if p.clientID.Static() {
+		if _, err = p.client(ctx, p.configuration.ClientID); err != nil {
+			log.Fatal().Err(err).Str("broker", config.Broker).Msg("Can't connect to MQTT broker")
+		}
+	}
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	topic := p.topic.ExecuteWith(data)
+	clientID := p.clientID.ExecuteWith(data)
+	if topic == "" {
+		log.Error().Msg("Empty MQTT topic")
+		return
+	}
+
+	p.lock.Lock()
+	c, err := p.client(ctx, clientID)
+	p.lock.Unlock()
+	if err != nil {
+		log.Error().Err(err).Str("client_id", clientID).Msg("Can't connect to MQTT broker")
+		return
+	}
+
+	if err = c.publish(ctx, topic, p.configuration.QoS, p.configuration.Retain, v); err != nil {
+		log.Error().Err(err).Str("topic", topic).Str("client_id", clientID).Msg("Failed to publish MQTT message")
+	}
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var errs []error
+	for id, c := range p.clients {
+		if err := c.disconnect(ctx); err != nil {
+			log.Warn().Err(err).Str("client_id", id).Msg("Failed to close MQTT connection")
+			errs = append(errs, err)
+		}
+	}
+	clear(p.clients)
+	return errors.Join(errs...)
+}
+
+// client returns the connection of a client ID, connecting it the first time
+func (p *Producer) client(ctx context.Context, id string) (client, error) {
+	if c, ok := p.clients[id]; ok {
+		return c, nil
+	}
+
+	s := p.settings
+	s.clientID = id
+	var c client
+	var err error
+	if p.configuration.Version == Version5 {
+		c, err = connectV5(ctx, s)
+	} else {
+		c, err = connectV3(s)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.clients[id] = c
+	return c, nil
+}
@@ -0,0 +1,185 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package mqtt_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/mqtt"
+	server "github.com/mochi-mqtt/server/v2"
+	"github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+)
+
+type message struct {
+	clientID string
+	version  byte
+	topic    string
+	payload  string
+	qos      byte
+	retain   bool
+}
+
+// recorder is a broker hook recording the published messages
+type recorder struct {
+	server.HookBase
+	lock     sync.Mutex
+	messages []message
+}
+
+func (r *recorder) ID() string {
+	return "recorder"
+}
+
+func (r *recorder) Provides(b byte) bool {
+	return b == server.OnPublished
+}
+
+func (r *recorder) OnPublished(cl *server.Client, pk packets.Packet) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.messages = append(r.messages, message{
+		clientID: cl.ID,
+		version:  cl.Properties.ProtocolVersion,
+		topic:    pk.TopicName,
+		payload:  string(pk.Payload),
+		qos:      pk.FixedHeader.Qos,
+		retain:   pk.FixedHeader.Retain,
+	})
+}
+
+func (r *recorder) received() []message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	messages := append([]message(nil), r.messages...)
+	sort.Slice(messages, func(i, j int) bool { return messages[i].payload < messages[j].payload })
+	return messages
+}
+
+func startBroker(t *testing.T) (string, *recorder) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := l.Addr().String()
+	_ = l.Close()
+
+	broker := server.New(&server.Options{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	if err = broker.AddHook(new(auth.AllowHook), nil); err != nil {
+		t.Fatal(err)
+	}
+	r := &recorder{}
+	if err = broker.AddHook(r, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err = broker.AddListener(listeners.NewTCP(listeners.Config{ID: "tcp", Address: address})); err != nil {
+		t.Fatal(err)
+	}
+	if err = broker.Serve(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = broker.Close() })
+	return "tcp://" + address, r
+}
+
+func TestProducer(t *testing.T) {
+	records := []string{
+		`{"n": 0, "device_id": "d1"}`,
+		`{"n": 1, "device_id": "d2"}`,
+		`{"n": 2, "device_id": "d1"}`,
+	}
+
+	testCases := []struct {
+		name   string
+		config mqtt.Config
+		want   func(i int, device string) message
+	}{
+		{
+			name:   "v3.1.1 single client",
+			config: mqtt.Config{Topic: "jr/devices", ClientID: "jr-test", QoS: 1},
+			want: func(_ int, _ string) message {
+				return message{clientID: "jr-test", version: 4, topic: "jr/devices", qos: 1}
+			},
+		},
+		{
+			name:   "v5 client per device",
+			config: mqtt.Config{Version: mqtt.Version5, Topic: "devices/{{.Value.device_id}}/telemetry", ClientID: "{{.Value.device_id}}", QoS: 2, Retain: true},
+			want: func(_ int, device string) message {
+				return message{clientID: device, version: 5, topic: fmt.Sprintf("devices/%s/telemetry", device), qos: 2, retain: true}
+			},
+		},
+		{
+			name:   "v3.1.1 topic from key",
+			config: mqtt.Config{Topic: "keys/{{.K}}"},
+			want: func(i int, _ string) message {
+				return message{version: 4, topic: fmt.Sprintf("keys/k%d", i)}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			broker, r := startBroker(t)
+			tc.config.Broker = broker
+
+			p := &mqtt.Producer{}
+			p.InitializeFromConfig(ctx, tc.config)
+			for i, v := range records {
+				p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(v), nil)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			var got []message
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+				if got = r.received(); len(got) == len(records) {
+					break
+				}
+			}
+			if len(got) != len(records) {
+				t.Fatalf("got %d messages, want %d", len(got), len(records))
+			}
+			for i, m := range got {
+				device := []string{"d1", "d2", "d1"}[i]
+				want := tc.want(i, device)
+				want.payload = records[i]
+				if want.clientID == "" {
+					want.clientID = m.clientID
+				}
+				if m != want {
+					t.Errorf("message %d: got %+v, want %+v", i, m, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nats
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	DefaultAckWait = "5s"
+	DefaultMsgID   = "{{.K}}"
+)
+
+type Config struct {
+	// URL of the servers, comma separated, e.g. nats://localhost:4222
+	URL string `json:"url"`
+	// Subject is evaluated for each record, e.g. "orders.{{.Value.region}}"
+	Subject string `json:"subject"`
+	// Headers values are evaluated for each record
+	Headers map[string]string `json:"headers"`
+
+	// JetStream publishes to a stream and waits for its ack, up to AckWait
+	JetStream bool   `json:"jetstream"`
+	AckWait   string `json:"ack_wait"`
+	// MsgID is evaluated for each record and set as Nats-Msg-Id, so that JetStream
+	// discards duplicates; the JR key by default, unless it is the default "null" key
+	MsgID string `json:"msg_id"`
+	// Stream, when set, is created or updated at startup to capture StreamSubjects
+	Stream         string   `json:"stream"`
+	StreamSubjects []string `json:"stream_subjects"`
+
+	Username        string           `json:"username"`
+	Password        string           `json:"password"`
+	Token           string           `json:"token"`
+	CredentialsFile string           `json:"credentials_file"`
+	TLS             tlsconfig.Config `json:"tls"`
+}
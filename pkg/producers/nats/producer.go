@@ -0,0 +1,175 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nats
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer publishes records to NATS subjects, or to JetStream waiting for the acks
+type Producer struct {
+	configuration Config
+	subject       *record.Template
+	headers       map[string]*record.Template
+	msgID         *record.Template
+	ackWait       time.Duration
+
+	conn *natsgo.Conn
+	js   jetstream.JetStream
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	p.InitializeFromConfig(ctx, config)
+}
+
+func (p *Producer) InitializeFromConfig(ctx context.Context, config Config) {
+	var err error
+	p.configuration = config
+	if config.URL == "" {
+		p.configuration.URL = natsgo.DefaultURL
+	}
+	if config.AckWait == "" {
+		p.configuration.AckWait = DefaultAckWait
+	}
+
+	p.subject, err = record.NewTemplate("subject", config.Subject)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse subject template")
+	}
+	if config.JetStream && config.MsgID == "" {
+		p.configuration.MsgID = DefaultMsgID
+	}
+	p.msgID, err = record.NewTemplate("msg_id", p.configuration.MsgID)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse msg_id template")
+	}
+	p.headers = make(map[string]*record.Template, len(config.Headers))
+	for name, value := range config.Headers {
+		p.headers[name], err = record.NewTemplate(name, value)
+		if err != nil {
+			log.Fatal().Err(err).Str("header", name).Msg("Failed to parse header template")
+		}
+	}
+	p.ackWait, err = time.ParseDuration(p.configuration.AckWait)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse ack_wait")
+	}
+
+	options := []natsgo.Option{natsgo.Name("jr")}
+	if config.Username != "" {
+		options = append(options, natsgo.UserInfo(config.Username, config.Password))
+	}
+	if config.Token != "" {
+		options = append(options, natsgo.Token(config.Token))
+	}
+	if config.CredentialsFile != "" {
+		options = append(options, natsgo.UserCredentials(config.CredentialsFile))
+	}
+	if config.TLS.IsSet() {
+		tlsConfig, err := config.TLS.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+		options = append(options, natsgo.Secure(tlsConfig))
+	}
+
+	p.conn, err = natsgo.Connect(p.configuration.URL, options...)
+	if err != nil {
+		log.Fatal().Err(err).Str("url", p.configuration.URL).Msg("Can't connect to NATS")
+	}
+
+	if !config.JetStream {
+		return
+	}
+	p.js, err = jetstream.New(p.conn)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create JetStream context")
+	}
+	if config.Stream != "" {
+		_, err = p.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+			Name:     config.Stream,
+			Subjects: config.StreamSubjects,
+		})
+		if err != nil {
+			log.Fatal().Err(err).Str("stream", config.Stream).Msg("Failed to create stream")
+		}
+	}
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	msg := natsgo.NewMsg(p.subject.ExecuteWith(data))
+	msg.Data = v
+	for name, value := range p.headers {
+		msg.Header.Set(name, value.ExecuteWith(data))
+	}
+
+	if p.js == nil {
+		if err := p.conn.PublishMsg(msg); err != nil {
+			log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to publish NATS message")
+		}
+		return
+	}
+
+	var opts []jetstream.PublishOpt
+	// the default "null" key would discard all the messages but the first as duplicates
+	if id := p.msgID.ExecuteWith(data); id != "" && strings.ToLower(id) != "null" {
+		opts = append(opts, jetstream.WithMsgID(id))
+	}
+	ctx, cancel := context.WithTimeout(ctx, p.ackWait)
+	defer cancel()
+	ack, err := p.js.PublishMsg(ctx, msg, opts...)
+	if err != nil {
+		log.Error().Err(err).Str("subject", msg.Subject).Msg("Failed to publish JetStream message")
+		return
+	}
+	if ack.Duplicate {
+		log.Debug().Str("subject", msg.Subject).Uint64("sequence", ack.Sequence).Msg("Duplicate JetStream message discarded")
+	}
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	// the pending messages are sent before closing
+	err := p.conn.Flush()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to flush NATS connection")
+	}
+	p.conn.Close()
+	return err
+}
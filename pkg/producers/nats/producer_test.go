@@ -0,0 +1,150 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package nats_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/nats"
+	"github.com/nats-io/nats-server/v2/server"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func startServer(t *testing.T) string {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(s.Shutdown)
+	return s.ClientURL()
+}
+
+var records = []string{
+	`{"region": "eu", "n": 0}`,
+	`{"region": "us", "n": 1}`,
+	`{"region": "eu", "n": 2}`,
+}
+
+func produce(t *testing.T, config nats.Config, keys []string) {
+	t.Helper()
+	ctx := context.Background()
+	p := &nats.Producer{}
+	p.InitializeFromConfig(ctx, config)
+	for i, v := range records {
+		p.Produce(ctx, []byte(keys[i]), []byte(v), nil)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProducer(t *testing.T) {
+	url := startServer(t)
+	conn, err := natsgo.Connect(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	sub, err := conn.SubscribeSync("orders.>")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	produce(t, nats.Config{
+		URL:     url,
+		Subject: "orders.{{.Value.region}}",
+		Headers: map[string]string{"source": "jr", "key": "{{.K}}"},
+	}, []string{"a", "b", "c"})
+
+	for i, want := range []string{"orders.eu", "orders.us", "orders.eu"} {
+		msg, err := sub.NextMsg(2 * time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Subject != want || string(msg.Data) != records[i] {
+			t.Errorf("got %s %s, want %s %s", msg.Subject, msg.Data, want, records[i])
+		}
+		if msg.Header.Get("source") != "jr" || msg.Header.Get("key") != []string{"a", "b", "c"}[i] {
+			t.Errorf("unexpected headers %v", msg.Header)
+		}
+	}
+}
+
+func TestProducerJetStream(t *testing.T) {
+	testCases := []struct {
+		name  string
+		msgID string
+		keys  []string
+		want  uint64
+	}{
+		{name: "default msg_id", keys: []string{"a", "b", "a"}, want: 2},
+		{name: "default key", keys: []string{"null", "null", "null"}, want: 3},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			url := startServer(t)
+			produce(t, nats.Config{
+				URL:            url,
+				Subject:        "orders.{{.Value.region}}",
+				JetStream:      true,
+				AckWait:        "2s",
+				MsgID:          tc.msgID,
+				Stream:         "ORDERS",
+				StreamSubjects: []string{"orders.>"},
+			}, tc.keys)
+
+			conn, err := natsgo.Connect(url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			js, _ := jetstream.New(conn)
+			stream, err := js.Stream(context.Background(), "ORDERS")
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := stream.Info(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.State.Msgs != tc.want {
+				t.Errorf("got %d messages, want %d", info.State.Msgs, tc.want)
+			}
+			msg, err := stream.GetMsg(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fmt.Sprintf("%s %s", msg.Subject, msg.Data); got != "orders.eu "+records[0] {
+				t.Errorf("got %s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otlp
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	GRPC         = "grpc"
+	HTTPProtobuf = "http/protobuf"
+
+	Logs    = "logs"
+	Traces  = "traces"
+	Metrics = "metrics"
+
+	DefaultGRPCEndpoint  = "localhost:4317"
+	DefaultHTTPEndpoint  = "http://localhost:4318"
+	DefaultTimeout       = "10s"
+	DefaultBatchSize     = 100
+	DefaultRetries       = 3
+	DefaultFlushInterval = "1s"
+)
+
+type LogsConfig struct {
+	// Body is evaluated for each record, the whole record by default
+	Body string `json:"body"`
+	// Severity is evaluated for each record, as a name, e.g. "WARN", or a number from 1 to 24
+	Severity string `json:"severity"`
+}
+
+type TracesConfig struct {
+	// Services are the names of the services of the spans
+	Services []string `json:"services"`
+	// RootName is the name of the root span, evaluated for each record
+	RootName string `json:"root_name"`
+	// MaxDepth is the number of levels of calls under the root span, MaxChildren
+	// the maximum number of calls of each span
+	MaxDepth    int `json:"max_depth"`
+	MaxChildren int `json:"max_children"`
+	// MinDuration and MaxDuration bound the duration of the root span, e.g. "20ms" and "2s"
+	MinDuration string `json:"min_duration"`
+	MaxDuration string `json:"max_duration"`
+	// ErrorRate is the probability of a span to fail, from 0 to 1
+	ErrorRate float64 `json:"error_rate"`
+}
+
+type MetricsConfig struct {
+	// Prefix is prepended to the names of the gauges, one for each numeric field of the record
+	Prefix string `json:"prefix"`
+}
+
+type Config struct {
+	// Endpoint is host:port for gRPC and the base URL for HTTP
+	Endpoint string `json:"endpoint"`
+	// Protocol is "grpc", the default, or "http/protobuf"
+	Protocol string `json:"protocol"`
+	// Signal is "logs", the default, "traces" or "metrics"
+	Signal  string            `json:"signal"`
+	Headers map[string]string `json:"headers"`
+	Timeout string            `json:"timeout"`
+	TLS     tlsconfig.Config  `json:"tls"`
+
+	// BatchSize records are exported in each request, at least every FlushInterval, 1s by default;
+	// exports rejected as retryable are sent again up to MaxRetries times, -1 for none
+	BatchSize     int    `json:"batch_size"`
+	FlushInterval string `json:"flush_interval"`
+	MaxRetries    int    `json:"max_retries"`
+
+	// Resource attributes, service.name defaults to "jr"
+	Resource map[string]string `json:"resource"`
+	// RecordAttributes adds the top-level fields of each record as attributes
+	RecordAttributes bool `json:"record_attributes"`
+	// Attributes values are evaluated for each record
+	Attributes map[string]string `json:"attributes"`
+
+	Logs    LogsConfig    `json:"logs"`
+	Traces  TracesConfig  `json:"traces"`
+	Metrics MetricsConfig `json:"metrics"`
+}
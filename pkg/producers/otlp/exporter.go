@@ -0,0 +1,178 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// retryableError is an export rejected by the collector that can be sent again
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+type exporter interface {
+	export(ctx context.Context, signal string, request proto.Message) error
+	close() error
+}
+
+type grpcExporter struct {
+	conn    *grpc.ClientConn
+	headers metadata.MD
+	logs    collogspb.LogsServiceClient
+	traces  coltracepb.TraceServiceClient
+	metrics colmetricspb.MetricsServiceClient
+}
+
+func newGRPCExporter(endpoint string, tlsConfig *tls.Config, headers map[string]string) (*grpcExporter, error) {
+	transport := insecure.NewCredentials()
+	if tlsConfig != nil {
+		transport = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(transport))
+	if err != nil {
+		return nil, err
+	}
+	return &grpcExporter{
+		conn:    conn,
+		headers: metadata.New(headers),
+		logs:    collogspb.NewLogsServiceClient(conn),
+		traces:  coltracepb.NewTraceServiceClient(conn),
+		metrics: colmetricspb.NewMetricsServiceClient(conn),
+	}, nil
+}
+
+func (e *grpcExporter) export(ctx context.Context, _ string, request proto.Message) error {
+	ctx = metadata.NewOutgoingContext(ctx, e.headers)
+
+	var err error
+	var rejected int64
+	var message string
+	switch r := request.(type) {
+	case *collogspb.ExportLogsServiceRequest:
+		var resp *collogspb.ExportLogsServiceResponse
+		if resp, err = e.logs.Export(ctx, r); err == nil {
+			rejected, message = resp.GetPartialSuccess().GetRejectedLogRecords(), resp.GetPartialSuccess().GetErrorMessage()
+		}
+	case *coltracepb.ExportTraceServiceRequest:
+		var resp *coltracepb.ExportTraceServiceResponse
+		if resp, err = e.traces.Export(ctx, r); err == nil {
+			rejected, message = resp.GetPartialSuccess().GetRejectedSpans(), resp.GetPartialSuccess().GetErrorMessage()
+		}
+	case *colmetricspb.ExportMetricsServiceRequest:
+		var resp *colmetricspb.ExportMetricsServiceResponse
+		if resp, err = e.metrics.Export(ctx, r); err == nil {
+			rejected, message = resp.GetPartialSuccess().GetRejectedDataPoints(), resp.GetPartialSuccess().GetErrorMessage()
+		}
+	default:
+		return fmt.Errorf("unsupported request %T", request)
+	}
+
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+			return retryableError{err}
+		}
+		return err
+	}
+	if rejected > 0 {
+		return fmt.Errorf("%d items rejected: %s", rejected, message)
+	}
+	return nil
+}
+
+func (e *grpcExporter) close() error {
+	return e.conn.Close()
+}
+
+type httpExporter struct {
+	client   *http.Client
+	endpoint string
+	headers  map[string]string
+}
+
+func newHTTPExporter(endpoint string, tlsConfig *tls.Config, headers map[string]string) *httpExporter {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &httpExporter{
+		client:   &http.Client{Transport: transport},
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		headers:  headers,
+	}
+}
+
+func (e *httpExporter) export(ctx context.Context, signal string, request proto.Message) error {
+	body, err := proto.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+"/v1/"+signal, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	for name, value := range e.headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return retryableError{err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusBadGateway ||
+		resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout:
+		return retryableError{fmt.Errorf("unexpected status code %d", resp.StatusCode)}
+	default:
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+func (e *httpExporter) close() error {
+	e.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,394 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otlp
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/functions"
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+var defaultServices = []string{"frontend", "checkout", "cart", "payment", "inventory", "shipping"}
+
+var severityNames = map[string]logspb.SeverityNumber{
+	"TRACE":    logspb.SeverityNumber_SEVERITY_NUMBER_TRACE,
+	"DEBUG":    logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG,
+	"INFO":     logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+	"WARN":     logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"WARNING":  logspb.SeverityNumber_SEVERITY_NUMBER_WARN,
+	"ERROR":    logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+	"FATAL":    logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+	"CRITICAL": logspb.SeverityNumber_SEVERITY_NUMBER_FATAL,
+}
+
+// telemetry is what a record is converted to, depending on the signal
+type telemetry struct {
+	log     *logspb.LogRecord
+	spans   []serviceSpan
+	metrics []*metricspb.Metric
+}
+
+func (t telemetry) size() int {
+	size := proto.Size(t.log)
+	for _, s := range t.spans {
+		size += proto.Size(s.span)
+	}
+	for _, m := range t.metrics {
+		size += proto.Size(m)
+	}
+	return size
+}
+
+// Producer exports records to an OpenTelemetry collector as log records,
+// synthetic traces or gauges
+type Producer struct {
+	configuration Config
+	timeout       time.Duration
+	exporter      exporter
+	batcher       *batcher.Batcher[telemetry]
+	resource      []*commonpb.KeyValue
+	scope         *commonpb.InstrumentationScope
+	now           func() time.Time
+
+	attributes map[string]*record.Template
+	body       *record.Template
+	severity   *record.Template
+	rootName   *record.Template
+	traces     *traceGenerator
+}
+
+func (p *Producer) Initialize(configFile string) {
+	cfgBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config file")
+	}
+
+	config := Config{}
+	if err := json.Unmarshal(cfgBytes, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal config")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+
+	switch config.Signal {
+	case "":
+		p.configuration.Signal = Logs
+	case Logs, Traces, Metrics:
+	default:
+		log.Fatal().Str("signal", config.Signal).Msg("signal must be logs, traces or metrics")
+	}
+
+	if config.Timeout == "" {
+		p.configuration.Timeout = DefaultTimeout
+	}
+	if p.timeout, err = time.ParseDuration(p.configuration.Timeout); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse timeout")
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS.IsSet() {
+		if tlsConfig, err = config.TLS.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+	switch config.Protocol {
+	case "", GRPC:
+		if p.configuration.Endpoint == "" {
+			p.configuration.Endpoint = DefaultGRPCEndpoint
+		}
+		if p.exporter, err = newGRPCExporter(p.configuration.Endpoint, tlsConfig, config.Headers); err != nil {
+			log.Fatal().Err(err).Msg("Failed to create client")
+		}
+	case HTTPProtobuf:
+		if p.configuration.Endpoint == "" {
+			p.configuration.Endpoint = DefaultHTTPEndpoint
+		}
+		p.exporter = newHTTPExporter(p.configuration.Endpoint, tlsConfig, config.Headers)
+	default:
+		log.Fatal().Str("protocol", config.Protocol).Msg("protocol must be grpc or http/protobuf")
+	}
+
+	resource := map[string]any{"service.name": "jr"}
+	for k, v := range config.Resource {
+		resource[k] = v
+	}
+	p.resource = keyValues(resource)
+	p.scope = &commonpb.InstrumentationScope{Name: "github.com/jrnd-io/jr"}
+	p.now = time.Now
+
+	p.attributes = make(map[string]*record.Template, len(config.Attributes))
+	for name, value := range config.Attributes {
+		p.attributes[name] = newTemplate(name, value, "")
+	}
+	p.body = newTemplate("body", config.Logs.Body, "{{.V}}")
+	p.severity = newTemplate("severity", config.Logs.Severity, "INFO")
+	p.rootName = newTemplate("root_name", config.Traces.RootName, "GET /")
+	p.traces = newTraceGenerator(config.Traces)
+
+	if p.configuration.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+	if config.MaxRetries == 0 {
+		p.configuration.MaxRetries = DefaultRetries
+	} else if config.MaxRetries < 0 {
+		p.configuration.MaxRetries = 0
+	}
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	p.batcher = batcher.New(p.export, telemetry.size, batcher.Config{
+		MaxItems:   p.configuration.BatchSize,
+		MaxRetries: p.configuration.MaxRetries,
+		Interval:   interval,
+	})
+}
+
+func newTemplate(name string, text string, defaultText string) *record.Template {
+	if text == "" {
+		text = defaultText
+	}
+	t, err := record.NewTemplate(name, text)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to parse %s template", name)
+	}
+	return t
+}
+
+func newTraceGenerator(config TracesConfig) *traceGenerator {
+	g := &traceGenerator{
+		rand:        functions.Random,
+		services:    config.Services,
+		maxDepth:    config.MaxDepth,
+		maxChildren: config.MaxChildren,
+		errorRate:   config.ErrorRate,
+		minDuration: 20 * time.Millisecond,
+		maxDuration: 2 * time.Second,
+	}
+	if len(g.services) == 0 {
+		g.services = defaultServices
+	}
+	if g.maxDepth <= 0 {
+		g.maxDepth = 3
+	}
+	if g.maxChildren <= 0 {
+		g.maxChildren = 3
+	}
+	var err error
+	if config.MinDuration != "" {
+		if g.minDuration, err = time.ParseDuration(config.MinDuration); err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse min_duration")
+		}
+	}
+	if config.MaxDuration != "" {
+		if g.maxDuration, err = time.ParseDuration(config.MaxDuration); err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse max_duration")
+		}
+	}
+	return g
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	var attributes []*commonpb.KeyValue
+	if p.configuration.RecordAttributes && data.Value != nil {
+		attributes = keyValues(data.Value)
+	}
+	if len(p.attributes) > 0 {
+		evaluated := make(map[string]any, len(p.attributes))
+		for name, value := range p.attributes {
+			evaluated[name] = value.ExecuteWith(data)
+		}
+		attributes = append(attributes, keyValues(evaluated)...)
+	}
+
+	now := p.now()
+	var t telemetry
+	switch p.configuration.Signal {
+	case Logs:
+		t.log = p.logRecord(data, now, attributes)
+	case Traces:
+		t.spans = p.traces.generate(p.rootName.ExecuteWith(data), now, attributes)
+	case Metrics:
+		t.metrics = p.gauges(data, now, attributes)
+		if len(t.metrics) == 0 {
+			return
+		}
+	}
+	p.batcher.Add(ctx, t)
+}
+
+func (p *Producer) logRecord(data record.Data, now time.Time, attributes []*commonpb.KeyValue) *logspb.LogRecord {
+	severity := p.severity.ExecuteWith(data)
+	number, ok := severityNames[strings.ToUpper(severity)]
+	if !ok {
+		if n, err := strconv.Atoi(severity); err == nil && n >= 1 && n <= 24 {
+			number = logspb.SeverityNumber(n)
+		}
+	}
+	return &logspb.LogRecord{
+		TimeUnixNano:         uint64(now.UnixNano()),
+		ObservedTimeUnixNano: uint64(now.UnixNano()),
+		SeverityNumber:       number,
+		SeverityText:         severity,
+		Body:                 anyValue(p.body.ExecuteWith(data)),
+		Attributes:           attributes,
+	}
+}
+
+// gauges returns a gauge for each numeric top-level field of the record; the
+// attributes of the data points are the other fields
+func (p *Producer) gauges(data record.Data, now time.Time, attributes []*commonpb.KeyValue) []*metricspb.Metric {
+	var metrics []*metricspb.Metric
+	var dimensions []*commonpb.KeyValue
+	for _, kv := range keyValues(data.Value) {
+		switch value := kv.Value.Value.(type) {
+		case *commonpb.AnyValue_IntValue:
+			metrics = append(metrics, gauge(p.configuration.Metrics.Prefix+kv.Key, now, float64(value.IntValue)))
+		case *commonpb.AnyValue_DoubleValue:
+			metrics = append(metrics, gauge(p.configuration.Metrics.Prefix+kv.Key, now, value.DoubleValue))
+		case *commonpb.AnyValue_StringValue, *commonpb.AnyValue_BoolValue:
+			if !p.configuration.RecordAttributes {
+				dimensions = append(dimensions, kv)
+			}
+		}
+	}
+	dimensions = append(dimensions, attributes...)
+	for _, m := range metrics {
+		m.GetGauge().DataPoints[0].Attributes = dimensions
+	}
+	return metrics
+}
+
+func gauge(name string, now time.Time, value float64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{{
+				TimeUnixNano: uint64(now.UnixNano()),
+				Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+			}},
+		}},
+	}
+}
+
+// export sends a batch in one request, returning the whole batch when the collector
+// asks to send it again
+func (p *Producer) export(ctx context.Context, items []telemetry) ([]telemetry, error) {
+	var request proto.Message
+	switch p.configuration.Signal {
+	case Logs:
+		records := make([]*logspb.LogRecord, len(items))
+		for i, t := range items {
+			records[i] = t.log
+		}
+		request = &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+			Resource:  &resourcepb.Resource{Attributes: p.resource},
+			ScopeLogs: []*logspb.ScopeLogs{{Scope: p.scope, LogRecords: records}},
+		}}}
+	case Traces:
+		request = &coltracepb.ExportTraceServiceRequest{ResourceSpans: p.resourceSpans(items)}
+	case Metrics:
+		var metrics []*metricspb.Metric
+		for _, t := range items {
+			metrics = append(metrics, t.metrics...)
+		}
+		request = &colmetricspb.ExportMetricsServiceRequest{ResourceMetrics: []*metricspb.ResourceMetrics{{
+			Resource:     &resourcepb.Resource{Attributes: p.resource},
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Scope: p.scope, Metrics: metrics}},
+		}}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	err := p.exporter.export(ctx, p.configuration.Signal, request)
+	var retryable retryableError
+	if errors.As(err, &retryable) {
+		log.Warn().Err(err).Msg("Export failed, retrying")
+		return items, nil
+	}
+	return nil, err
+}
+
+// resourceSpans groups the spans by service, the service.name of their resource
+func (p *Producer) resourceSpans(items []telemetry) []*tracepb.ResourceSpans {
+	var services []string
+	spans := make(map[string][]*tracepb.Span)
+	for _, t := range items {
+		for _, s := range t.spans {
+			if _, ok := spans[s.service]; !ok {
+				services = append(services, s.service)
+			}
+			spans[s.service] = append(spans[s.service], s.span)
+		}
+	}
+
+	resourceSpans := make([]*tracepb.ResourceSpans, len(services))
+	for i, service := range services {
+		attributes := make([]*commonpb.KeyValue, 0, len(p.resource))
+		for _, kv := range p.resource {
+			if kv.Key != "service.name" {
+				attributes = append(attributes, kv)
+			}
+		}
+		attributes = append(attributes, stringAttribute("service.name", service))
+		resourceSpans[i] = &tracepb.ResourceSpans{
+			Resource:   &resourcepb.Resource{Attributes: attributes},
+			ScopeSpans: []*tracepb.ScopeSpans{{Scope: p.scope, Spans: spans[service]}},
+		}
+	}
+	return resourceSpans
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if closeErr := p.exporter.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
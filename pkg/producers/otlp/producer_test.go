@@ -0,0 +1,284 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otlp
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+type collector struct {
+	collogspb.UnimplementedLogsServiceServer
+	coltracepb.UnimplementedTraceServiceServer
+	colmetricspb.UnimplementedMetricsServiceServer
+
+	lock     sync.Mutex
+	tokens   []string
+	logs     []*collogspb.ExportLogsServiceRequest
+	traces   []*coltracepb.ExportTraceServiceRequest
+	metrics  []*colmetricspb.ExportMetricsServiceRequest
+	requests int
+}
+
+func (c *collector) record(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.tokens = append(c.tokens, md.Get("authorization")...)
+}
+
+func (c *collector) Export(ctx context.Context, r *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.record(ctx)
+	c.logs = append(c.logs, r)
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+type traceCollector struct{ *collector }
+
+func (c traceCollector) Export(ctx context.Context, r *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.record(ctx)
+	c.traces = append(c.traces, r)
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+type metricsCollector struct{ *collector }
+
+func (c metricsCollector) Export(ctx context.Context, r *colmetricspb.ExportMetricsServiceRequest) (*colmetricspb.ExportMetricsServiceResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.record(ctx)
+	c.metrics = append(c.metrics, r)
+	return &colmetricspb.ExportMetricsServiceResponse{}, nil
+}
+
+func startCollector(t *testing.T) (*collector, string) {
+	c := &collector{}
+	server := grpc.NewServer()
+	collogspb.RegisterLogsServiceServer(server, c)
+	coltracepb.RegisterTraceServiceServer(server, traceCollector{c})
+	colmetricspb.RegisterMetricsServiceServer(server, metricsCollector{c})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	return c, listener.Addr().String()
+}
+
+func TestProducerLogs(t *testing.T) {
+	ctx := context.Background()
+	c, addr := startCollector(t)
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{
+		Endpoint:         addr,
+		Headers:          map[string]string{"authorization": "Bearer secret"},
+		BatchSize:        2,
+		Resource:         map[string]string{"service.name": "shop"},
+		RecordAttributes: true,
+		Attributes:       map[string]string{"jr.key": "{{.K}}"},
+		Logs:             LogsConfig{Body: "{{.Value.message}}", Severity: "{{.Value.level}}"},
+	})
+	if p.configuration.FlushInterval != DefaultFlushInterval {
+		t.Errorf("got flush_interval %q, want %q", p.configuration.FlushInterval, DefaultFlushInterval)
+	}
+	p.Produce(ctx, []byte("k1"), []byte(`{"message":"order created","level":"warn","amount":12.5}`), nil)
+	p.Produce(ctx, []byte("k2"), []byte(`{"message":"order paid","level":"17","items":3}`), nil)
+	p.Produce(ctx, []byte("k3"), []byte(`{"message":"order shipped","level":"info"}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.logs) != 2 {
+		t.Fatalf("got %d requests, want 2", len(c.logs))
+	}
+	if len(c.tokens) != 2 || c.tokens[0] != "Bearer secret" {
+		t.Errorf("got authorization %v", c.tokens)
+	}
+	resource := c.logs[0].ResourceLogs[0].Resource.Attributes
+	if len(resource) != 1 || resource[0].Key != "service.name" || resource[0].Value.GetStringValue() != "shop" {
+		t.Errorf("got resource %v", resource)
+	}
+
+	records := c.logs[0].ResourceLogs[0].ScopeLogs[0].LogRecords
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	first := records[0]
+	if first.Body.GetStringValue() != "order created" || first.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_WARN || first.SeverityText != "warn" {
+		t.Errorf("got record %v", first)
+	}
+	if len(first.Attributes) != 4 {
+		t.Fatalf("got attributes %v", first.Attributes)
+	}
+	// record fields sorted by name, then the templated attributes
+	amount, jrKey, level := first.Attributes[0], first.Attributes[3], first.Attributes[1]
+	if amount.Key != "amount" || amount.Value.GetDoubleValue() != 12.5 {
+		t.Errorf("got attribute %v", amount)
+	}
+	if level.Key != "level" || level.Value.GetStringValue() != "warn" {
+		t.Errorf("got attribute %v", level)
+	}
+	if jrKey.Key != "jr.key" || jrKey.Value.GetStringValue() != "k1" {
+		t.Errorf("got attribute %v", jrKey)
+	}
+	if records[1].SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_ERROR {
+		t.Errorf("got severity %s, want ERROR", records[1].SeverityNumber)
+	}
+}
+
+func TestProducerTraces(t *testing.T) {
+	ctx := context.Background()
+	c, addr := startCollector(t)
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{
+		Endpoint: addr,
+		Signal:   Traces,
+		Traces:   TracesConfig{Services: []string{"frontend", "payment"}, RootName: "POST /{{.Value.path}}", MaxChildren: 3},
+	})
+	p.traces.rand = rand.New(rand.NewSource(1))
+	for i := 0; i < 10; i++ {
+		p.Produce(ctx, nil, []byte(`{"path":"orders"}`), nil)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.traces) != 1 {
+		t.Fatalf("got %d requests, want 1", len(c.traces))
+	}
+	var services []string
+	roots := 0
+	for _, rs := range c.traces[0].ResourceSpans {
+		services = append(services, rs.Resource.Attributes[0].Value.GetStringValue())
+		for _, span := range rs.ScopeSpans[0].Spans {
+			if span.ParentSpanId == nil {
+				roots++
+				if span.Name != "POST /orders" {
+					t.Errorf("got root span %q", span.Name)
+				}
+			}
+		}
+	}
+	sort.Strings(services)
+	if len(services) != 2 || services[0] != "frontend" || services[1] != "payment" {
+		t.Errorf("got services %v", services)
+	}
+	if roots != 10 {
+		t.Errorf("got %d traces, want 10", roots)
+	}
+}
+
+func TestProducerMetrics(t *testing.T) {
+	ctx := context.Background()
+	c, addr := startCollector(t)
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{Endpoint: addr, Signal: Metrics, Metrics: MetricsConfig{Prefix: "shop."}})
+	p.Produce(ctx, nil, []byte(`{"store":"s1","temperature":21.5,"visitors":7}`), nil)
+	p.Produce(ctx, nil, []byte(`{"store":"s2"}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := c.metrics[0].ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 2 {
+		t.Fatalf("got %d metrics, want 2", len(metrics))
+	}
+	for i, want := range []struct {
+		name  string
+		value float64
+	}{{"shop.temperature", 21.5}, {"shop.visitors", 7}} {
+		point := metrics[i].GetGauge().DataPoints[0]
+		if metrics[i].Name != want.name || point.GetAsDouble() != want.value {
+			t.Errorf("got %s %v, want %s %v", metrics[i].Name, point.GetAsDouble(), want.name, want.value)
+		}
+		if len(point.Attributes) != 1 || point.Attributes[0].Value.GetStringValue() != "s1" {
+			t.Errorf("got attributes %v", point.Attributes)
+		}
+	}
+}
+
+func TestProducerHTTP(t *testing.T) {
+	ctx := context.Background()
+
+	var lock sync.Mutex
+	var calls int
+	var received []*collogspb.ExportLogsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		calls++
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/x-protobuf" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// the first export is throttled
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		request := &collogspb.ExportLogsServiceRequest{}
+		if err := proto.Unmarshal(body, request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received = append(received, request)
+	}))
+	defer server.Close()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{Endpoint: server.URL, Protocol: HTTPProtobuf, FlushInterval: "10ms"})
+	p.batcher.Backoff = time.Millisecond
+	p.Produce(ctx, []byte("k"), []byte(`{"n":1}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 2 || len(received) != 1 {
+		t.Fatalf("got %d calls and %d requests, want 2 and 1", calls, len(received))
+	}
+	record := received[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if record.Body.GetStringValue() != `{"n":1}` || record.SeverityNumber != logspb.SeverityNumber_SEVERITY_NUMBER_INFO {
+		t.Errorf("got record %v", record)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otlp
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+var operations = []string{"get", "list", "create", "update", "validate", "process"}
+
+// serviceSpan is a span with the name of the service, the resource, that emitted it
+type serviceSpan struct {
+	service string
+	span    *tracepb.Span
+}
+
+// traceGenerator generates synthetic traces: a server span for the root call,
+// with nested calls to other services, made of a client span in the caller
+// and a server span in the callee, and internal spans in the same service
+type traceGenerator struct {
+	rand        *rand.Rand
+	services    []string
+	maxDepth    int
+	maxChildren int
+	minDuration time.Duration
+	maxDuration time.Duration
+	errorRate   float64
+}
+
+// generate returns the spans of a trace ending at end, with the attributes on the root span
+func (g *traceGenerator) generate(name string, end time.Time, attributes []*commonpb.KeyValue) []serviceSpan {
+	traceID := g.id(16)
+	duration := g.minDuration
+	if g.maxDuration > g.minDuration {
+		duration += time.Duration(g.rand.Int63n(int64(g.maxDuration - g.minDuration)))
+	}
+
+	service := g.services[g.rand.Intn(len(g.services))]
+	root := g.span(traceID, nil, name, tracepb.Span_SPAN_KIND_SERVER, end.Add(-duration), end)
+	root.Attributes = append(root.Attributes, attributes...)
+	spans := []serviceSpan{{service, root}}
+	g.setStatus(root, g.children(&spans, service, root, 1))
+	return spans
+}
+
+// children adds the calls made by the parent span, one after the other within its interval,
+// returning true if any of the calls failed
+func (g *traceGenerator) children(spans *[]serviceSpan, service string, parent *tracepb.Span, depth int) bool {
+	if depth > g.maxDepth {
+		return false
+	}
+	n := g.rand.Intn(g.maxChildren + 1)
+	start := parent.StartTimeUnixNano
+	end := parent.EndTimeUnixNano
+	// the parent works before, between and after its calls
+	gap := (end - start) / uint64(4*n+2)
+
+	failed := false
+	cursor := start + gap
+	for i := 0; i < n; i++ {
+		slot := (int64(end) - int64(gap) - int64(cursor)) / int64(n-i)
+		if slot < 2 {
+			break
+		}
+		childStart := cursor
+		childEnd := childStart + uint64(slot/2+g.rand.Int63n(slot/2))
+		cursor = childEnd + gap
+
+		operation := operations[g.rand.Intn(len(operations))]
+		callee := g.services[g.rand.Intn(len(g.services))]
+		if callee == service {
+			span := g.span(parent.TraceId, parent.SpanId, operation, tracepb.Span_SPAN_KIND_INTERNAL, time.Unix(0, int64(childStart)), time.Unix(0, int64(childEnd)))
+			*spans = append(*spans, serviceSpan{service, span})
+			childFailed := g.children(spans, service, span, depth+1)
+			failed = g.setStatus(span, childFailed) || failed
+			continue
+		}
+
+		// the server span starts and ends within the client span, after the network latency
+		latency := (childEnd - childStart) / 10
+		name := fmt.Sprintf("%s %s", callee, operation)
+		client := g.span(parent.TraceId, parent.SpanId, name, tracepb.Span_SPAN_KIND_CLIENT, time.Unix(0, int64(childStart)), time.Unix(0, int64(childEnd)))
+		client.Attributes = append(client.Attributes, stringAttribute("peer.service", callee))
+		server := g.span(parent.TraceId, client.SpanId, name, tracepb.Span_SPAN_KIND_SERVER, time.Unix(0, int64(childStart+latency)), time.Unix(0, int64(childEnd-latency)))
+		*spans = append(*spans, serviceSpan{service, client}, serviceSpan{callee, server})
+
+		serverFailed := g.setStatus(server, g.children(spans, callee, server, depth+1))
+		failed = g.setStatus(client, serverFailed) || failed
+	}
+	return failed
+}
+
+func (g *traceGenerator) span(traceID []byte, parentID []byte, name string, kind tracepb.Span_SpanKind, start time.Time, end time.Time) *tracepb.Span {
+	return &tracepb.Span{
+		TraceId:           traceID,
+		SpanId:            g.id(8),
+		ParentSpanId:      parentID,
+		Name:              name,
+		Kind:              kind,
+		StartTimeUnixNano: uint64(start.UnixNano()),
+		EndTimeUnixNano:   uint64(end.UnixNano()),
+	}
+}
+
+// setStatus fails the span randomly, or when a call failed, returning true if it failed;
+// server spans get the HTTP status code too
+func (g *traceGenerator) setStatus(span *tracepb.Span, failedCall bool) bool {
+	failed := failedCall || g.rand.Float64() < g.errorRate
+	if failed {
+		span.Status = &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "error"}
+	}
+	if span.Kind == tracepb.Span_SPAN_KIND_SERVER {
+		code := int64(200)
+		if failed {
+			code = 500
+		}
+		span.Attributes = append(span.Attributes, intAttribute("http.response.status_code", code))
+	}
+	return failed
+}
+
+func (g *traceGenerator) id(n int) []byte {
+	b := make([]byte, n)
+	g.rand.Read(b)
+	return b
+}
@@ -0,0 +1,105 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package otlp
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+func TestTraceGenerator(t *testing.T) {
+	g := &traceGenerator{
+		rand:        rand.New(rand.NewSource(1)),
+		services:    []string{"frontend", "checkout", "payment"},
+		maxDepth:    3,
+		maxChildren: 4,
+		minDuration: 10 * time.Millisecond,
+		maxDuration: time.Second,
+		errorRate:   0.2,
+	}
+	end := time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC)
+
+	failed := 0
+	for i := 0; i < 200; i++ {
+		spans := g.generate("GET /", end, nil)
+
+		byID := make(map[string]serviceSpan)
+		for _, s := range spans {
+			byID[string(s.span.SpanId)] = s
+		}
+		root := spans[0].span
+		if root.ParentSpanId != nil || root.Kind != tracepb.Span_SPAN_KIND_SERVER || root.Name != "GET /" {
+			t.Fatalf("invalid root span %v", root)
+		}
+		if got := time.Duration(root.EndTimeUnixNano - root.StartTimeUnixNano); got < g.minDuration || got > g.maxDuration {
+			t.Errorf("got root duration %s", got)
+		}
+		if root.EndTimeUnixNano != uint64(end.UnixNano()) {
+			t.Errorf("root span doesn't end at %s", end)
+		}
+
+		if root.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR {
+			failed++
+		}
+		for _, s := range spans[1:] {
+			span := s.span
+			if !bytes.Equal(span.TraceId, root.TraceId) {
+				t.Fatalf("span %s not in the trace", span.Name)
+			}
+			parent, ok := byID[string(span.ParentSpanId)]
+			if !ok {
+				t.Fatalf("parent of span %s not found", span.Name)
+			}
+			if span.StartTimeUnixNano < parent.span.StartTimeUnixNano || span.EndTimeUnixNano > parent.span.EndTimeUnixNano ||
+				span.StartTimeUnixNano >= span.EndTimeUnixNano {
+				t.Errorf("span %s is not within its parent", span.Name)
+			}
+
+			switch span.Kind {
+			case tracepb.Span_SPAN_KIND_SERVER:
+				// a call from another service, failed when the server failed
+				if parent.span.Kind != tracepb.Span_SPAN_KIND_CLIENT || parent.service == s.service {
+					t.Errorf("server span %s is not called by a client span of another service", span.Name)
+				}
+				if span.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR && parent.span.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+					t.Errorf("client span %s succeeded with a failed server span", span.Name)
+				}
+			case tracepb.Span_SPAN_KIND_CLIENT, tracepb.Span_SPAN_KIND_INTERNAL:
+				if parent.service != s.service {
+					t.Errorf("span %s is not in the service of its parent", span.Name)
+				}
+				// a failed call fails the caller
+				if span.Status.GetCode() == tracepb.Status_STATUS_CODE_ERROR && parent.span.Status.GetCode() != tracepb.Status_STATUS_CODE_ERROR {
+					t.Errorf("failed span %s has a successful parent", span.Name)
+				}
+			default:
+				t.Errorf("unexpected kind %s", span.Kind)
+			}
+		}
+	}
+	if failed == 0 || failed == 200 {
+		t.Errorf("got %d failed traces of 200", failed)
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pubsub
+
+type Config struct {
+	// ProjectID defaults to the project of the Application Default Credentials
+	ProjectID string `json:"project_id"`
+	Topic     string `json:"topic"`
+	// CreateTopic creates the topic if it doesn't exist, e.g. in the emulator
+	CreateTopic bool `json:"create_topic"`
+	// MessageOrdering publishes the records with the JR key, when set, as ordering key
+	MessageOrdering bool `json:"message_ordering"`
+	// Attributes values are evaluated for each record, e.g. "{{.Value.country}}"
+	Attributes map[string]string `json:"attributes"`
+	// BatchSize, BatchBytes and FlushInterval publish a batch when it has this many
+	// messages, this many bytes or is older than this duration, e.g. "10ms"
+	BatchSize     int    `json:"batch_size"`
+	BatchBytes    int    `json:"batch_bytes"`
+	FlushInterval string `json:"flush_interval"`
+	// Endpoint overrides the Pub/Sub endpoint; PUBSUB_EMULATOR_HOST takes precedence
+	Endpoint string `json:"endpoint"`
+}
@@ -0,0 +1,161 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pubsubclient "cloud.google.com/go/pubsub"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/api/option"
+)
+
+// Producer publishes records to a Pub/Sub topic. When PUBSUB_EMULATOR_HOST
+// is set, the client connects to the emulator without credentials.
+type Producer struct {
+	configuration Config
+	client        *pubsubclient.Client
+	topic         *pubsubclient.Topic
+	attributes    map[string]*record.Template
+	pending       sync.WaitGroup
+	failed        atomic.Int64
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	p.initialize(ctx, config)
+}
+
+func (p *Producer) initialize(ctx context.Context, config Config) {
+	p.configuration = config
+	if config.Topic == "" {
+		log.Fatal().Msg("topic is mandatory")
+	}
+
+	project := config.ProjectID
+	if project == "" {
+		project = pubsubclient.DetectProjectID
+	}
+	var opts []option.ClientOption
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint))
+	}
+
+	// Use Google Application Default Credentials to authorize and authenticate the client.
+	client, err := pubsubclient.NewClient(ctx, project, opts...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create client")
+	}
+	p.client = client
+
+	p.topic = client.Topic(config.Topic)
+	if config.CreateTopic {
+		exists, err := p.topic.Exists(ctx)
+		if err != nil {
+			log.Fatal().Err(err).Str("topic", config.Topic).Msg("Failed to check topic")
+		}
+		if !exists {
+			if p.topic, err = client.CreateTopic(ctx, config.Topic); err != nil {
+				log.Fatal().Err(err).Str("topic", config.Topic).Msg("Failed to create topic")
+			}
+		}
+	}
+
+	p.topic.EnableMessageOrdering = config.MessageOrdering
+	if config.BatchSize > 0 {
+		p.topic.PublishSettings.CountThreshold = config.BatchSize
+	}
+	if config.BatchBytes > 0 {
+		p.topic.PublishSettings.ByteThreshold = config.BatchBytes
+	}
+	if config.FlushInterval != "" {
+		p.topic.PublishSettings.DelayThreshold, err = time.ParseDuration(config.FlushInterval)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+		}
+	}
+
+	p.attributes = make(map[string]*record.Template, len(config.Attributes))
+	for name, value := range config.Attributes {
+		t, err := record.NewTemplate(name, value)
+		if err != nil {
+			log.Fatal().Err(err).Msgf("Failed to parse attribute %s", name)
+		}
+		p.attributes[name] = t
+	}
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	msg := &pubsubclient.Message{Data: v}
+	// the default "null" key would serialize all the messages on one ordering key
+	if p.configuration.MessageOrdering && strings.ToLower(data.K) != "null" {
+		msg.OrderingKey = data.K
+	}
+	if len(p.attributes) > 0 {
+		msg.Attributes = make(map[string]string, len(p.attributes))
+		for name, value := range p.attributes {
+			msg.Attributes[name] = value.ExecuteWith(data)
+		}
+	}
+
+	result := p.topic.Publish(ctx, msg)
+	p.pending.Add(1)
+	go func() {
+		defer p.pending.Done()
+		if _, err := result.Get(ctx); err != nil {
+			p.failed.Add(1)
+			log.Error().Err(err).Str("topic", p.configuration.Topic).Msg("Failed to publish message")
+			if msg.OrderingKey != "" {
+				// publishing for the key is paused after an error
+				p.topic.ResumePublish(msg.OrderingKey)
+			}
+		}
+	}()
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	p.topic.Stop()
+	p.pending.Wait()
+	if err := p.client.Close(); err != nil {
+		return err
+	}
+	if failed := p.failed.Load(); failed > 0 {
+		return fmt.Errorf("%d messages not published", failed)
+	}
+	return nil
+}
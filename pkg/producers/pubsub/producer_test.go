@@ -0,0 +1,96 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pubsub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"cloud.google.com/go/pubsub/pstest"
+)
+
+func newEmulator(t *testing.T) *pstest.Server {
+	srv := pstest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+	t.Setenv("PUBSUB_EMULATOR_HOST", srv.Addr)
+	return srv
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+	srv := newEmulator(t)
+
+	p := &Producer{}
+	p.initialize(ctx, Config{
+		ProjectID:       "jr",
+		Topic:           "orders",
+		CreateTopic:     true,
+		MessageOrdering: true,
+		Attributes:      map[string]string{"source": "jr", "country": "{{.Value.country}}"},
+		BatchSize:       2,
+		FlushInterval:   "5ms",
+	})
+	for i := 0; i < 5; i++ {
+		p.Produce(ctx, []byte(fmt.Sprintf("k%d", i%2)), []byte(fmt.Sprintf(`{"n":%d,"country":"c%d"}`, i, i)), nil)
+	}
+	// the default key is not an ordering key
+	p.Produce(ctx, []byte("null"), []byte(`{"n":5,"country":"c5"}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := srv.Messages()
+	if len(messages) != 6 {
+		t.Fatalf("got %d messages, want 6", len(messages))
+	}
+	for _, m := range messages {
+		var n int
+		if _, err := fmt.Sscanf(string(m.Data), `{"n":%d`, &n); err != nil {
+			t.Fatalf("unexpected data %s", m.Data)
+		}
+		if m.Topic != "projects/jr/topics/orders" {
+			t.Errorf("got topic %q", m.Topic)
+		}
+		want := fmt.Sprintf("k%d", n%2)
+		if n == 5 {
+			want = ""
+		}
+		if m.OrderingKey != want {
+			t.Errorf("got ordering key %q for %s, want %q", m.OrderingKey, m.Data, want)
+		}
+		if want = fmt.Sprintf("c%d", n); m.Attributes["country"] != want || m.Attributes["source"] != "jr" {
+			t.Errorf("got attributes %v for %s", m.Attributes, m.Data)
+		}
+	}
+}
+
+func TestProducerMissingTopic(t *testing.T) {
+	ctx := context.Background()
+	newEmulator(t)
+
+	p := &Producer{}
+	p.initialize(ctx, Config{ProjectID: "jr", Topic: "missing"})
+	p.Produce(ctx, []byte("k"), []byte(`{}`), nil)
+	if err := p.Close(ctx); err == nil {
+		t.Error("expected an error publishing to a missing topic")
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pulsar
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	SchemaAvro = "avro"
+	SchemaJSON = "json"
+)
+
+type Batching struct {
+	Disabled bool `json:"disabled"`
+	// MaxPublishDelay is how long messages are batched, e.g. "10ms"
+	MaxPublishDelay string `json:"max_publish_delay"`
+	MaxMessages     uint   `json:"max_messages"`
+	MaxSize         uint   `json:"max_size"`
+}
+
+type Config struct {
+	// URL of the service, e.g. pulsar://localhost:6650 or pulsar+ssl://localhost:6651
+	URL   string `json:"url"`
+	Topic string `json:"topic"`
+	// EventTime is evaluated for each record and gives the event time of the message:
+	// an RFC 3339 timestamp, a date or epoch milliseconds, e.g. "{{.Value.ts}}"
+	EventTime string `json:"event_time"`
+	// Properties values are evaluated for each record
+	Properties map[string]string `json:"properties"`
+	Batching   Batching          `json:"batching"`
+	// Compression is one of none (the default), lz4, zlib or zstd
+	Compression string `json:"compression"`
+	// SchemaType is avro or json, with the Avro SchemaFile describing the records
+	SchemaType string `json:"schema_type"`
+	SchemaFile string `json:"schema_file"`
+
+	Token string           `json:"token"`
+	TLS   tlsconfig.Config `json:"tls"`
+}
@@ -0,0 +1,245 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pulsar
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	pulsarclient "github.com/apache/pulsar-client-go/pulsar"
+	"github.com/hamba/avro/v2"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/jrnd-io/jr/pkg/schema"
+	"github.com/rs/zerolog/log"
+)
+
+var compressions = map[string]pulsarclient.CompressionType{
+	"":     pulsarclient.NoCompression,
+	"none": pulsarclient.NoCompression,
+	"lz4":  pulsarclient.LZ4,
+	"zlib": pulsarclient.ZLib,
+	"zstd": pulsarclient.ZSTD,
+}
+
+// Producer sends records to a Pulsar topic, with the JR key as partition key
+type Producer struct {
+	configuration Config
+	eventTime     *record.Template
+	properties    map[string]*record.Template
+	avroSchema    avro.Schema
+
+	client   pulsarclient.Client
+	producer pulsarclient.Producer
+}
+
+func (p *Producer) Initialize(configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	if config.Topic == "" {
+		log.Fatal().Msg("Topic is mandatory")
+	}
+
+	clientOptions := pulsarclient.ClientOptions{URL: config.URL}
+	if config.Token != "" {
+		clientOptions.Authentication = pulsarclient.NewAuthenticationToken(config.Token)
+	} else if config.TLS.CertFile != "" {
+		clientOptions.Authentication = pulsarclient.NewAuthenticationTLS(config.TLS.CertFile, config.TLS.KeyFile)
+	}
+	if config.TLS.IsSet() {
+		clientOptions.TLSConfig, err = config.TLS.Load()
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+		clientOptions.TLSAllowInsecureConnection = config.TLS.InsecureSkipVerify
+	}
+	p.client, err = pulsarclient.NewClient(clientOptions)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create Pulsar client")
+	}
+
+	producerOptions, err := producerOptions(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid Pulsar configuration")
+	}
+	producer, err := p.client.CreateProducer(producerOptions)
+	if err != nil {
+		log.Fatal().Err(err).Str("topic", config.Topic).Msg("Failed to create Pulsar producer")
+	}
+
+	p.initialize(config, producer)
+}
+
+// initialize sets up the producer on a created Pulsar producer
+func (p *Producer) initialize(config Config, producer pulsarclient.Producer) {
+	var err error
+	p.configuration = config
+	p.producer = producer
+
+	p.eventTime, err = record.NewTemplate("event_time", config.EventTime)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse event_time template")
+	}
+	p.properties = make(map[string]*record.Template, len(config.Properties))
+	for name, value := range config.Properties {
+		p.properties[name], err = record.NewTemplate(name, value)
+		if err != nil {
+			log.Fatal().Err(err).Str("property", name).Msg("Failed to parse property template")
+		}
+	}
+
+	if config.SchemaType == SchemaAvro {
+		p.avroSchema, err = schema.LoadAvro(config.SchemaFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to load schema")
+		}
+	}
+}
+
+func producerOptions(config Config) (pulsarclient.ProducerOptions, error) {
+	options := pulsarclient.ProducerOptions{
+		Topic:               config.Topic,
+		DisableBatching:     config.Batching.Disabled,
+		BatchingMaxMessages: config.Batching.MaxMessages,
+		BatchingMaxSize:     config.Batching.MaxSize,
+	}
+
+	compression, ok := compressions[config.Compression]
+	if !ok {
+		return options, fmt.Errorf("unsupported compression %q", config.Compression)
+	}
+	options.CompressionType = compression
+
+	if config.Batching.MaxPublishDelay != "" {
+		delay, err := time.ParseDuration(config.Batching.MaxPublishDelay)
+		if err != nil {
+			return options, fmt.Errorf("max_publish_delay: %w", err)
+		}
+		options.BatchingMaxPublishDelay = delay
+	}
+
+	if config.SchemaType == "" {
+		return options, nil
+	}
+	if config.SchemaFile == "" {
+		return options, fmt.Errorf("schema_type %s needs a schema_file", config.SchemaType)
+	}
+	definition, err := os.ReadFile(config.SchemaFile)
+	if err != nil {
+		return options, err
+	}
+	// both the Avro and the JSON schemas of Pulsar are described by an Avro schema
+	switch config.SchemaType {
+	case SchemaAvro:
+		options.Schema, err = pulsarclient.NewAvroSchemaWithValidation(string(definition), nil)
+	case SchemaJSON:
+		options.Schema, err = pulsarclient.NewJSONSchemaWithValidation(string(definition), nil)
+	default:
+		err = fmt.Errorf("unsupported schema_type %q", config.SchemaType)
+	}
+	return options, err
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	msg, err := p.message(k, v)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to create Pulsar message")
+		return
+	}
+
+	p.producer.SendAsync(ctx, msg, func(_ pulsarclient.MessageID, _ *pulsarclient.ProducerMessage, err error) {
+		if err != nil {
+			log.Error().Err(err).Str("topic", p.configuration.Topic).Msg("Failed to send Pulsar message")
+		}
+	})
+}
+
+func (p *Producer) message(k []byte, v []byte) (*pulsarclient.ProducerMessage, error) {
+	data := record.NewData(k, v)
+	msg := &pulsarclient.ProducerMessage{Payload: v}
+	// the default "null" key would route all the messages to one partition
+	if data.K != "" && strings.ToLower(data.K) != "null" {
+		msg.Key = data.K
+	}
+
+	if p.configuration.EventTime != "" {
+		eventTime, err := parseEventTime(p.eventTime.ExecuteWith(data))
+		if err != nil {
+			return nil, fmt.Errorf("event_time: %w", err)
+		}
+		msg.EventTime = eventTime
+	}
+	if len(p.properties) > 0 {
+		msg.Properties = make(map[string]string, len(p.properties))
+		for name, value := range p.properties {
+			msg.Properties[name] = value.ExecuteWith(data)
+		}
+	}
+
+	// the payload is encoded here, so that the JSON of the record maps to the Avro schema
+	if p.avroSchema != nil {
+		native, err := schema.AvroNative(p.avroSchema, v)
+		if err != nil {
+			return nil, err
+		}
+		msg.Payload, err = avro.Marshal(p.avroSchema, native)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+// parseEventTime accepts epoch milliseconds or the timestamps of the schemas
+func parseEventTime(str string) (time.Time, error) {
+	if ms, err := strconv.ParseInt(str, 10, 64); err == nil {
+		return time.UnixMilli(ms), nil
+	}
+	return schema.ParseTimestamp(str)
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	err := p.producer.Flush()
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to flush Pulsar producer")
+	}
+	p.producer.Close()
+	if p.client != nil {
+		p.client.Close()
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package pulsar
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	pulsarclient "github.com/apache/pulsar-client-go/pulsar"
+	"github.com/hamba/avro/v2"
+)
+
+// fakeProducer records the messages sent
+type fakeProducer struct {
+	pulsarclient.Producer
+	messages []*pulsarclient.ProducerMessage
+	flushed  bool
+}
+
+func (f *fakeProducer) SendAsync(_ context.Context, msg *pulsarclient.ProducerMessage, callback func(pulsarclient.MessageID, *pulsarclient.ProducerMessage, error)) {
+	f.messages = append(f.messages, msg)
+	callback(nil, msg, nil)
+}
+
+func (f *fakeProducer) Flush() error {
+	f.flushed = true
+	return nil
+}
+
+func (f *fakeProducer) Close() {}
+
+func TestProducer(t *testing.T) {
+	value := `{"sensor": "s1", "value": 21.5, "ts": 1700000000000}`
+
+	testCases := []struct {
+		name    string
+		config  Config
+		key     string
+		want    *pulsarclient.ProducerMessage
+		payload func(t *testing.T, payload []byte)
+	}{
+		{
+			name:   "json",
+			config: Config{EventTime: "{{.Value.ts}}", Properties: map[string]string{"sensor": "{{.Value.sensor}}"}},
+			want: &pulsarclient.ProducerMessage{
+				Key:        "k1",
+				Payload:    []byte(value),
+				EventTime:  time.UnixMilli(1700000000000),
+				Properties: map[string]string{"sensor": "s1"},
+			},
+		},
+		{
+			name:   "event time from timestamp",
+			config: Config{EventTime: "2024-05-01T10:00:00Z"},
+			want: &pulsarclient.ProducerMessage{
+				Key:       "k1",
+				Payload:   []byte(value),
+				EventTime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "default key",
+			config: Config{},
+			key:    "null",
+			want:   &pulsarclient.ProducerMessage{Payload: []byte(value)},
+		},
+		{
+			name:   "avro",
+			config: Config{SchemaType: SchemaAvro, SchemaFile: "testdata/reading.avsc"},
+			want:   &pulsarclient.ProducerMessage{Key: "k1"},
+			payload: func(t *testing.T, payload []byte) {
+				s, err := avro.ParseFiles("testdata/reading.avsc")
+				if err != nil {
+					t.Fatal(err)
+				}
+				var got map[string]any
+				if err = avro.Unmarshal(s, payload, &got); err != nil {
+					t.Fatal(err)
+				}
+				want := map[string]any{"sensor": "s1", "value": 21.5, "ts": int64(1700000000000)}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got %v, want %v", got, want)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.Topic = "readings"
+			if _, err := producerOptions(tc.config); err != nil {
+				t.Fatal(err)
+			}
+
+			fake := &fakeProducer{}
+			p := &Producer{}
+			p.initialize(tc.config, fake)
+			key := "k1"
+			if tc.key != "" {
+				key = tc.key
+			}
+			p.Produce(context.Background(), []byte(key), []byte(value), nil)
+			if err := p.Close(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(fake.messages) != 1 || !fake.flushed {
+				t.Fatalf("got %d messages, flushed %v", len(fake.messages), fake.flushed)
+			}
+			got := fake.messages[0]
+			if tc.payload != nil {
+				tc.payload(t, got.Payload)
+				got.Payload = nil
+			}
+			if !got.EventTime.Equal(tc.want.EventTime) {
+				t.Errorf("got event time %v, want %v", got.EventTime, tc.want.EventTime)
+			}
+			got.EventTime = tc.want.EventTime
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProducerOptions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{name: "defaults", config: Config{Topic: "t"}},
+		{name: "batching", config: Config{Topic: "t", Compression: "zstd", Batching: Batching{MaxPublishDelay: "5ms", MaxMessages: 10}}},
+		{name: "json schema", config: Config{Topic: "t", SchemaType: SchemaJSON, SchemaFile: "testdata/reading.avsc"}},
+		{name: "unknown compression", config: Config{Topic: "t", Compression: "brotli"}, wantErr: true},
+		{name: "schema without file", config: Config{Topic: "t", SchemaType: SchemaAvro}, wantErr: true},
+		{name: "unknown schema type", config: Config{Topic: "t", SchemaType: "protobuf", SchemaFile: "testdata/reading.avsc"}, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := producerOptions(tc.config)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/rs/zerolog/log"
+)
+
+// channel is the part of amqp.Channel used by the producer
+type channel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error)
+	Close() error
+}
+
+// Producer publishes records to an AMQP 0-9-1 broker like RabbitMQ
+type Producer struct {
+	configuration  Config
+	exchange       *record.Template
+	routingKey     *record.Template
+	correlationID  *record.Template
+	messageID      *record.Template
+	headers        map[string]*record.Template
+	confirmTimeout time.Duration
+
+	conn    *amqp.Connection
+	channel channel
+}
+
+func (p *Producer) Initialize(configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	if config.URL == "" {
+		config.URL = DefaultURL
+	}
+
+	p.conn, err = dial(config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Can't connect to AMQP broker")
+	}
+	ch, err := p.conn.Channel()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open AMQP channel")
+	}
+	if config.Confirms.Enabled {
+		if err = ch.Confirm(false); err != nil {
+			log.Fatal().Err(err).Msg("Failed to enable publisher confirms")
+		}
+	}
+
+	p.initialize(config, ch)
+}
+
+// dial connects to the broker, with TLS when configured
+func dial(config Config) (*amqp.Connection, error) {
+	if !config.TLS.IsSet() {
+		return amqp.Dial(config.URL)
+	}
+	tlsConfig, err := config.TLS.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS configuration: %w", err)
+	}
+	return amqp.DialTLS(config.URL, tlsConfig)
+}
+
+// initialize sets up the producer on an open channel
+func (p *Producer) initialize(config Config, ch channel) {
+	var err error
+	p.configuration = config
+	p.channel = ch
+	if p.configuration.Exchange.Type == "" {
+		p.configuration.Exchange.Type = DefaultExchangeType
+	}
+	if p.configuration.Message.ContentType == "" {
+		p.configuration.Message.ContentType = DefaultContentType
+	}
+	if p.configuration.Confirms.Timeout == "" {
+		p.configuration.Confirms.Timeout = DefaultConfirmTimeout
+	}
+	p.confirmTimeout, err = time.ParseDuration(p.configuration.Confirms.Timeout)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse publisher_confirms timeout")
+	}
+
+	p.exchange = newTemplate("exchange", config.Exchange.Name)
+	p.routingKey = newTemplate("routing_key", config.Message.RoutingKey)
+	p.correlationID = newTemplate("correlation_id", config.Message.CorrelationID)
+	p.messageID = newTemplate("message_id", config.Message.MessageID)
+	p.headers = make(map[string]*record.Template, len(config.Message.Headers))
+	for name, value := range config.Message.Headers {
+		p.headers[name] = newTemplate(name, value)
+	}
+
+	if err = p.declare(); err != nil {
+		log.Fatal().Err(err).Msg("Failed to declare AMQP topology")
+	}
+}
+
+// declare creates the exchange and the queue when requested, binding them together
+func (p *Producer) declare() error {
+	e := p.configuration.Exchange
+	q := p.configuration.Queue
+	if e.Declare {
+		if !p.exchange.Static() || e.Name == "" {
+			return fmt.Errorf("only a constant, non default exchange can be declared, got %q", e.Name)
+		}
+		if err := p.channel.ExchangeDeclare(e.Name, e.Type, e.Durable, false, false, false, nil); err != nil {
+			return err
+		}
+	}
+	if q.Declare {
+		if _, err := p.channel.QueueDeclare(q.Name, q.Durable, false, false, false, nil); err != nil {
+			return err
+		}
+		if e.Declare {
+			if err := p.channel.QueueBind(q.Name, q.BindingKey, e.Name, false, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	exchange := p.exchange.ExecuteWith(data)
+	routingKey := p.routingKey.ExecuteWith(data)
+
+	dc, err := p.channel.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, false, false, p.publishing(data))
+	if err != nil {
+		log.Error().Err(err).Str("exchange", exchange).Str("routing_key", routingKey).Msg("Failed to publish AMQP message")
+		return
+	}
+	// without publisher confirms there's nothing to wait for
+	if dc == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.confirmTimeout)
+	defer cancel()
+	acked, err := dc.WaitContext(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("exchange", exchange).Str("routing_key", routingKey).Msg("AMQP message not confirmed")
+	} else if !acked {
+		log.Error().Str("exchange", exchange).Str("routing_key", routingKey).Msg("AMQP message rejected by the broker")
+	}
+}
+
+func newTemplate(name string, text string) *record.Template {
+	t, err := record.NewTemplate(name, text)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to parse %s template", name)
+	}
+	return t
+}
+
+func (p *Producer) publishing(data record.Data) amqp.Publishing {
+	msg := amqp.Publishing{
+		ContentType:   p.configuration.Message.ContentType,
+		CorrelationId: p.correlationID.ExecuteWith(data),
+		MessageId:     p.messageID.ExecuteWith(data),
+		Type:          p.configuration.Message.Type,
+		Timestamp:     time.Now(),
+		Body:          []byte(data.V),
+	}
+	if p.configuration.Message.Persistent {
+		msg.DeliveryMode = amqp.Persistent
+	}
+	if len(p.headers) > 0 {
+		msg.Headers = make(amqp.Table, len(p.headers))
+		for name, value := range p.headers {
+			msg.Headers[name] = value.ExecuteWith(data)
+		}
+	}
+	return msg
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	err := p.channel.Close()
+	if p.conn != nil {
+		if cerr := p.conn.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to close AMQP connection")
+	}
+	return err
+}
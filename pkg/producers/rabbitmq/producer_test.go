@@ -0,0 +1,165 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package rabbitmq
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type published struct {
+	exchange string
+	key      string
+	msg      amqp.Publishing
+}
+
+// fakeChannel records the calls made by the producer
+type fakeChannel struct {
+	calls     []string
+	published []published
+}
+
+func (f *fakeChannel) ExchangeDeclare(name, kind string, durable, _, _, _ bool, _ amqp.Table) error {
+	f.calls = append(f.calls, "exchange "+name+" "+kind)
+	return nil
+}
+
+func (f *fakeChannel) QueueDeclare(name string, _, _, _, _ bool, _ amqp.Table) (amqp.Queue, error) {
+	f.calls = append(f.calls, "queue "+name)
+	return amqp.Queue{Name: name}, nil
+}
+
+func (f *fakeChannel) QueueBind(name, key, exchange string, _ bool, _ amqp.Table) error {
+	f.calls = append(f.calls, "bind "+name+" "+key+" "+exchange)
+	return nil
+}
+
+func (f *fakeChannel) PublishWithDeferredConfirmWithContext(_ context.Context, exchange, key string, _, _ bool, msg amqp.Publishing) (*amqp.DeferredConfirmation, error) {
+	f.published = append(f.published, published{exchange: exchange, key: key, msg: msg})
+	return nil, nil
+}
+
+func (f *fakeChannel) Close() error {
+	f.calls = append(f.calls, "close")
+	return nil
+}
+
+func TestProducer(t *testing.T) {
+	testCases := []struct {
+		name      string
+		config    Config
+		wantCalls []string
+		want      published
+	}{
+		{
+			name: "declared topology",
+			config: Config{
+				Exchange: Exchange{Name: "orders", Declare: true, Durable: true},
+				Queue:    Queue{Name: "orders.all", Declare: true, BindingKey: "orders.#"},
+				Message: Message{
+					RoutingKey:    "orders.{{.Value.country}}",
+					CorrelationID: "{{.K}}",
+					Persistent:    true,
+					Headers:       map[string]string{"source": "jr", "country": "{{.Value.country}}"},
+				},
+			},
+			wantCalls: []string{"exchange orders topic", "queue orders.all", "bind orders.all orders.# orders", "close"},
+			want: published{
+				exchange: "orders",
+				key:      "orders.IT",
+				msg: amqp.Publishing{
+					ContentType:   DefaultContentType,
+					CorrelationId: "k1",
+					DeliveryMode:  amqp.Persistent,
+					Headers:       amqp.Table{"source": "jr", "country": "IT"},
+				},
+			},
+		},
+		{
+			name: "templated exchange",
+			config: Config{
+				Exchange: Exchange{Name: "{{.Value.country}}-events"},
+				Message:  Message{RoutingKey: "{{.K}}", ContentType: "text/plain", MessageID: "id-{{.K}}", Type: "order"},
+			},
+			wantCalls: []string{"close"},
+			want: published{
+				exchange: "IT-events",
+				key:      "k1",
+				msg:      amqp.Publishing{ContentType: "text/plain", MessageId: "id-k1", Type: "order"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			ch := &fakeChannel{}
+			p := &Producer{}
+			p.initialize(tc.config, ch)
+
+			value := `{"country": "IT"}`
+			p.Produce(ctx, []byte("k1"), []byte(value), nil)
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(ch.calls, tc.wantCalls) {
+				t.Errorf("got calls %v, want %v", ch.calls, tc.wantCalls)
+			}
+			if len(ch.published) != 1 {
+				t.Fatalf("got %d messages, want 1", len(ch.published))
+			}
+			got := ch.published[0]
+			if got.msg.Timestamp.IsZero() {
+				t.Error("timestamp not set")
+			}
+			got.msg.Timestamp = tc.want.msg.Timestamp
+			tc.want.msg.Body = []byte(value)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDialTLSFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	config := Config{URL: "amqps://guest:guest@" + addr + "/", TLS: tlsconfig.Config{Enabled: true}}
+	conn, err := dial(config)
+	if err == nil {
+		conn.Close()
+		t.Fatal("expected an error dialing a closed port")
+	}
+	if conn != nil {
+		t.Errorf("got connection %v with error %v", conn, err)
+	}
+}
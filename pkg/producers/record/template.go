@@ -0,0 +1,90 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package record holds the per-record templates of the producers, like topics,
+// routing keys or index names computed from each record.
+package record
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/jrnd-io/jr/pkg/functions"
+	"github.com/jrnd-io/jr/pkg/tpl"
+)
+
+// Data is what per-record templates see: the key and value as rendered, and
+// the value decoded when it is a JSON object, e.g. {{.Value.device_id}}
+type Data struct {
+	K     string
+	V     string
+	Value map[string]any
+}
+
+func NewData(k []byte, v []byte) Data {
+	data := Data{K: string(k), V: string(v)}
+	dec := json.NewDecoder(bytes.NewReader(v))
+	dec.UseNumber()
+	if err := dec.Decode(&data.Value); err != nil {
+		data.Value = nil
+	}
+	return data
+}
+
+// Template is evaluated for each record, with Data and all the JR functions
+type Template struct {
+	text string
+	tpl  *tpl.Tpl
+}
+
+func NewTemplate(name string, text string) (*Template, error) {
+	t := &Template{text: text}
+	if !strings.Contains(text, "{{") {
+		return t, nil
+	}
+	parsed, err := tpl.NewTpl(name, text, functions.FunctionsMap(), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.tpl = &parsed
+	return t, nil
+}
+
+// Static is true when the template has no actions and always gives the same text
+func (t *Template) Static() bool {
+	return t.tpl == nil
+}
+
+func (t *Template) Execute(k []byte, v []byte) string {
+	if t.tpl == nil {
+		return t.text
+	}
+	return t.tpl.ExecuteWith(NewData(k, v))
+}
+
+// ExecuteWith evaluates the template with data already decoded, when several templates
+// are evaluated for the same record
+func (t *Template) ExecuteWith(data Data) string {
+	if t.tpl == nil {
+		return t.text
+	}
+	return t.tpl.ExecuteWith(data)
+}
@@ -0,0 +1,144 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+)
+
+// keepTTL is the default of --redis.ttl, which keeps the TTL of the keys that are set again
+const keepTTL = -1
+
+// writer builds the commands writing a record
+type writer struct {
+	mode   string
+	key    *record.Template
+	ttl    time.Duration
+	stream StreamConfig
+	score  *record.Template
+	member *record.Template
+}
+
+func (w *writer) commands(k []byte, v []byte) ([][]any, error) {
+	data := record.NewData(k, v)
+	key := w.key.ExecuteWith(data)
+	if key == "" {
+		return nil, errors.New("empty key")
+	}
+
+	var cmd []any
+	switch w.mode {
+	case Set:
+		cmd = []any{"SET", key, data.V}
+		switch {
+		case w.ttl > 0:
+			return [][]any{append(cmd, "PX", w.ttl.Milliseconds())}, nil
+		case w.ttl == keepTTL:
+			return [][]any{append(cmd, "KEEPTTL")}, nil
+		}
+		return [][]any{cmd}, nil
+	case Publish:
+		return [][]any{{"PUBLISH", key, data.V}}, nil
+	case JSON:
+		cmd = []any{"JSON.SET", key, "$", data.V}
+	case Stream:
+		cmd = []any{"XADD", key}
+		if w.stream.MaxLen > 0 {
+			cmd = append(cmd, "MAXLEN")
+			if !w.stream.Exact {
+				cmd = append(cmd, "~")
+			}
+			cmd = append(cmd, w.stream.MaxLen)
+		}
+		cmd = append(cmd, "*")
+		if w.stream.ValueField != "" {
+			cmd = append(cmd, w.stream.ValueField, data.V)
+			break
+		}
+		fields, err := flatten(data)
+		if err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, fields...)
+	case Hash:
+		fields, err := flatten(data)
+		if err != nil {
+			return nil, err
+		}
+		cmd = append([]any{"HSET", key}, fields...)
+	case SortedSet:
+		score, err := strconv.ParseFloat(w.score.ExecuteWith(data), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score: %w", err)
+		}
+		cmd = []any{"ZADD", key, score, w.member.ExecuteWith(data)}
+	default:
+		return nil, fmt.Errorf("unsupported mode %q", w.mode)
+	}
+
+	if w.ttl > 0 {
+		return [][]any{cmd, {"PEXPIRE", key, w.ttl.Milliseconds()}}, nil
+	}
+	return [][]any{cmd}, nil
+}
+
+// flatten returns the top-level fields of a record and their values, sorted by
+// field; nested values are JSON text
+func flatten(data record.Data) ([]any, error) {
+	if data.Value == nil {
+		return nil, errors.New("record is not a JSON object")
+	}
+	if len(data.Value) == 0 {
+		return nil, errors.New("record has no fields")
+	}
+
+	names := make([]string, 0, len(data.Value))
+	for name := range data.Value {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	fields := make([]any, 0, 2*len(names))
+	for _, name := range names {
+		switch v := data.Value[name].(type) {
+		case string:
+			fields = append(fields, name, v)
+		case json.Number:
+			fields = append(fields, name, v.String())
+		case nil:
+			fields = append(fields, name, "")
+		default:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, name, string(b))
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,115 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCommands(t *testing.T) {
+	value := `{"user":"u1","points":42,"tags":["a"]}`
+
+	testCases := []struct {
+		name   string
+		config ProducerConfig
+		ttl    time.Duration
+		want   [][]any
+	}{
+		{
+			name: "set",
+			ttl:  keepTTL,
+			want: [][]any{{"SET", "k", value, "KEEPTTL"}},
+		},
+		{
+			name:   "set with ttl",
+			config: ProducerConfig{TTL: "10s"},
+			want:   [][]any{{"SET", "k", value, "PX", int64(10000)}},
+		},
+		{
+			name:   "json",
+			config: ProducerConfig{Mode: JSON, Key: "user:{{.Value.user}}"},
+			want:   [][]any{{"JSON.SET", "user:u1", "$", value}},
+		},
+		{
+			name:   "stream",
+			config: ProducerConfig{Mode: Stream, Key: "events", Stream: StreamConfig{MaxLen: 1000}},
+			want:   [][]any{{"XADD", "events", "MAXLEN", "~", int64(1000), "*", "points", "42", "tags", `["a"]`, "user", "u1"}},
+		},
+		{
+			name:   "stream value field",
+			config: ProducerConfig{Mode: Stream, Key: "events", Stream: StreamConfig{MaxLen: 10, Exact: true, ValueField: "record"}},
+			want:   [][]any{{"XADD", "events", "MAXLEN", int64(10), "*", "record", value}},
+		},
+		{
+			name:   "publish",
+			config: ProducerConfig{Mode: Publish, Key: "points.{{.Value.user}}"},
+			ttl:    time.Minute,
+			want:   [][]any{{"PUBLISH", "points.u1", value}},
+		},
+		{
+			name:   "hash with ttl",
+			config: ProducerConfig{Mode: Hash, Key: "user:{{.K}}"},
+			ttl:    time.Minute,
+			want:   [][]any{{"HSET", "user:k", "points", "42", "tags", `["a"]`, "user", "u1"}, {"PEXPIRE", "user:k", int64(60000)}},
+		},
+		{
+			name:   "zset",
+			config: ProducerConfig{Mode: SortedSet, Key: "leaderboard", SortedSet: SortedSetConfig{Score: "{{.Value.points}}", Member: "{{.Value.user}}"}},
+			want:   [][]any{{"ZADD", "leaderboard", float64(42), "u1"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := newWriter(tc.config, tc.ttl)
+			got, err := w.commands([]byte("k"), []byte(value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCommandsErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config ProducerConfig
+		value  string
+	}{
+		{name: "empty key", config: ProducerConfig{Key: `{{or .Value.missing ""}}`}, value: `{}`},
+		{name: "hash of a non object", config: ProducerConfig{Mode: Hash}, value: `[1]`},
+		{name: "empty hash", config: ProducerConfig{Mode: Hash}, value: `{}`},
+		{name: "invalid score", config: ProducerConfig{Mode: SortedSet, SortedSet: SortedSetConfig{Score: "{{.Value.user}}"}}, value: `{"user":"u1"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := newWriter(tc.config, 0).commands([]byte("k"), []byte(tc.value)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
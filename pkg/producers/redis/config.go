@@ -0,0 +1,93 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	Set       = "set"
+	JSON      = "json"
+	Stream    = "stream"
+	Publish   = "publish"
+	Hash      = "hash"
+	SortedSet = "zset"
+
+	DefaultPipelineSize = 100
+)
+
+type StreamConfig struct {
+	// MaxLen trims the stream to about MaxLen entries, exactly with Exact
+	MaxLen int64 `json:"max_len"`
+	Exact  bool  `json:"exact"`
+	// ValueField, when set, is the only field of the entries, with the whole record;
+	// otherwise each top-level field of the record is a field of the entry
+	ValueField string `json:"value_field"`
+}
+
+type SortedSetConfig struct {
+	// Score and Member are evaluated for each record, e.g. "{{.Value.points}}";
+	// the member is the whole record by default
+	Score  string `json:"score"`
+	Member string `json:"member"`
+}
+
+// ProducerConfig extends the connection options with the way records are written.
+// The keys of the connection options are the ones of the go-redis options, e.g. "addr",
+// "db" and "PoolSize", durations being nanoseconds
+type ProducerConfig struct {
+	redis.Options
+
+	Addr string `json:"addr"`
+	// Host and Port are used when Addr is not set
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	DB       int    `json:"db"`
+
+	// Addrs are the seed nodes of a Redis Cluster, with Cluster, or the sentinels of MasterName
+	Addrs            []string `json:"addrs"`
+	Cluster          bool     `json:"cluster"`
+	MasterName       string   `json:"master_name"`
+	SentinelUsername string   `json:"sentinel_username"`
+	SentinelPassword string   `json:"sentinel_password"`
+
+	TLS tlsconfig.Config `json:"tls"`
+
+	// Mode is "set", the default, "json" (JSON.SET), "stream" (XADD), "publish",
+	// "hash" (HSET of the top-level fields) or "zset" (ZADD)
+	Mode string `json:"mode"`
+	// Key is evaluated for each record, as the key, the stream or the channel;
+	// the JR key by default
+	Key string `json:"key"`
+	// TTL expires the written keys, e.g. "10m", overriding --redis.ttl
+	TTL string `json:"ttl"`
+
+	Stream    StreamConfig    `json:"stream"`
+	SortedSet SortedSetConfig `json:"zset"`
+
+	// PipelineSize records are written in each pipeline, at least every FlushInterval
+	PipelineSize  int    `json:"pipeline_size"`
+	FlushInterval string `json:"flush_interval"`
+}
@@ -0,0 +1,194 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package redis
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	testCases := []struct {
+		name   string
+		config ProducerConfig
+		check  func(t *testing.T)
+	}{
+		{
+			name:   "set",
+			config: ProducerConfig{Key: "order:{{.K}}", TTL: "1h"},
+			check: func(t *testing.T) {
+				if got, _ := server.Get("order:k2"); got != `{"user":"u2","points":20}` {
+					t.Errorf("got %q", got)
+				}
+				if ttl := server.TTL("order:k2"); ttl != time.Hour {
+					t.Errorf("got ttl %v", ttl)
+				}
+			},
+		},
+		{
+			name:   "stream",
+			config: ProducerConfig{Mode: Stream, Key: "points", Stream: StreamConfig{MaxLen: 2, Exact: true}},
+			check: func(t *testing.T) {
+				entries, err := server.Stream("points")
+				if err != nil {
+					t.Fatal(err)
+				}
+				var values [][]string
+				for _, e := range entries {
+					values = append(values, e.Values)
+				}
+				want := [][]string{{"points", "10", "user", "u1"}, {"points", "20", "user", "u2"}}
+				if !reflect.DeepEqual(values, want) {
+					t.Errorf("got %v, want %v", values, want)
+				}
+			},
+		},
+		{
+			name:   "hash",
+			config: ProducerConfig{Mode: Hash, Key: "user:{{.Value.user}}"},
+			check: func(t *testing.T) {
+				if got := server.HGet("user:u1", "points"); got != "10" {
+					t.Errorf("got %q", got)
+				}
+			},
+		},
+		{
+			name:   "zset",
+			config: ProducerConfig{Mode: SortedSet, Key: "leaderboard", SortedSet: SortedSetConfig{Score: "{{.Value.points}}", Member: "{{.Value.user}}"}},
+			check: func(t *testing.T) {
+				members, err := server.ZMembers("leaderboard")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want := []string{"u0", "u1", "u2"}; !reflect.DeepEqual(members, want) {
+					t.Errorf("got %v, want %v", members, want)
+				}
+				if score, _ := server.ZScore("leaderboard", "u2"); score != 20 {
+					t.Errorf("got score %v", score)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.config.Addr = server.Addr()
+			tc.config.PipelineSize = 2
+			p := &Producer{}
+			p.InitializeFromConfig(tc.config)
+			for i := 0; i < 3; i++ {
+				p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf(`{"user":"u%d","points":%d}`, i, i*10)), nil)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+			tc.check(t)
+		})
+	}
+}
+
+func TestProducerPublish(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	subscriber := server.NewSubscriber()
+	defer subscriber.Close()
+	subscriber.Subscribe("points.u1")
+	// PUBLISH waits for the subscriber to read the message
+	messages := make(chan miniredis.PubsubMessage, 1)
+	go func() {
+		messages <- <-subscriber.Messages()
+	}()
+
+	p := &Producer{}
+	p.InitializeFromConfig(ProducerConfig{Host: "127.0.0.1", Port: server.Port(), Mode: Publish, Key: "points.{{.Value.user}}"})
+	p.Produce(ctx, nil, []byte(`{"user":"u1"}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case m := <-messages:
+		if m.Channel != "points.u1" || m.Message != `{"user":"u1"}` {
+			t.Errorf("got %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no message published")
+	}
+}
+
+func TestProducerFailedCommands(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	server.Set("user:u1", "not a hash")
+
+	p := &Producer{}
+	p.InitializeFromConfig(ProducerConfig{Addr: server.Addr(), Mode: Hash, Key: "user:{{.Value.user}}"})
+	p.Produce(ctx, nil, []byte(`{"user":"u1"}`), nil)
+	p.Produce(ctx, nil, []byte(`{"user":"u2"}`), nil)
+
+	err := p.Close(ctx)
+	if err == nil || !strings.Contains(err.Error(), "1 commands failed") {
+		t.Errorf("expected 1 failed command, got %v", err)
+	}
+	if got := server.HGet("user:u2", "user"); got != "u2" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+
+	// the go-redis options of the previous configuration files are still used
+	config, err := parseConfig([]byte(fmt.Sprintf(`{
+		"addr": %q,
+		"PoolSize": 3,
+		"DialTimeout": 2000000000,
+		"ClientName": "jr",
+		"mode": "hash"
+	}`, server.Addr())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Producer{}
+	p.InitializeFromConfig(config)
+	options := p.client.(*redis.Client).Options()
+	if options.Addr != server.Addr() || options.PoolSize != 3 || options.DialTimeout != 2*time.Second || options.ClientName != "jr" {
+		t.Errorf("got options %+v", options)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := parseConfig([]byte(`{"addr": "localhost:6379", "pool_size": 3}`)); err == nil {
+		t.Error("expected an error for an unknown key")
+	}
+}
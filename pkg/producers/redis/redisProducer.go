@@ -21,47 +21,229 @@
 package redis
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"os"
+	"sync/atomic"
 	"time"
 
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/jrnd-io/jr/pkg/producers/record"
 	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog/log"
 )
 
+// entry is the commands writing a record
+type entry struct {
+	commands [][]any
+	bytes    int
+}
+
+func (e entry) size() int {
+	return e.bytes
+}
+
 type Producer struct {
-	client redis.Client
-	Ttl    time.Duration
+	Ttl time.Duration
+
+	configuration ProducerConfig
+	client        redis.UniversalClient
+	writer        *writer
+	batcher       *batcher.Batcher[entry]
+	failed        atomic.Int64
 }
 
 func (p *Producer) Initialize(configFile string) {
-	var options redis.Options
-
 	data, err := os.ReadFile(configFile)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load Redis configFile")
 	}
 
-	err = json.Unmarshal(data, &options)
+	config, err := parseConfig(data)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
 	}
 
-	p.client = *redis.NewClient(&options)
+	p.InitializeFromConfig(config)
 }
 
-func (p *Producer) Close(_ context.Context) error {
-	err := p.client.Close()
-	if err != nil {
-		log.Warn().Err(err).Msg("Failed to close Redis connection")
+// parseConfig rejects unknown keys, which would otherwise be silently ignored
+func parseConfig(data []byte) (ProducerConfig, error) {
+	var config ProducerConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&config)
+	return config, err
+}
+
+func (p *Producer) InitializeFromConfig(config ProducerConfig) {
+	var err error
+	p.configuration = config
+
+	var tlsConfig *tls.Config
+	if config.TLS.IsSet() {
+		if tlsConfig, err = config.TLS.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+
+	addr := config.Addr
+	if addr == "" && config.Host != "" {
+		addr = net.JoinHostPort(config.Host, config.Port)
+	}
+	o := config.Options
+	options := &redis.UniversalOptions{
+		Addrs:            config.Addrs,
+		ClientName:       o.ClientName,
+		Protocol:         o.Protocol,
+		Username:         config.Username,
+		Password:         config.Password,
+		DB:               config.DB,
+		MasterName:       config.MasterName,
+		SentinelUsername: config.SentinelUsername,
+		SentinelPassword: config.SentinelPassword,
+		TLSConfig:        tlsConfig,
+
+		MaxRetries:      o.MaxRetries,
+		MinRetryBackoff: o.MinRetryBackoff,
+		MaxRetryBackoff: o.MaxRetryBackoff,
+
+		DialTimeout:           o.DialTimeout,
+		ReadTimeout:           o.ReadTimeout,
+		WriteTimeout:          o.WriteTimeout,
+		ContextTimeoutEnabled: o.ContextTimeoutEnabled,
+
+		PoolFIFO:        o.PoolFIFO,
+		PoolSize:        o.PoolSize,
+		PoolTimeout:     o.PoolTimeout,
+		MinIdleConns:    o.MinIdleConns,
+		MaxIdleConns:    o.MaxIdleConns,
+		MaxActiveConns:  o.MaxActiveConns,
+		ConnMaxIdleTime: o.ConnMaxIdleTime,
+		ConnMaxLifetime: o.ConnMaxLifetime,
+
+		DisableIndentity: o.DisableIndentity,
+		DisableIdentity:  o.DisableIdentity,
+		IdentitySuffix:   o.IdentitySuffix,
+		UnstableResp3:    o.UnstableResp3,
+	}
+	if len(options.Addrs) == 0 && addr != "" {
+		options.Addrs = []string{addr}
+	}
+	switch {
+	case config.Cluster:
+		p.client = redis.NewClusterClient(options.Cluster())
+	case config.MasterName != "":
+		p.client = redis.NewFailoverClient(options.Failover())
+	default:
+		simple := options.Simple()
+		simple.Network = o.Network
+		p.client = redis.NewClient(simple)
+	}
+
+	p.writer = newWriter(config, p.Ttl)
+
+	if config.PipelineSize <= 0 {
+		p.configuration.PipelineSize = DefaultPipelineSize
+	}
+	var interval time.Duration
+	if config.FlushInterval != "" {
+		if interval, err = time.ParseDuration(config.FlushInterval); err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+		}
+	} else if p.configuration.PipelineSize > 1 {
+		interval = 100 * time.Millisecond
+	}
+	p.batcher = batcher.New(p.exec, entry.size, batcher.Config{
+		MaxItems: p.configuration.PipelineSize,
+		Interval: interval,
+	})
+}
+
+func newWriter(config ProducerConfig, ttl time.Duration) *writer {
+	var err error
+	w := &writer{mode: config.Mode, ttl: ttl, stream: config.Stream}
+	switch config.Mode {
+	case "":
+		w.mode = Set
+	case Set, JSON, Stream, Publish, Hash, SortedSet:
+	default:
+		log.Fatal().Str("mode", config.Mode).Msg("mode must be set, json, stream, publish, hash or zset")
+	}
+
+	if config.TTL != "" {
+		if w.ttl, err = time.ParseDuration(config.TTL); err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse ttl")
+		}
+	}
+
+	if config.Key == "" {
+		config.Key = "{{.K}}"
+	}
+	if w.key, err = record.NewTemplate("key", config.Key); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse key template")
+	}
+	if w.mode == SortedSet && config.SortedSet.Score == "" {
+		log.Fatal().Msg("zset score is mandatory")
+	}
+	if w.score, err = record.NewTemplate("score", config.SortedSet.Score); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse score template")
+	}
+	if config.SortedSet.Member == "" {
+		config.SortedSet.Member = "{{.V}}"
+	}
+	if w.member, err = record.NewTemplate("member", config.SortedSet.Member); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse member template")
+	}
+	return w
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if failed := p.failed.Load(); failed > 0 {
+		err = errors.Join(err, fmt.Errorf("%d commands failed", failed))
+	}
+	if closeErr := p.client.Close(); closeErr != nil {
+		log.Warn().Err(closeErr).Msg("Failed to close Redis connection")
+		err = errors.Join(err, closeErr)
 	}
 	return err
 }
 
 func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
-	err := p.client.Set(ctx, string(k), string(v), p.Ttl).Err()
+	commands, err := p.writer.commands(k, v)
 	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to write data in Redis")
+		log.Error().Err(err).Msg("Failed to write data in Redis")
+		return
+	}
+	p.batcher.Add(ctx, entry{commands: commands, bytes: len(k) + len(v)})
+}
+
+// exec sends the commands of a batch in a pipeline
+func (p *Producer) exec(ctx context.Context, entries []entry) ([]entry, error) {
+	pipe := p.client.Pipeline()
+	for _, e := range entries {
+		for _, command := range e.commands {
+			pipe.Do(ctx, command...)
+		}
+	}
+
+	cmds, err := pipe.Exec(ctx)
+	if err == nil {
+		return nil, nil
+	}
+	failed := 0
+	for _, cmd := range cmds {
+		if cmd.Err() != nil {
+			failed++
+		}
 	}
+	p.failed.Add(int64(failed))
+	log.Error().Err(err).Int("commands", failed).Msg("Failed to write data in Redis")
+	return nil, nil
 }
@@ -28,19 +28,25 @@ import (
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	awsconfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/google/uuid"
+	"github.com/jrnd-io/jr/pkg/producers/awscfg"
+	"github.com/jrnd-io/jr/pkg/producers/format"
 	"github.com/rs/zerolog/log"
 )
 
 type Config struct {
 	Bucket string `json:"bucket"`
+	// with a format, records are written in batches, one object per batch
+	format.BatchConfig
+	// region, endpoint and profile
+	awscfg.Config
 }
 
 type Producer struct {
 	client *s3.Client
 	bucket string
+	batch  *format.Batch
 }
 
 func (p *Producer) Initialize(ctx context.Context, configFile string) {
@@ -54,19 +60,41 @@ func (p *Producer) Initialize(ctx context.Context, configFile string) {
 		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
 	}
 
-	awsConfig, err := awsconfig.LoadDefaultConfig(ctx)
+	awsConfig, err := awscfg.Load(ctx, config.Config)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to load default AWS config")
 	}
 
-	client := s3.NewFromConfig(awsConfig)
+	client := s3.NewFromConfig(awsConfig, func(o *s3.Options) {
+		// custom endpoints like LocalStack don't resolve bucket subdomains
+		o.UsePathStyle = config.Endpoint != ""
+	})
 
 	p.client = client
 	p.bucket = config.Bucket
+
+	if config.Format != "" {
+		p.batch, err = format.NewBatch(config.BatchConfig)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Invalid object format")
+		}
+	}
 }
 
 func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
 
+	if p.batch != nil {
+		full, err := p.batch.Add(v)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to encode record")
+			return
+		}
+		if full {
+			p.flush(ctx)
+		}
+		return
+	}
+
 	bucket := p.bucket
 	var key string
 
@@ -89,7 +117,29 @@ func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
 	}
 }
 
-func (p *Producer) Close(_ context.Context) error {
+func (p *Producer) flush(ctx context.Context) {
+	key, content, err := p.batch.Flush()
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to encode batch")
+	}
+	if content == nil {
+		return
+	}
+
+	_, err = p.client.PutObject(ctx, &s3.PutObjectInput{
+		Body:   bytes.NewReader(content),
+		Bucket: aws.String(p.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to write data in s3")
+	}
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	if p.batch != nil {
+		p.flush(ctx)
+	}
 	log.Warn().Msg("S3 Client doesn't provide a close method!")
 	return nil
 }
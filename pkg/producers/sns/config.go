@@ -0,0 +1,50 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sns
+
+import "github.com/jrnd-io/jr/pkg/producers/awscfg"
+
+const (
+	// MaxBatchMessages is the maximum number of messages of PublishBatch
+	MaxBatchMessages     = 10
+	DefaultRetries       = 3
+	DefaultFlushInterval = "1s"
+)
+
+type Config struct {
+	TopicARN string `json:"topic_arn"`
+	// Subject is evaluated for each record, for email subscriptions
+	Subject string `json:"subject"`
+	// MessageGroupID and DeduplicationID are evaluated for each record, for FIFO topics,
+	// e.g. "{{.Value.customer_id}}" and "{{.K}}"
+	MessageGroupID  string `json:"message_group_id"`
+	DeduplicationID string `json:"deduplication_id"`
+	// Attributes values are evaluated for each record and sent as String message attributes
+	Attributes map[string]string `json:"attributes"`
+	// BatchSize is the number of messages of each request, up to 10
+	BatchSize int `json:"batch_size"`
+	// FlushInterval sends an incomplete batch when it is older than this duration, 1s by default
+	FlushInterval string `json:"flush_interval"`
+	// MaxRetries is the number of times failed messages are sent again, -1 for none
+	MaxRetries int `json:"max_retries"`
+	// region, endpoint and profile
+	awscfg.Config
+}
@@ -0,0 +1,120 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sns
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+)
+
+// fakeSNS fails each message the first time it is published
+type fakeSNS struct {
+	seen     map[string]bool
+	topics   []string
+	requests []int
+	received []types.PublishBatchRequestEntry
+}
+
+func (f *fakeSNS) PublishBatch(_ context.Context, in *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	f.topics = append(f.topics, aws.ToString(in.TopicArn))
+	f.requests = append(f.requests, len(in.PublishBatchRequestEntries))
+	out := &sns.PublishBatchOutput{}
+	for _, e := range in.PublishBatchRequestEntries {
+		m := aws.ToString(e.Message)
+		if !f.seen[m] {
+			f.seen[m] = true
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{Id: e.Id, Code: aws.String("Throttled")})
+			continue
+		}
+		f.received = append(f.received, e)
+		out.Successful = append(out.Successful, types.PublishBatchResultEntry{Id: e.Id})
+	}
+	return out, nil
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSNS{seen: make(map[string]bool)}
+	p := &Producer{client: fake}
+	topic := "arn:aws:sns:us-east-1:000000000000:orders.fifo"
+	p.initialize(Config{
+		TopicARN:        topic,
+		Subject:         "Order {{.K}}",
+		MessageGroupID:  "{{.Value.customer}}",
+		DeduplicationID: "{{.K}}",
+		Attributes:      map[string]string{"customer": "{{.Value.customer}}"},
+		BatchSize:       4,
+	})
+	p.batcher.Backoff = time.Millisecond
+
+	for i := 0; i < 5; i++ {
+		p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf(`{"n":%d,"customer":"c%d"}`, i, i%2)), nil)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []int{4, 4, 1, 1}; !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got requests %v, want %v", fake.requests, want)
+	}
+	for _, got := range fake.topics {
+		if got != topic {
+			t.Errorf("got topic %q, want %q", got, topic)
+		}
+	}
+	if len(fake.received) != 5 {
+		t.Fatalf("got %d messages, want 5", len(fake.received))
+	}
+	e := fake.received[1]
+	if got := aws.ToString(e.Subject); got != "Order k1" {
+		t.Errorf("got subject %q", got)
+	}
+	if got := aws.ToString(e.MessageGroupId); got != "c1" {
+		t.Errorf("got group ID %q, want c1", got)
+	}
+	if got := aws.ToString(e.MessageDeduplicationId); got != "k1" {
+		t.Errorf("got deduplication ID %q, want k1", got)
+	}
+	if got := aws.ToString(e.MessageAttributes["customer"].StringValue); got != "c1" {
+		t.Errorf("got customer attribute %q, want c1", got)
+	}
+}
+
+func TestProducerRetriesExhausted(t *testing.T) {
+	ctx := context.Background()
+	p := &Producer{client: &fakeSNS{seen: make(map[string]bool)}}
+	p.initialize(Config{TopicARN: "arn:aws:sns:us-east-1:000000000000:jr", MaxRetries: -1})
+	if p.configuration.FlushInterval != DefaultFlushInterval {
+		t.Errorf("got flush interval %q, want %q", p.configuration.FlushInterval, DefaultFlushInterval)
+	}
+
+	p.Produce(ctx, nil, []byte(`{}`), nil)
+	if err := p.Close(ctx); err == nil {
+		t.Error("expected an error for the failed message")
+	}
+}
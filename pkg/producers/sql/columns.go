@@ -0,0 +1,163 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hamba/avro/v2"
+)
+
+type column struct {
+	name string
+	kind columnType
+}
+
+// record is a rendered value: its top-level fields in order, and their values
+type record struct {
+	names  []string
+	values map[string]any
+}
+
+// parseRecord decodes a JSON object keeping the order of its fields, which
+// gives the order of the columns when the table is inferred
+func parseRecord(data []byte) (record, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	r := record{values: make(map[string]any)}
+	t, err := dec.Token()
+	if err != nil {
+		return r, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return r, fmt.Errorf("expected a JSON object, got %v", t)
+	}
+	for dec.More() {
+		t, err = dec.Token()
+		if err != nil {
+			return r, err
+		}
+		name := t.(string)
+		var v any
+		if err = dec.Decode(&v); err != nil {
+			return r, fmt.Errorf("field %q: %w", name, err)
+		}
+		if _, ok := r.values[name]; !ok {
+			r.names = append(r.names, name)
+		}
+		r.values[name] = v
+	}
+	if _, err = dec.Token(); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// inferColumns derives the columns from the fields of a record
+func inferColumns(r record) []column {
+	columns := make([]column, len(r.names))
+	for i, name := range r.names {
+		columns[i] = column{name: name, kind: kindOf(r.values[name])}
+	}
+	return columns
+}
+
+func kindOf(v any) columnType {
+	switch t := v.(type) {
+	case json.Number:
+		if _, err := t.Int64(); err == nil {
+			return integerColumn
+		}
+		return floatColumn
+	case bool:
+		return booleanColumn
+	case map[string]any, []any:
+		return jsonColumn
+	default:
+		return textColumn
+	}
+}
+
+// avroColumns derives the columns from the fields of an Avro record schema
+func avroColumns(s avro.Schema) ([]column, error) {
+	rs, ok := s.(*avro.RecordSchema)
+	if !ok {
+		return nil, fmt.Errorf("expected an Avro record schema, got %s", s.Type())
+	}
+	columns := make([]column, len(rs.Fields()))
+	for i, f := range rs.Fields() {
+		columns[i] = column{name: f.Name(), kind: avroKind(f.Type())}
+	}
+	return columns, nil
+}
+
+func avroKind(s avro.Schema) columnType {
+	switch s.Type() {
+	case avro.Int, avro.Long:
+		return integerColumn
+	case avro.Float, avro.Double:
+		return floatColumn
+	case avro.Boolean:
+		return booleanColumn
+	case avro.Bytes, avro.Fixed:
+		if l, ok := s.(avro.LogicalTypeSchema); ok && l.Logical() != nil && l.Logical().Type() == avro.Decimal {
+			return floatColumn
+		}
+		return textColumn
+	case avro.Record, avro.Array, avro.Map:
+		return jsonColumn
+	case avro.Union:
+		us := s.(*avro.UnionSchema)
+		if us.Nullable() {
+			for _, t := range us.Types() {
+				if t.Type() != avro.Null {
+					return avroKind(t)
+				}
+			}
+		}
+		return jsonColumn
+	default:
+		return textColumn
+	}
+}
+
+// columnValue converts a decoded JSON value to a driver value: nested objects
+// and arrays are stored as JSON text
+func columnValue(v any) (any, error) {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	case map[string]any, []any:
+		b, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		return t, nil
+	}
+}
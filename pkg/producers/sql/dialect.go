@@ -0,0 +1,303 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type columnType int
+
+const (
+	textColumn columnType = iota
+	integerColumn
+	floatColumn
+	booleanColumn
+	jsonColumn
+)
+
+// dialect holds what changes between databases: identifier quoting, placeholders,
+// column types and the upsert clause
+type dialect struct {
+	name string
+	// driver is the database/sql driver name
+	driver string
+	// quoteChars are the opening and closing identifier quotes
+	quoteChars [2]string
+	types      map[columnType]string
+	// keyType is used instead of the text type for a text key column, since
+	// some databases cannot index unbounded text
+	keyType   string
+	maxParams int
+	// numbered placeholders ($1, $2...) instead of ?
+	numbered bool
+	// maxRows is the maximum number of rows of a VALUES list, when limited
+	maxRows int
+
+	// literals of scripts
+	booleans         [2]string
+	backslashEscapes bool
+	stringPrefix     string
+}
+
+var dialects = map[string]*dialect{
+	"postgres": {
+		name:       "postgres",
+		driver:     "postgres",
+		quoteChars: [2]string{`"`, `"`},
+		types: map[columnType]string{
+			textColumn:    "TEXT",
+			integerColumn: "BIGINT",
+			floatColumn:   "DOUBLE PRECISION",
+			booleanColumn: "BOOLEAN",
+			jsonColumn:    "JSONB",
+		},
+		keyType:   "TEXT",
+		maxParams: 65535,
+		numbered:  true,
+		booleans:  [2]string{"FALSE", "TRUE"},
+	},
+	"mysql": {
+		name:       "mysql",
+		driver:     "mysql",
+		quoteChars: [2]string{"`", "`"},
+		types: map[columnType]string{
+			textColumn:    "TEXT",
+			integerColumn: "BIGINT",
+			floatColumn:   "DOUBLE",
+			booleanColumn: "BOOLEAN",
+			jsonColumn:    "JSON",
+		},
+		keyType:          "VARCHAR(255)",
+		maxParams:        65535,
+		booleans:         [2]string{"FALSE", "TRUE"},
+		backslashEscapes: true,
+	},
+	"sqlite": {
+		name:       "sqlite",
+		driver:     "sqlite3",
+		quoteChars: [2]string{`"`, `"`},
+		types: map[columnType]string{
+			textColumn:    "TEXT",
+			integerColumn: "INTEGER",
+			floatColumn:   "REAL",
+			booleanColumn: "BOOLEAN",
+			jsonColumn:    "TEXT",
+		},
+		keyType:   "TEXT",
+		maxParams: 32766,
+		booleans:  [2]string{"0", "1"},
+	},
+	// sqlserver has no driver: it is only used for scripts
+	"sqlserver": {
+		name:       "sqlserver",
+		quoteChars: [2]string{"[", "]"},
+		types: map[columnType]string{
+			textColumn:    "NVARCHAR(MAX)",
+			integerColumn: "BIGINT",
+			floatColumn:   "FLOAT",
+			booleanColumn: "BIT",
+			jsonColumn:    "NVARCHAR(MAX)",
+		},
+		keyType:      "NVARCHAR(255)",
+		maxParams:    2100,
+		maxRows:      1000,
+		booleans:     [2]string{"0", "1"},
+		stringPrefix: "N",
+	},
+}
+
+func dialectOf(name string) (*dialect, error) {
+	switch strings.ToLower(name) {
+	case "postgresql", "pg":
+		name = "postgres"
+	case "sqlite3":
+		name = "sqlite"
+	case "mssql":
+		name = "sqlserver"
+	}
+	d, ok := dialects[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver %q", name)
+	}
+	return d, nil
+}
+
+// quote quotes a table name; qualified names like schema.table are quoted part by part
+func (d *dialect) quote(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = d.quoteColumn(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteColumn quotes a column name as a single identifier, since record fields may contain dots
+func (d *dialect) quoteColumn(name string) string {
+	return d.quoteChars[0] + strings.ReplaceAll(name, d.quoteChars[1], d.quoteChars[1]+d.quoteChars[1]) + d.quoteChars[1]
+}
+
+func (d *dialect) quoteAll(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.quoteColumn(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (d *dialect) placeholder(i int) string {
+	if d.numbered {
+		return fmt.Sprintf("$%d", i)
+	}
+	return "?"
+}
+
+func (d *dialect) columnType(c column, key bool) string {
+	if key && c.kind == textColumn {
+		return d.keyType
+	}
+	return d.types[c.kind]
+}
+
+func (d *dialect) createTable(table string, columns []column, key string) string {
+	var b strings.Builder
+	if d.name == "sqlserver" {
+		fmt.Fprintf(&b, "IF OBJECT_ID(%s, N'U') IS NULL CREATE TABLE %s (", d.literal(table), d.quote(table))
+	} else {
+		fmt.Fprintf(&b, "CREATE TABLE IF NOT EXISTS %s (", d.quote(table))
+	}
+	for i, c := range columns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		fmt.Fprintf(&b, "%s %s", d.quoteColumn(c.name), d.columnType(c, c.name == key))
+		if c.name == key {
+			b.WriteString(" NOT NULL")
+		}
+	}
+	if key != "" {
+		fmt.Fprintf(&b, ", PRIMARY KEY (%s)", d.quoteColumn(key))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// insert builds a multi-row INSERT with placeholders for rows rows; when key is set,
+// rows with an existing key update all the other columns
+func (d *dialect) insert(table string, names []string, rows int, key string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", d.quote(table), d.quoteAll(names))
+	n := 1
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for i := range names {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(d.placeholder(n))
+			n++
+		}
+		b.WriteString(")")
+	}
+	if key != "" {
+		b.WriteString(d.upsert(names, key))
+	}
+	return b.String()
+}
+
+// insertValues builds a multi-row INSERT of literal values, for scripts
+func (d *dialect) insertValues(table string, names []string, rows [][]any, key string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES", d.quote(table), d.quoteAll(names))
+	for r, row := range rows {
+		if r > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n  (")
+		for i, v := range row {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(d.literal(v))
+		}
+		b.WriteString(")")
+	}
+	if key != "" {
+		b.WriteString(d.upsert(names, key))
+	}
+	return b.String()
+}
+
+// literal renders a column value as an SQL literal
+func (d *dialect) literal(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if t {
+			return d.booleans[1]
+		}
+		return d.booleans[0]
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	default:
+		str := fmt.Sprint(t)
+		if d.backslashEscapes {
+			str = strings.ReplaceAll(str, `\`, `\\`)
+			str = strings.ReplaceAll(str, "\x00", `\0`)
+		}
+		return d.stringPrefix + "'" + strings.ReplaceAll(str, "'", "''") + "'"
+	}
+}
+
+func (d *dialect) upsert(names []string, key string) string {
+	var updates []string
+	for _, name := range names {
+		if name == key {
+			continue
+		}
+		q := d.quoteColumn(name)
+		if d.name == "mysql" {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", q, q))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", q, q))
+		}
+	}
+
+	if d.name == "mysql" {
+		if len(updates) == 0 {
+			q := d.quoteColumn(key)
+			return fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", q, q)
+		}
+		return " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	}
+	if len(updates) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", d.quoteColumn(key))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", d.quoteColumn(key), strings.Join(updates, ", "))
+}
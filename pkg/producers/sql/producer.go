@@ -0,0 +1,260 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"context"
+	dbsql "database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer writes records as table rows, mapping the top-level fields of each
+// record to columns. Records are written in batches of multi-row inserts, or with COPY.
+type Producer struct {
+	configuration Config
+	dialect       *dialect
+	db            *dbsql.DB
+	flushInterval time.Duration
+
+	lock     sync.Mutex
+	table    *table
+	oldest   time.Time
+	stopTick chan struct{}
+	tickDone chan struct{}
+}
+
+func (p *Producer) Initialize(configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+	if p.configuration.Table == "" {
+		log.Fatal().Msg("Table is mandatory")
+	}
+	if p.configuration.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+
+	p.dialect, err = dialectOf(config.Driver)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid SQL configuration")
+	}
+	if p.dialect.driver == "" {
+		log.Fatal().Str("driver", config.Driver).Msg("Driver can only be used for SQL scripts")
+	}
+	if config.Copy && (p.dialect.name != "postgres" || config.KeyColumn != "") {
+		log.Fatal().Msg("copy is only supported by postgres and without key_column")
+	}
+
+	p.table, err = newTable(config.Table, config.KeyColumn, config.SchemaFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to derive columns from schema")
+	}
+
+	p.db, err = dbsql.Open(p.dialect.driver, config.DSN)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to open database")
+	}
+	if err = p.db.Ping(); err != nil {
+		log.Fatal().Err(err).Str("driver", config.Driver).Msg("Can't connect to database")
+	}
+
+	if config.CreateTable && p.table.columns != nil {
+		p.createTable(context.Background())
+	}
+
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	p.flushInterval, err = time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	if p.flushInterval <= 0 {
+		log.Fatal().Str("flush_interval", p.configuration.FlushInterval).Msg("flush_interval must be positive")
+	}
+	p.stopTick = make(chan struct{})
+	p.tickDone = make(chan struct{})
+	go p.flushOnInterval()
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	r, err := p.table.parse(k, v)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse record")
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if len(p.table.batch) == 0 {
+		p.oldest = time.Now()
+	}
+	if p.table.add(r) && p.configuration.CreateTable {
+		p.createTable(ctx)
+	}
+
+	if len(p.table.batch) >= p.configuration.BatchSize {
+		if err := p.flush(ctx); err != nil {
+			log.Fatal().Err(err).Str("table", p.configuration.Table).Msg("Failed to write data in database")
+		}
+	}
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	if p.stopTick != nil {
+		close(p.stopTick)
+		<-p.tickDone
+	}
+
+	p.lock.Lock()
+	err := p.flush(ctx)
+	p.lock.Unlock()
+	if err != nil {
+		log.Error().Err(err).Str("table", p.configuration.Table).Msg("Failed to write data in database")
+	}
+	if cerr := p.db.Close(); cerr != nil {
+		log.Warn().Err(cerr).Msg("Failed to close database connection")
+		if err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (p *Producer) createTable(ctx context.Context) {
+	stmt := p.dialect.createTable(p.table.name, p.table.columns, p.table.key)
+	if _, err := p.db.ExecContext(ctx, stmt); err != nil {
+		log.Fatal().Err(err).Str("statement", stmt).Msg("Failed to create table")
+	}
+}
+
+func (p *Producer) flushOnInterval() {
+	defer close(p.tickDone)
+
+	ticker := time.NewTicker(max(p.flushInterval/2, time.Nanosecond))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopTick:
+			return
+		case <-ticker.C:
+			p.lock.Lock()
+			if len(p.table.batch) > 0 && time.Since(p.oldest) >= p.flushInterval {
+				if err := p.flush(context.Background()); err != nil {
+					log.Error().Err(err).Str("table", p.configuration.Table).Msg("Failed to write data in database")
+				}
+			}
+			p.lock.Unlock()
+		}
+	}
+}
+
+// flush writes the current batch in a single transaction
+func (p *Producer) flush(ctx context.Context) error {
+	if len(p.table.batch) == 0 {
+		return nil
+	}
+	rows, err := p.table.rows()
+	if err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if p.configuration.Copy {
+		err = p.copyRows(ctx, tx, rows)
+	} else {
+		err = p.insertRows(ctx, tx, rows)
+	}
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (p *Producer) insertRows(ctx context.Context, tx *dbsql.Tx, rows [][]any) error {
+	// each statement stays within the placeholders limit of the database
+	if len(p.table.names) == 0 {
+		return errors.New("table has no columns")
+	}
+	perStatement := min(p.configuration.BatchSize, p.dialect.maxParams/len(p.table.names))
+	for len(rows) > 0 {
+		n := min(perStatement, len(rows))
+		args := make([]any, 0, n*len(p.table.names))
+		for _, row := range rows[:n] {
+			args = append(args, row...)
+		}
+		stmt := p.dialect.insert(p.table.name, p.table.names, n, p.table.key)
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return err
+		}
+		rows = rows[n:]
+	}
+	return nil
+}
+
+func (p *Producer) copyRows(ctx context.Context, tx *dbsql.Tx, rows [][]any) error {
+	copyIn := pq.CopyIn(p.configuration.Table, p.table.names...)
+	if schemaName, table, ok := strings.Cut(p.configuration.Table, "."); ok {
+		copyIn = pq.CopyInSchema(schemaName, table, p.table.names...)
+	}
+	stmt, err := tx.PrepareContext(ctx, copyIn)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err = stmt.ExecContext(ctx, row...); err != nil {
+			_ = stmt.Close()
+			return err
+		}
+	}
+	if _, err = stmt.ExecContext(ctx); err != nil {
+		_ = stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
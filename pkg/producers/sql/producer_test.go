@@ -0,0 +1,193 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	dbsql "database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/sql"
+)
+
+func query(t *testing.T, dsn string, q string) [][]any {
+	t.Helper()
+	db, err := dbsql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	columns, _ := rows.Columns()
+
+	var result [][]any
+	for rows.Next() {
+		row := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, row)
+	}
+	return result
+}
+
+func TestProducer(t *testing.T) {
+	testCases := []struct {
+		name    string
+		config  sql.Config
+		keys    []string
+		records []string
+		query   string
+		want    [][]any
+	}{
+		{
+			name:   "inferred table",
+			config: sql.Config{Table: "shoes", CreateTable: true, BatchSize: 2},
+			records: []string{
+				`{"name": "O'Brien", "size": 42, "price": 9.5, "sale": true, "tags": ["a", "b"]}`,
+				`{"name": "Air", "size": 40, "price": 10, "sale": false, "tags": []}`,
+				`{"name": "Max", "size": 41, "extra": 1}`,
+			},
+			query: `SELECT name, size, price, sale, tags FROM shoes ORDER BY size`,
+			want: [][]any{
+				{"Air", int64(40), float64(10), false, "[]"},
+				{"Max", int64(41), nil, nil, nil},
+				{"O'Brien", int64(42), 9.5, true, `["a","b"]`},
+			},
+		},
+		{
+			name:   "upsert on key",
+			config: sql.Config{Table: "users", CreateTable: true, KeyColumn: "id", BatchSize: 10},
+			keys:   []string{"1", "2", "1", "3"},
+			records: []string{
+				`{"name": "a", "v": 1}`,
+				`{"name": "b", "v": 1}`,
+				`{"name": "a", "v": 2}`,
+				`{"id": "1", "name": "a", "v": 3}`,
+			},
+			query: `SELECT id, name, v FROM users ORDER BY id`,
+			want: [][]any{
+				{"1", "a", int64(3)},
+				{"2", "b", int64(1)},
+			},
+		},
+		{
+			name:    "dotted field",
+			config:  sql.Config{Table: "main.places", CreateTable: true, KeyColumn: "geo.id"},
+			records: []string{`{"geo.id": "p1", "geo.lat": 45.5}`, `{"geo.id": "p1", "geo.lat": 46.5}`},
+			query:   `SELECT "geo.id", "geo.lat" FROM places`,
+			want:    [][]any{{"p1", 46.5}},
+		},
+		{
+			name:    "empty first record",
+			config:  sql.Config{Table: "events", CreateTable: true},
+			records: []string{`{}`, `{"name": "a"}`},
+			query:   `SELECT name FROM events`,
+			want:    [][]any{{"a"}},
+		},
+		{
+			name:   "default key",
+			config: sql.Config{Table: "users", CreateTable: true, KeyColumn: "id"},
+			keys:   []string{"null", "null"},
+			records: []string{
+				`{"id": "1", "name": "a"}`,
+				`{"name": "b"}`,
+			},
+			query: `SELECT id, name FROM users`,
+			want:  [][]any{{"1", "a"}},
+		},
+		{
+			name:   "schema columns",
+			config: sql.Config{Table: "main.orders", CreateTable: true, SchemaFile: "testdata/order.avsc"},
+			records: []string{
+				`{"id": "o1", "qty": 2, "paid": true, "lines": ["x"]}`,
+			},
+			query: `SELECT id, qty, price, paid, lines FROM orders`,
+			want: [][]any{
+				{"o1", int64(2), nil, true, `["x"]`},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			dsn := fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "jr.db"))
+			tc.config.Driver = "sqlite"
+			tc.config.DSN = dsn
+
+			p := &sql.Producer{}
+			p.InitializeFromConfig(tc.config)
+			for i, r := range tc.records {
+				var key []byte
+				if tc.keys != nil {
+					key = []byte(tc.keys[i])
+				}
+				p.Produce(ctx, key, []byte(r), nil)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			got := query(t, dsn, tc.query)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProducerFlushInterval(t *testing.T) {
+	for _, interval := range []string{"", "1ns"} {
+		t.Run("interval "+interval, func(t *testing.T) {
+			ctx := context.Background()
+			dsn := fmt.Sprintf("file:%s", filepath.Join(t.TempDir(), "jr.db"))
+			p := &sql.Producer{}
+			p.InitializeFromConfig(sql.Config{Driver: "sqlite", DSN: dsn, Table: "events", CreateTable: true, FlushInterval: interval})
+
+			// an incomplete batch is written without waiting for Close
+			p.Produce(ctx, nil, []byte(`{"name": "a"}`), nil)
+			deadline := time.Now().Add(5 * time.Second)
+			for len(query(t, dsn, `SELECT name FROM events`)) == 0 {
+				if time.Now().After(deadline) {
+					t.Fatal("batch not written before close")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
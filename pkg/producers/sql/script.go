@@ -0,0 +1,166 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultScriptDriver = "postgres"
+	DefaultTable        = "jr"
+)
+
+// ScriptProducer writes an SQL script instead of connecting to a database: a CREATE TABLE with
+// the columns of the schema or of the first record, followed by batched INSERT statements
+type ScriptProducer struct {
+	// Table, when set, is the table used when the configuration has none
+	Table string
+
+	configuration Config
+	dialect       *dialect
+	table         *table
+	file          *os.File
+	writer        *bufio.Writer
+}
+
+// Initialize reads the configuration file; without a file, the defaults are used
+func (p *ScriptProducer) Initialize(configFile string) {
+	config := Config{}
+	if configFile != "" {
+		file, err := os.ReadFile(configFile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to read configuration file")
+		}
+		if err = json.Unmarshal(file, &config); err != nil {
+			log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+		}
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *ScriptProducer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+	if p.configuration.Driver == "" {
+		p.configuration.Driver = DefaultScriptDriver
+	}
+	if p.configuration.Table == "" {
+		p.configuration.Table = p.Table
+	}
+	if p.configuration.Table == "" {
+		p.configuration.Table = DefaultTable
+	}
+	if p.configuration.ScriptFile == "" {
+		p.configuration.ScriptFile = p.configuration.Table + ".sql"
+	}
+	if p.configuration.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+
+	p.dialect, err = dialectOf(p.configuration.Driver)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Invalid SQL configuration")
+	}
+	if p.dialect.name == "sqlserver" && p.configuration.KeyColumn != "" {
+		log.Fatal().Msg("key_column upserts are not supported by sqlserver scripts")
+	}
+	if p.dialect.maxRows > 0 {
+		p.configuration.BatchSize = min(p.configuration.BatchSize, p.dialect.maxRows)
+	}
+
+	p.table, err = newTable(p.configuration.Table, p.configuration.KeyColumn, p.configuration.SchemaFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to derive columns from schema")
+	}
+
+	if err = os.MkdirAll(filepath.Dir(p.configuration.ScriptFile), 0755); err != nil {
+		log.Fatal().Err(err).Msg("Failed to create script directory")
+	}
+	p.file, err = os.Create(p.configuration.ScriptFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create script file")
+	}
+	p.writer = bufio.NewWriter(p.file)
+
+	if p.table.columns != nil {
+		p.createTable()
+	}
+}
+
+func (p *ScriptProducer) Produce(_ context.Context, k []byte, v []byte, _ any) {
+	r, err := p.table.parse(k, v)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to parse record")
+		return
+	}
+
+	if p.table.add(r) {
+		p.createTable()
+	}
+	if len(p.table.batch) >= p.configuration.BatchSize {
+		if err = p.flush(); err != nil {
+			log.Fatal().Err(err).Str("file", p.configuration.ScriptFile).Msg("Failed to write script")
+		}
+	}
+}
+
+func (p *ScriptProducer) Close(_ context.Context) error {
+	err := p.flush()
+	if err == nil {
+		err = p.writer.Flush()
+	}
+	if cerr := p.file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Error().Err(err).Str("file", p.configuration.ScriptFile).Msg("Failed to write script")
+	}
+	return err
+}
+
+func (p *ScriptProducer) createTable() {
+	stmt := p.dialect.createTable(p.table.name, p.table.columns, p.table.key)
+	if _, err := fmt.Fprintf(p.writer, "%s;\n\n", stmt); err != nil {
+		log.Fatal().Err(err).Str("file", p.configuration.ScriptFile).Msg("Failed to write script")
+	}
+}
+
+func (p *ScriptProducer) flush() error {
+	if len(p.table.batch) == 0 {
+		return nil
+	}
+	rows, err := p.table.rows()
+	if err != nil {
+		return err
+	}
+	stmt := p.dialect.insertValues(p.table.name, p.table.names, rows, p.table.key)
+	_, err = fmt.Fprintf(p.writer, "%s;\n\n", stmt)
+	return err
+}
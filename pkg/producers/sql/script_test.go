@@ -0,0 +1,164 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql_test
+
+import (
+	"context"
+	dbsql "database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jrnd-io/jr/pkg/producers/sql"
+)
+
+var scriptRecords = []string{
+	`{"id": 1, "name": "O'Brien", "score": 9.5, "active": true, "path": "C:\\jr"}`,
+	`{"id": 2, "name": "Zoë", "score": null, "active": false, "tags": ["x"]}`,
+}
+
+func TestScriptProducer(t *testing.T) {
+	testCases := []struct {
+		name   string
+		config sql.Config
+		want   string
+	}{
+		{
+			name:   "postgres",
+			config: sql.Config{Driver: "postgres"},
+			want: `CREATE TABLE IF NOT EXISTS "people" ("id" BIGINT, "name" TEXT, "score" DOUBLE PRECISION, "active" BOOLEAN, "path" TEXT);
+
+INSERT INTO "people" ("id", "name", "score", "active", "path") VALUES
+  (1, 'O''Brien', 9.5, TRUE, 'C:\jr'),
+  (2, 'Zoë', NULL, FALSE, NULL);
+
+`,
+		},
+		{
+			name:   "mysql with key",
+			config: sql.Config{Driver: "mysql", KeyColumn: "id", BatchSize: 1},
+			want: "CREATE TABLE IF NOT EXISTS `people` (`id` BIGINT NOT NULL, `name` TEXT, `score` DOUBLE, `active` BOOLEAN, `path` TEXT, PRIMARY KEY (`id`));\n\n" +
+				"INSERT INTO `people` (`id`, `name`, `score`, `active`, `path`) VALUES\n  (1, 'O''Brien', 9.5, TRUE, 'C:\\\\jr') ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `score` = VALUES(`score`), `active` = VALUES(`active`), `path` = VALUES(`path`);\n\n" +
+				"INSERT INTO `people` (`id`, `name`, `score`, `active`, `path`) VALUES\n  (2, 'Zoë', NULL, FALSE, NULL) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `score` = VALUES(`score`), `active` = VALUES(`active`), `path` = VALUES(`path`);\n\n",
+		},
+		{
+			name:   "sqlserver",
+			config: sql.Config{Driver: "sqlserver", Table: "dbo.people"},
+			want: `IF OBJECT_ID(N'dbo.people', N'U') IS NULL CREATE TABLE [dbo].[people] ([id] BIGINT, [name] NVARCHAR(MAX), [score] FLOAT, [active] BIT, [path] NVARCHAR(MAX));
+
+INSERT INTO [dbo].[people] ([id], [name], [score], [active], [path]) VALUES
+  (1, N'O''Brien', 9.5, 1, N'C:\jr'),
+  (2, N'Zoë', NULL, 0, NULL);
+
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			tc.config.ScriptFile = filepath.Join(t.TempDir(), "people.sql")
+
+			p := &sql.ScriptProducer{Table: "people"}
+			p.InitializeFromConfig(tc.config)
+			for _, r := range scriptRecords {
+				p.Produce(ctx, nil, []byte(r), nil)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(tc.config.ScriptFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestScriptProducerSQLite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	config := sql.Config{Driver: "sqlite", Table: "people", KeyColumn: "id", ScriptFile: filepath.Join(dir, "people.sql")}
+
+	p := &sql.ScriptProducer{}
+	p.InitializeFromConfig(config)
+	for _, r := range append(scriptRecords, `{"id": 1, "name": "O'Brien \"Jr\"", "score": 1}`) {
+		p.Produce(ctx, nil, []byte(r), nil)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := os.ReadFile(config.ScriptFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dsn := "file:" + filepath.Join(dir, "jr.db")
+	db, err := dbsql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err = db.Exec(string(script)); err != nil {
+		t.Fatalf("script failed: %v\n%s", err, script)
+	}
+
+	got := query(t, dsn, `SELECT id, name, score, active, path FROM people ORDER BY id`)
+	want := [][]any{
+		{int64(1), `O'Brien "Jr"`, float64(1), nil, nil},
+		{int64(2), "Zoë", nil, false, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestScriptProducerEmptyRecord(t *testing.T) {
+	ctx := context.Background()
+	config := sql.Config{Driver: "postgres", Table: "events", ScriptFile: filepath.Join(t.TempDir(), "events.sql")}
+
+	p := &sql.ScriptProducer{}
+	p.InitializeFromConfig(config)
+	p.Produce(ctx, nil, []byte(`{}`), nil)
+	p.Produce(ctx, nil, []byte(`{"name": "a"}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(config.ScriptFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `CREATE TABLE IF NOT EXISTS "events" ("name" TEXT);
+
+INSERT INTO "events" ("name") VALUES
+  ('a');
+
+`
+	if string(got) != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
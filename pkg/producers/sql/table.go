@@ -0,0 +1,149 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jrnd-io/jr/pkg/schema"
+	"github.com/rs/zerolog/log"
+)
+
+// table accumulates batches of records for a table, whose columns come from
+// the schema or from the first record
+type table struct {
+	name    string
+	key     string
+	columns []column
+	names   []string
+	unknown map[string]bool
+	batch   []record
+	keys    map[string]int
+}
+
+func newTable(name string, key string, schemaFile string) (*table, error) {
+	t := &table{name: name, key: key, unknown: make(map[string]bool), keys: make(map[string]int)}
+	if schemaFile != "" {
+		s, err := schema.LoadAvro(schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		columns, err := avroColumns(s)
+		if err != nil {
+			return nil, err
+		}
+		t.setColumns(columns)
+	}
+	return t, nil
+}
+
+func (t *table) setColumns(columns []column) {
+	t.columns = columns
+	if t.key != "" && !t.hasColumn(t.key) {
+		t.columns = append(t.columns, column{name: t.key, kind: textColumn})
+	}
+	t.names = make([]string, len(t.columns))
+	for i, c := range t.columns {
+		t.names[i] = c.name
+	}
+}
+
+func (t *table) hasColumn(name string) bool {
+	for _, c := range t.columns {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// parse decodes a record; records without the key field take the record key,
+// unless it is the default "null" key
+func (t *table) parse(k []byte, v []byte) (record, error) {
+	r, err := parseRecord(v)
+	if err != nil {
+		return r, err
+	}
+	if t.key != "" {
+		if _, ok := r.values[t.key]; !ok && len(k) > 0 && strings.ToLower(string(k)) != "null" {
+			r.names = append(r.names, t.key)
+			r.values[t.key] = string(k)
+		}
+		if r.values[t.key] == nil {
+			return r, fmt.Errorf("record has no %s field and no --key", t.key)
+		}
+	}
+	// the columns of the table can't be inferred from an empty record
+	if len(r.names) == 0 {
+		return r, errors.New("record has no fields")
+	}
+	return r, nil
+}
+
+// add appends a record to the batch, returning true when the columns were just inferred from it
+func (t *table) add(r record) bool {
+	inferred := false
+	if t.columns == nil {
+		t.setColumns(inferColumns(r))
+		inferred = true
+	}
+	for _, name := range r.names {
+		if !t.hasColumn(name) && !t.unknown[name] {
+			t.unknown[name] = true
+			log.Warn().Str("field", name).Str("table", t.name).Msg("Field has no column and is ignored")
+		}
+	}
+
+	// a statement can't upsert the same key twice, so the last record wins
+	if t.key != "" {
+		id := fmt.Sprint(r.values[t.key])
+		if i, ok := t.keys[id]; ok {
+			t.batch[i] = r
+			return inferred
+		}
+		t.keys[id] = len(t.batch)
+	}
+	t.batch = append(t.batch, r)
+	return inferred
+}
+
+// rows empties the batch, returning the column values of its records
+func (t *table) rows() ([][]any, error) {
+	batch := t.batch
+	t.batch = nil
+	clear(t.keys)
+
+	rows := make([][]any, len(batch))
+	for i, r := range batch {
+		row := make([]any, len(t.names))
+		for j, name := range t.names {
+			v, err := columnValue(r.values[name])
+			if err != nil {
+				return nil, fmt.Errorf("column %s: %w", name, err)
+			}
+			row[j] = v
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
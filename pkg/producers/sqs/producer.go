@@ -0,0 +1,211 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/jrnd-io/jr/pkg/producers/awscfg"
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+)
+
+// maxBatchBytes is the maximum size of the messages of a SendMessageBatch
+const maxBatchBytes = 256 * 1024
+
+type sqsAPI interface {
+	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+type message struct {
+	body            string
+	groupID         string
+	deduplicationID string
+	attributes      map[string]string
+}
+
+func (m message) size() int {
+	size := len(m.body)
+	for name, value := range m.attributes {
+		size += len(name) + len(value)
+	}
+	return size
+}
+
+// Producer sends records to an SQS queue with SendMessageBatch
+type Producer struct {
+	configuration   Config
+	client          sqsAPI
+	groupID         *record.Template
+	deduplicationID *record.Template
+	attributes      map[string]*record.Template
+	batcher         *batcher.Batcher[message]
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	config := Config{}
+	file, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read configuration file")
+	}
+	if err = json.Unmarshal(file, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse configuration parameters")
+	}
+
+	awsConfig, err := awscfg.Load(ctx, config.Config)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load default AWS config")
+	}
+	p.client = sqs.NewFromConfig(awsConfig)
+
+	p.initialize(ctx, config)
+}
+
+func (p *Producer) initialize(ctx context.Context, config Config) {
+	var err error
+	p.configuration = config
+	if config.BatchSize <= 0 || config.BatchSize > MaxBatchMessages {
+		p.configuration.BatchSize = MaxBatchMessages
+	}
+	if config.MaxRetries == 0 {
+		p.configuration.MaxRetries = DefaultRetries
+	} else if config.MaxRetries < 0 {
+		p.configuration.MaxRetries = 0
+	}
+
+	if config.QueueURL == "" {
+		if config.Queue == "" {
+			log.Fatal().Msg("Either queue_url or queue is mandatory")
+		}
+		out, err := p.client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(config.Queue)})
+		if err != nil {
+			log.Fatal().Err(err).Str("queue", config.Queue).Msg("Failed to get queue URL")
+		}
+		p.configuration.QueueURL = aws.ToString(out.QueueUrl)
+	}
+
+	p.groupID = newTemplate("message_group_id", config.MessageGroupID)
+	p.deduplicationID = newTemplate("deduplication_id", config.DeduplicationID)
+	p.attributes = make(map[string]*record.Template, len(config.Attributes))
+	for name, value := range config.Attributes {
+		p.attributes[name] = newTemplate(name, value)
+	}
+
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	p.batcher = batcher.New(p.sendMessageBatch, message.size, batcher.Config{
+		MaxItems:   p.configuration.BatchSize,
+		MaxBytes:   maxBatchBytes,
+		MaxRetries: p.configuration.MaxRetries,
+		Interval:   interval,
+	})
+}
+
+func newTemplate(name string, text string) *record.Template {
+	t, err := record.NewTemplate(name, text)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to parse %s template", name)
+	}
+	return t
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	m := message{
+		body:            data.V,
+		groupID:         p.groupID.ExecuteWith(data),
+		deduplicationID: p.deduplicationID.ExecuteWith(data),
+	}
+	if len(p.attributes) > 0 {
+		m.attributes = make(map[string]string, len(p.attributes))
+		for name, value := range p.attributes {
+			m.attributes[name] = value.ExecuteWith(data)
+		}
+	}
+	p.batcher.Add(ctx, m)
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if err != nil {
+		log.Error().Err(err).Str("queue", p.configuration.QueueURL).Msg("Failed to send messages")
+	}
+	return err
+}
+
+func (p *Producer) sendMessageBatch(ctx context.Context, messages []message) ([]message, error) {
+	entries := make([]types.SendMessageBatchRequestEntry, len(messages))
+	for i, m := range messages {
+		entries[i] = types.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(i)),
+			MessageBody: aws.String(m.body),
+		}
+		if m.groupID != "" {
+			entries[i].MessageGroupId = aws.String(m.groupID)
+		}
+		if m.deduplicationID != "" {
+			entries[i].MessageDeduplicationId = aws.String(m.deduplicationID)
+		}
+		if len(m.attributes) > 0 {
+			entries[i].MessageAttributes = make(map[string]types.MessageAttributeValue, len(m.attributes))
+			for name, value := range m.attributes {
+				entries[i].MessageAttributes[name] = types.MessageAttributeValue{
+					DataType:    aws.String("String"),
+					StringValue: aws.String(value),
+				}
+			}
+		}
+	}
+
+	out, err := p.client.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(p.configuration.QueueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []message
+	for _, f := range out.Failed {
+		i, _ := strconv.Atoi(aws.ToString(f.Id))
+		// errors of the sender, like a missing group ID, fail again
+		if f.SenderFault {
+			log.Error().Str("code", aws.ToString(f.Code)).Str("message", aws.ToString(f.Message)).Msg("Message rejected")
+			continue
+		}
+		failed = append(failed, messages[i])
+	}
+	return failed, nil
+}
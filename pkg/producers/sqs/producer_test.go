@@ -0,0 +1,136 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeSQS fails each message the first time it is sent, and rejects
+// the messages without a body as a sender fault
+type fakeSQS struct {
+	seen     map[string]bool
+	requests []int
+	received []types.SendMessageBatchRequestEntry
+}
+
+func (f *fakeSQS) GetQueueUrl(_ context.Context, in *sqs.GetQueueUrlInput, _ ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	return &sqs.GetQueueUrlOutput{QueueUrl: aws.String("https://sqs.local/000000000000/" + aws.ToString(in.QueueName))}, nil
+}
+
+func (f *fakeSQS) SendMessageBatch(_ context.Context, in *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.requests = append(f.requests, len(in.Entries))
+	out := &sqs.SendMessageBatchOutput{}
+	for _, e := range in.Entries {
+		body := aws.ToString(e.MessageBody)
+		if body == "" {
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{Id: e.Id, Code: aws.String("EmptyValue"), SenderFault: true})
+			continue
+		}
+		if !f.seen[body] {
+			f.seen[body] = true
+			out.Failed = append(out.Failed, types.BatchResultErrorEntry{Id: e.Id, Code: aws.String("InternalError")})
+			continue
+		}
+		f.received = append(f.received, e)
+		out.Successful = append(out.Successful, types.SendMessageBatchResultEntry{Id: e.Id})
+	}
+	return out, nil
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSQS{seen: make(map[string]bool)}
+	p := &Producer{client: fake}
+	p.initialize(ctx, Config{
+		Queue:           "orders.fifo",
+		MessageGroupID:  "{{.Value.customer}}",
+		DeduplicationID: "{{.K}}",
+		Attributes:      map[string]string{"source": "jr", "customer": "{{.Value.customer}}"},
+	})
+	p.batcher.Backoff = time.Millisecond
+
+	if want := "https://sqs.local/000000000000/orders.fifo"; p.configuration.QueueURL != want {
+		t.Errorf("got queue URL %q, want %q", p.configuration.QueueURL, want)
+	}
+
+	for i := 0; i < 12; i++ {
+		p.Produce(ctx, []byte(fmt.Sprintf("k%d", i)), []byte(fmt.Sprintf(`{"n":%d,"customer":"c%d"}`, i, i%2)), nil)
+	}
+	p.Produce(ctx, []byte("empty"), nil, nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// each batch is sent twice, since all the messages fail once
+	if want := []int{10, 10, 3, 2}; !reflect.DeepEqual(fake.requests, want) {
+		t.Errorf("got requests %v, want %v", fake.requests, want)
+	}
+	if len(fake.received) != 12 {
+		t.Fatalf("got %d messages, want 12", len(fake.received))
+	}
+	e := fake.received[3]
+	if got := aws.ToString(e.MessageBody); got != `{"n":3,"customer":"c1"}` {
+		t.Errorf("got body %q", got)
+	}
+	if got := aws.ToString(e.MessageGroupId); got != "c1" {
+		t.Errorf("got group ID %q, want c1", got)
+	}
+	if got := aws.ToString(e.MessageDeduplicationId); got != "k3" {
+		t.Errorf("got deduplication ID %q, want k3", got)
+	}
+	if got := aws.ToString(e.MessageAttributes["customer"].StringValue); got != "c1" {
+		t.Errorf("got customer attribute %q, want c1", got)
+	}
+	if got := aws.ToString(e.MessageAttributes["source"].DataType); got != "String" {
+		t.Errorf("got attribute data type %q, want String", got)
+	}
+}
+
+func TestProducerStandardQueue(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeSQS{seen: map[string]bool{"{}": true}}
+	p := &Producer{client: fake}
+	p.initialize(ctx, Config{QueueURL: "https://sqs.local/000000000000/jr", BatchSize: 20})
+
+	if p.configuration.BatchSize != MaxBatchMessages {
+		t.Errorf("got batch size %d, want %d", p.configuration.BatchSize, MaxBatchMessages)
+	}
+	if p.configuration.FlushInterval != DefaultFlushInterval {
+		t.Errorf("got flush interval %q, want %q", p.configuration.FlushInterval, DefaultFlushInterval)
+	}
+	p.Produce(ctx, []byte("k"), []byte("{}"), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	e := fake.received[0]
+	if e.MessageGroupId != nil || e.MessageDeduplicationId != nil || e.MessageAttributes != nil {
+		t.Errorf("got FIFO fields or attributes for a standard queue: %+v", e)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syslog
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	RFC5424 = "rfc5424"
+	RFC3164 = "rfc3164"
+
+	OctetCounting  = "octet-counting"
+	NonTransparent = "non-transparent"
+
+	DefaultTimeout = "10s"
+)
+
+type Config struct {
+	// Address of the collector, e.g. "localhost:514"
+	Address string `json:"address"`
+	// Network is "udp", the default, "tcp" or "tls"
+	Network string `json:"network"`
+	// Format is "rfc5424", the default, or "rfc3164"
+	Format string `json:"format"`
+	// Framing of TCP and TLS messages is "octet-counting", the default, or
+	// "non-transparent", terminating each message with a newline; with
+	// non-transparent framing the newlines in a message are replaced by spaces
+	Framing string `json:"framing"`
+	// The header fields are evaluated for each record. Facility and Severity
+	// are names, e.g. "local0" and "warning", or numbers
+	Facility string `json:"facility"`
+	Severity string `json:"severity"`
+	Hostname string `json:"hostname"`
+	AppName  string `json:"app_name"`
+	ProcID   string `json:"proc_id"`
+	MsgID    string `json:"msg_id"`
+	// Message is the MSG part, the whole record by default
+	Message string `json:"message"`
+	// Timeout is the deadline of the connection and of each write
+	Timeout string           `json:"timeout"`
+	TLS     tlsconfig.Config `json:"tls"`
+}
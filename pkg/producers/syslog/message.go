@@ -0,0 +1,137 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syslog
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11, "ntp": 12, "security": 13, "console": 14, "solaris-cron": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var severities = map[string]int{
+	"emerg": 0, "emergency": 0, "panic": 0,
+	"alert": 1,
+	"crit":  2, "critical": 2,
+	"err": 3, "error": 3,
+	"warning": 4, "warn": 4,
+	"notice": 5,
+	"info":   6, "informational": 6,
+	"debug": 7,
+}
+
+// parseLevel parses a facility or severity given by name or by number
+func parseLevel(kind string, names map[string]int, max int, value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if n, ok := names[value]; ok {
+		return n, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 || n > max {
+		return 0, fmt.Errorf("invalid %s %q", kind, value)
+	}
+	return n, nil
+}
+
+func priority(facility string, severity string) (int, error) {
+	f, err := parseLevel("facility", facilities, 23, facility)
+	if err != nil {
+		return 0, err
+	}
+	s, err := parseLevel("severity", severities, 7, severity)
+	if err != nil {
+		return 0, err
+	}
+	return f*8 + s, nil
+}
+
+type message struct {
+	priority  int
+	timestamp time.Time
+	hostname  string
+	appName   string
+	procID    string
+	msgID     string
+	msg       string
+}
+
+// headerField is a printable US-ASCII header field of at most max characters,
+// or the NILVALUE when empty
+func headerField(value string, max int) string {
+	if value == "" {
+		return "-"
+	}
+	b := make([]byte, 0, min(len(value), max))
+	for i := 0; i < len(value) && len(b) < max; i++ {
+		c := value[i]
+		if c < 33 || c > 126 {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// rfc5424 formats the message as <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG,
+// without structured data
+func (m message) rfc5424() []byte {
+	return fmt.Appendf(nil, "<%d>1 %s %s %s %s %s - %s",
+		m.priority,
+		m.timestamp.Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(m.hostname, 255),
+		headerField(m.appName, 48),
+		headerField(m.procID, 128),
+		headerField(m.msgID, 32),
+		m.msg)
+}
+
+// rfc3164 formats the message as <PRI>Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG
+func (m message) rfc3164() []byte {
+	tag := strings.Map(func(r rune) rune {
+		if r > 127 || !(r == '-' || r == '_' || r == '.' || r == '/' ||
+			('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') || ('0' <= r && r <= '9')) {
+			return -1
+		}
+		return r
+	}, m.appName)
+	if len(tag) > 32 {
+		tag = tag[:32]
+	}
+	if m.procID != "" {
+		tag += "[" + m.procID + "]"
+	}
+	hostname := m.hostname
+	if hostname == "" {
+		hostname = "-"
+	}
+	return fmt.Appendf(nil, "<%d>%s %s %s: %s",
+		m.priority,
+		m.timestamp.Format(time.Stamp),
+		headerField(hostname, 255),
+		tag,
+		m.msg)
+}
@@ -0,0 +1,96 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syslog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriority(t *testing.T) {
+	testCases := []struct {
+		name     string
+		facility string
+		severity string
+		want     int
+		err      bool
+	}{
+		{name: "names", facility: "local0", severity: "warning", want: 132},
+		{name: "aliases", facility: "AUTH", severity: "error", want: 35},
+		{name: "numbers", facility: "23", severity: "7", want: 191},
+		{name: "invalid facility", facility: "24", severity: "info", err: true},
+		{name: "invalid severity", facility: "user", severity: "8", err: true},
+		{name: "unknown name", facility: "user", severity: "loud", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := priority(tc.facility, tc.severity)
+			if (err != nil) != tc.err {
+				t.Fatalf("got error %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	timestamp := time.Date(2024, 3, 5, 14, 7, 9, 123456000, time.UTC)
+
+	testCases := []struct {
+		name    string
+		message message
+		rfc5424 string
+		rfc3164 string
+	}{
+		{
+			name:    "all fields",
+			message: message{priority: 134, timestamp: timestamp, hostname: "host1", appName: "shop", procID: "42", msgID: "ORDER", msg: `{"id":1}`},
+			rfc5424: `<134>1 2024-03-05T14:07:09.123456Z host1 shop 42 ORDER - {"id":1}`,
+			rfc3164: `<134>Mar  5 14:07:09 host1 shop[42]: {"id":1}`,
+		},
+		{
+			name:    "nil values",
+			message: message{priority: 14, timestamp: timestamp, msg: "hello"},
+			rfc5424: `<14>1 2024-03-05T14:07:09.123456Z - - - - - hello`,
+			rfc3164: `<14>Mar  5 14:07:09 - : hello`,
+		},
+		{
+			name:    "invalid characters",
+			message: message{priority: 14, timestamp: timestamp, hostname: "my host", appName: "my app:v1", msg: "hello"},
+			rfc5424: `<14>1 2024-03-05T14:07:09.123456Z my_host my_app:v1 - - - hello`,
+			rfc3164: `<14>Mar  5 14:07:09 my_host myappv1: hello`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := string(tc.message.rfc5424()); got != tc.rfc5424 {
+				t.Errorf("got RFC 5424 %q, want %q", got, tc.rfc5424)
+			}
+			if got := string(tc.message.rfc3164()); got != tc.rfc3164 {
+				t.Errorf("got RFC 3164 %q, want %q", got, tc.rfc3164)
+			}
+		})
+	}
+}
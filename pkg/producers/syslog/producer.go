@@ -0,0 +1,234 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syslog
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer sends records as syslog messages to a collector
+type Producer struct {
+	configuration Config
+	timeout       time.Duration
+	tlsConfig     *tls.Config
+	conn          net.Conn
+	now           func() time.Time
+
+	facility *record.Template
+	severity *record.Template
+	hostname *record.Template
+	appName  *record.Template
+	procID   *record.Template
+	msgID    *record.Template
+	message  *record.Template
+}
+
+func (p *Producer) Initialize(configFile string) {
+	cfgBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config file")
+	}
+
+	config := Config{}
+	if err := json.Unmarshal(cfgBytes, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal config")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+	if config.Address == "" {
+		log.Fatal().Msg("address is mandatory")
+	}
+
+	switch strings.ToLower(config.Network) {
+	case "", "udp":
+		p.configuration.Network = "udp"
+	case "tcp":
+		p.configuration.Network = "tcp"
+	case "tls":
+		p.configuration.Network = "tls"
+		if p.tlsConfig, err = config.TLS.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	default:
+		log.Fatal().Str("network", config.Network).Msg("network must be udp, tcp or tls")
+	}
+
+	switch strings.ToLower(config.Format) {
+	case "", RFC5424:
+		p.configuration.Format = RFC5424
+	case RFC3164:
+		p.configuration.Format = RFC3164
+	default:
+		log.Fatal().Str("format", config.Format).Msg("format must be rfc5424 or rfc3164")
+	}
+
+	switch strings.ToLower(config.Framing) {
+	case "", OctetCounting:
+		p.configuration.Framing = OctetCounting
+	case NonTransparent:
+		p.configuration.Framing = NonTransparent
+	default:
+		log.Fatal().Str("framing", config.Framing).Msg("framing must be octet-counting or non-transparent")
+	}
+
+	if config.Timeout == "" {
+		p.configuration.Timeout = DefaultTimeout
+	}
+	if p.timeout, err = time.ParseDuration(p.configuration.Timeout); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse timeout")
+	}
+
+	if config.Hostname == "" {
+		p.configuration.Hostname, _ = os.Hostname()
+	}
+	p.facility = newTemplate("facility", config.Facility, "user")
+	p.severity = newTemplate("severity", config.Severity, "info")
+	p.hostname = newTemplate("hostname", p.configuration.Hostname, "")
+	p.appName = newTemplate("app_name", config.AppName, "jr")
+	p.procID = newTemplate("proc_id", config.ProcID, "")
+	p.msgID = newTemplate("msg_id", config.MsgID, "")
+	p.message = newTemplate("message", config.Message, "{{.V}}")
+	p.now = time.Now
+
+	if err := p.connect(); err != nil {
+		log.Fatal().Err(err).Str("address", config.Address).Msg("Failed to connect")
+	}
+}
+
+func newTemplate(name string, text string, defaultText string) *record.Template {
+	if text == "" {
+		text = defaultText
+	}
+	t, err := record.NewTemplate(name, text)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to parse %s template", name)
+	}
+	return t
+}
+
+// connect dials the collector, replacing the connection only on success
+func (p *Producer) connect() error {
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: p.timeout}
+	if p.configuration.Network == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", p.configuration.Address, p.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(p.configuration.Network, p.configuration.Address)
+	}
+	if err != nil {
+		return err
+	}
+	p.conn = conn
+	return nil
+}
+
+func (p *Producer) Produce(_ context.Context, k []byte, v []byte, _ any) {
+	data := record.NewData(k, v)
+	pri, err := priority(p.facility.ExecuteWith(data), p.severity.ExecuteWith(data))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to build syslog message")
+		return
+	}
+
+	m := message{
+		priority:  pri,
+		timestamp: p.now(),
+		hostname:  p.hostname.ExecuteWith(data),
+		appName:   p.appName.ExecuteWith(data),
+		procID:    p.procID.ExecuteWith(data),
+		msgID:     p.msgID.ExecuteWith(data),
+		msg:       p.message.ExecuteWith(data),
+	}
+	var b []byte
+	if p.configuration.Format == RFC3164 {
+		b = m.rfc3164()
+	} else {
+		b = m.rfc5424()
+	}
+
+	if p.configuration.Network != "udp" {
+		if p.configuration.Framing == OctetCounting {
+			b = append([]byte(strconv.Itoa(len(b))+" "), b...)
+		} else {
+			// a newline in the message would end it: multi-line values become one line
+			b = bytes.ReplaceAll(bytes.ReplaceAll(b, []byte("\r\n"), []byte(" ")), []byte("\n"), []byte(" "))
+			b = append(b, '\n')
+		}
+	}
+
+	if err := p.write(b); err != nil {
+		log.Error().Err(err).Str("address", p.configuration.Address).Msg("Failed to send syslog message")
+	}
+}
+
+// write sends the message, connecting again once if the connection was closed
+// or a previous reconnection failed
+func (p *Producer) write(b []byte) error {
+	if p.conn != nil {
+		err := p.writeOnce(b)
+		if err == nil || p.configuration.Network == "udp" {
+			return err
+		}
+		p.conn.Close()
+		p.conn = nil
+	}
+	if err := p.connect(); err != nil {
+		return fmt.Errorf("failed to reconnect: %w", err)
+	}
+	return p.writeOnce(b)
+}
+
+func (p *Producer) writeOnce(b []byte) error {
+	if p.conn == nil {
+		return errors.New("not connected")
+	}
+	if err := p.conn.SetWriteDeadline(time.Now().Add(p.timeout)); err != nil {
+		return err
+	}
+	_, err := p.conn.Write(b)
+	return err
+}
+
+func (p *Producer) Close(_ context.Context) error {
+	if p.conn == nil {
+		return nil
+	}
+	return p.conn.Close()
+}
@@ -0,0 +1,232 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package syslog
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+)
+
+const orderRecord = `{"level":"err","app":"shop"}`
+
+func testCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// listen accepts one stream connection and returns everything it receives
+func listen(t *testing.T, listener net.Listener) <-chan string {
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer conn.Close()
+		b, _ := io.ReadAll(conn)
+		received <- string(b)
+	}()
+	return received
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+	now := func() time.Time { return time.Date(2024, 3, 5, 14, 7, 9, 0, time.UTC) }
+	want5424 := `<163>1 2024-03-05T14:07:09.000000Z host1 shop - - - ` + orderRecord
+	want3164 := `<163>Mar  5 14:07:09 host1 shop: ` + orderRecord
+
+	testCases := []struct {
+		name    string
+		network string
+		config  Config
+		want    string
+	}{
+		{
+			name:   "tcp octet counting",
+			config: Config{Network: "tcp"},
+			want:   "80 " + want5424 + "80 " + want5424,
+		},
+		{
+			name:   "tcp non-transparent rfc3164",
+			config: Config{Network: "tcp", Framing: NonTransparent, Format: RFC3164},
+			want:   want3164 + "\n" + want3164 + "\n",
+		},
+		{
+			name:   "tls",
+			config: Config{Network: "tls", TLS: tlsconfig.Config{InsecureSkipVerify: true}},
+			want:   "80 " + want5424 + "80 " + want5424,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var listener net.Listener
+			var err error
+			if tc.config.Network == "tls" {
+				listener, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{testCertificate(t)}})
+			} else {
+				listener, err = net.Listen("tcp", "127.0.0.1:0")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			received := listen(t, listener)
+
+			tc.config.Address = listener.Addr().String()
+			tc.config.Facility = "local4"
+			tc.config.Severity = "{{.Value.level}}"
+			tc.config.Hostname = "host1"
+			tc.config.AppName = "{{.Value.app}}"
+			p := &Producer{}
+			p.InitializeFromConfig(tc.config)
+			p.now = now
+			p.Produce(ctx, nil, []byte(orderRecord), nil)
+			p.Produce(ctx, nil, []byte(orderRecord), nil)
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := <-received; got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProducerUDP(t *testing.T) {
+	ctx := context.Background()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{
+		Address:  conn.LocalAddr().String(),
+		Severity: `{{or .Value.severity "info"}}`,
+		MsgID:    "{{.K}}",
+		Message:  "{{.Value.app}} failed",
+	})
+	p.Produce(ctx, []byte("k1"), []byte(orderRecord), nil)
+	p.Produce(ctx, []byte("k2"), []byte(`{"severity":"8"}`), nil)
+	p.Produce(ctx, []byte("k3"), []byte(orderRecord), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// the record with an invalid severity is dropped, each message is a datagram
+	for _, msgID := range []string{"k1", "k3"} {
+		buf := make([]byte, 1024)
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := string(buf[:n])
+		if !strings.HasPrefix(got, "<14>1 ") || !strings.HasSuffix(got, " jr - "+msgID+" - shop failed") {
+			t.Errorf("got %q", got)
+		}
+	}
+}
+
+func TestProducerNonTransparentNewlines(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := listen(t, listener)
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{Address: listener.Addr().String(), Network: "tcp", Framing: NonTransparent})
+	p.Produce(ctx, nil, []byte("{\n  \"level\": \"err\"\r\n}"), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; strings.Count(got, "\n") != 1 || !strings.HasSuffix(got, `{   "level": "err" }`+"\n") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestProducerReconnect(t *testing.T) {
+	ctx := context.Background()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+
+	p := &Producer{}
+	p.InitializeFromConfig(Config{Address: address, Network: "tcp", Framing: NonTransparent, Message: "{{.K}}"})
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+	listener.Close()
+	p.conn.Close()
+
+	// the collector is gone: the records are dropped without a connection
+	p.Produce(ctx, []byte("k1"), []byte(orderRecord), nil)
+	p.Produce(ctx, []byte("k2"), []byte(orderRecord), nil)
+
+	// the collector is back: the next record connects again
+	listener, err = net.Listen("tcp", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	received := listen(t, listener)
+	p.Produce(ctx, []byte("k3"), []byte(orderRecord), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; !strings.HasSuffix(got, " k3\n") || strings.Contains(got, "k1") {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import "github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+
+const (
+	InfluxDB   = "influxdb"
+	Prometheus = "prometheus"
+
+	DefaultInfluxDBURL   = "http://localhost:8086"
+	DefaultPrometheusURL = "http://localhost:9090/api/v1/write"
+	DefaultTimeout       = "10s"
+	DefaultBatchSize     = 1000
+	DefaultFlushInterval = "1s"
+	DefaultRetries       = 3
+)
+
+type InfluxDBConfig struct {
+	Org    string `json:"org"`
+	Bucket string `json:"bucket"`
+	Token  string `json:"token"`
+	// Precision of the written timestamps is "ns", the default, "us", "ms" or "s"
+	Precision string `json:"precision"`
+}
+
+type PrometheusConfig struct {
+	// TenantID is sent as X-Scope-OrgID, for multi-tenant Mimir and Cortex
+	TenantID    string `json:"tenant_id"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+	BearerToken string `json:"bearer_token"`
+}
+
+type Config struct {
+	// URL is the base URL of InfluxDB, e.g. "http://localhost:8086", or the
+	// remote write URL, e.g. "http://localhost:9009/api/v1/push" for Mimir
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Timeout string            `json:"timeout"`
+	TLS     tlsconfig.Config  `json:"tls"`
+
+	// Measurement is evaluated for each record, e.g. "sensor" or "{{.Value.type}}";
+	// Prometheus metrics are named measurement_field
+	Measurement string `json:"measurement"`
+	// Tags are the fields of the record written as tags, or labels
+	Tags []string `json:"tags"`
+	// Fields are the fields of the record written as values, all the other
+	// numeric and boolean fields by default
+	Fields []string `json:"fields"`
+	// Timestamp is the field with the time of the record, the current time by default:
+	// an RFC 3339 string or a number of TimestampUnit, "s" (the default), "ms", "us" or "ns"
+	Timestamp     string `json:"timestamp"`
+	TimestampUnit string `json:"timestamp_unit"`
+
+	// BatchSize records are written in each request, at least every FlushInterval, 1s by default;
+	// requests failed with 429 or 5xx are sent again up to MaxRetries times, -1 for none
+	BatchSize     int    `json:"batch_size"`
+	FlushInterval string `json:"flush_interval"`
+	MaxRetries    int    `json:"max_retries"`
+
+	InfluxDB   InfluxDBConfig   `json:"influxdb"`
+	Prometheus PrometheusConfig `json:"prometheus"`
+}
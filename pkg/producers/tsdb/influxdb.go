@@ -0,0 +1,120 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	measurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "\n", `\n`)
+	keyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	stringEscaper      = strings.NewReplacer(`"`, `\"`, `\`, `\\`)
+)
+
+var precisions = map[string]time.Duration{
+	"ns": time.Nanosecond,
+	"us": time.Microsecond,
+	"ms": time.Millisecond,
+	"s":  time.Second,
+}
+
+// influxDBWriter writes the points in line protocol to the v2 write API
+type influxDBWriter struct {
+	poster    *poster
+	precision time.Duration
+	headers   map[string]string
+}
+
+func newInfluxDBWriter(p *poster, config InfluxDBConfig) (*influxDBWriter, error) {
+	if config.Precision == "" {
+		config.Precision = "ns"
+	}
+	precision, ok := precisions[config.Precision]
+	if !ok {
+		return nil, fmt.Errorf("precision must be ns, us, ms or s, got %q", config.Precision)
+	}
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("bucket is mandatory")
+	}
+
+	params := url.Values{"bucket": {config.Bucket}, "precision": {config.Precision}}
+	if config.Org != "" {
+		params.Set("org", config.Org)
+	}
+	p.url = strings.TrimSuffix(p.url, "/") + "/api/v2/write?" + params.Encode()
+
+	headers := map[string]string{"Content-Type": "text/plain; charset=utf-8"}
+	if config.Token != "" {
+		headers["Authorization"] = "Token " + config.Token
+	}
+	return &influxDBWriter{poster: p, precision: precision, headers: headers}, nil
+}
+
+func (w *influxDBWriter) write(ctx context.Context, points []point) error {
+	var b strings.Builder
+	for _, p := range points {
+		appendLine(&b, p, w.precision)
+	}
+	return w.poster.post(ctx, []byte(b.String()), w.headers)
+}
+
+// appendLine appends a point in line protocol, e.g.
+// sensor,vehicle_id=1234 average_rpm=3120i,engine_temperature=212.5 1714557600000000000
+func appendLine(b *strings.Builder, p point, precision time.Duration) {
+	b.WriteString(measurementEscaper.Replace(p.measurement))
+	for _, t := range p.tags {
+		b.WriteByte(',')
+		b.WriteString(keyEscaper.Replace(t.key))
+		b.WriteByte('=')
+		b.WriteString(keyEscaper.Replace(t.value))
+	}
+	for i, f := range p.fields {
+		if i == 0 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(keyEscaper.Replace(f.key))
+		b.WriteByte('=')
+		switch v := f.value.(type) {
+		case int64:
+			b.WriteString(strconv.FormatInt(v, 10))
+			b.WriteByte('i')
+		case float64:
+			b.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			b.WriteString(strconv.FormatBool(v))
+		case string:
+			b.WriteByte('"')
+			b.WriteString(stringEscaper.Replace(v))
+			b.WriteByte('"')
+		}
+	}
+	b.WriteByte(' ')
+	b.WriteString(strconv.FormatInt(p.time.UnixNano()/int64(precision), 10))
+	b.WriteByte('\n')
+}
@@ -0,0 +1,185 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+)
+
+var timestampUnits = map[string]time.Duration{
+	"s":  time.Second,
+	"ms": time.Millisecond,
+	"us": time.Microsecond,
+	"ns": time.Nanosecond,
+}
+
+type tag struct {
+	key   string
+	value string
+}
+
+// field is a value of a point: a float64, an int64, a bool or a string
+type field struct {
+	key   string
+	value any
+}
+
+// point is a record mapped to a measurement, sorted tags, fields and a time
+type point struct {
+	measurement string
+	tags        []tag
+	fields      []field
+	time        time.Time
+}
+
+func (p point) size() int {
+	size := len(p.measurement) + 8
+	for _, t := range p.tags {
+		size += len(t.key) + len(t.value)
+	}
+	for _, f := range p.fields {
+		size += len(f.key) + 8
+		if s, ok := f.value.(string); ok {
+			size += len(s)
+		}
+	}
+	return size
+}
+
+// mapper maps the fields of the records to points
+type mapper struct {
+	measurement *record.Template
+	tags        []string
+	fields      []string
+	timestamp   string
+	unit        time.Duration
+	now         func() time.Time
+}
+
+func (m *mapper) point(k []byte, v []byte) (point, error) {
+	data := record.NewData(k, v)
+	if data.Value == nil {
+		return point{}, errors.New("record is not a JSON object")
+	}
+
+	p := point{measurement: m.measurement.ExecuteWith(data)}
+	if p.measurement == "" {
+		return p, errors.New("empty measurement")
+	}
+
+	for _, key := range m.tags {
+		if value, ok := tagValue(data.Value[key]); ok {
+			p.tags = append(p.tags, tag{key: key, value: value})
+		}
+	}
+	slices.SortFunc(p.tags, func(a, b tag) int {
+		return strings.Compare(a.key, b.key)
+	})
+
+	if len(m.fields) > 0 {
+		for _, key := range m.fields {
+			if value, ok := fieldValue(data.Value[key], true); ok {
+				p.fields = append(p.fields, field{key: key, value: value})
+			}
+		}
+	} else {
+		for key, v := range data.Value {
+			if key == m.timestamp || slices.Contains(m.tags, key) {
+				continue
+			}
+			if value, ok := fieldValue(v, false); ok {
+				p.fields = append(p.fields, field{key: key, value: value})
+			}
+		}
+		slices.SortFunc(p.fields, func(a, b field) int {
+			return strings.Compare(a.key, b.key)
+		})
+	}
+	if len(p.fields) == 0 {
+		return p, errors.New("no fields")
+	}
+
+	if m.timestamp == "" {
+		p.time = m.now()
+		return p, nil
+	}
+	var err error
+	p.time, err = m.parseTime(data.Value[m.timestamp])
+	return p, err
+}
+
+func (m *mapper) parseTime(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return time.Unix(0, 0).Add(time.Duration(n) * m.unit), nil
+		}
+		f, err := t.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, int64(f*float64(m.unit))), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, t)
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %v", v)
+}
+
+func tagValue(v any) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, t != ""
+	case json.Number:
+		return t.String(), true
+	case bool:
+		return fmt.Sprint(t), true
+	}
+	return "", false
+}
+
+// fieldValue converts a JSON value to the value of a field; strings are
+// values only when the field is configured
+func fieldValue(v any, text bool) (any, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n, true
+		}
+		f, err := t.Float64()
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, false
+		}
+		return f, true
+	case bool:
+		return t, true
+	case string:
+		return t, text
+	}
+	return nil, false
+}
@@ -0,0 +1,165 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/record"
+)
+
+func newTestMapper(t *testing.T, measurement string, tags []string, fields []string, timestamp string) *mapper {
+	t.Helper()
+	tpl, err := record.NewTemplate("measurement", measurement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &mapper{
+		measurement: tpl,
+		tags:        tags,
+		fields:      fields,
+		timestamp:   timestamp,
+		unit:        time.Second,
+		now:         func() time.Time { return time.Unix(100, 0) },
+	}
+}
+
+func TestPoint(t *testing.T) {
+	at := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name   string
+		mapper *mapper
+		value  string
+		want   point
+	}{
+		{
+			name:   "numeric fields",
+			mapper: newTestMapper(t, "sensor", []string{"vehicle_id"}, nil, ""),
+			value:  `{"vehicle_id":1234,"engine_temperature":212.5,"average_rpm":3120,"model":"T1","ok":true}`,
+			want: point{
+				measurement: "sensor",
+				tags:        []tag{{"vehicle_id", "1234"}},
+				fields:      []field{{"average_rpm", int64(3120)}, {"engine_temperature", 212.5}, {"ok", true}},
+				time:        time.Unix(100, 0),
+			},
+		},
+		{
+			name:   "configured fields",
+			mapper: newTestMapper(t, "{{.Value.kind}}", []string{"zone", "host"}, []string{"model", "load"}, "at"),
+			value:  `{"kind":"cpu","host":"a","zone":"eu","load":0.5,"model":"x86","at":"2024-05-01T10:00:00Z"}`,
+			want: point{
+				measurement: "cpu",
+				tags:        []tag{{"host", "a"}, {"zone", "eu"}},
+				fields:      []field{{"model", "x86"}, {"load", 0.5}},
+				time:        at,
+			},
+		},
+		{
+			name:   "epoch",
+			mapper: newTestMapper(t, "sensor", nil, nil, "ts"),
+			value:  `{"ts":1714557600,"rpm":1}`,
+			want: point{
+				measurement: "sensor",
+				fields:      []field{{"rpm", int64(1)}},
+				time:        at,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.mapper.point(nil, []byte(tc.value))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.time.Equal(tc.want.time) {
+				t.Errorf("got time %v, want %v", got.time, tc.want.time)
+			}
+			got.time = tc.want.time
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestPointErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		mapper *mapper
+		value  string
+	}{
+		{name: "not an object", mapper: newTestMapper(t, "m", nil, nil, ""), value: `[1]`},
+		{name: "empty measurement", mapper: newTestMapper(t, `{{or .Value.missing ""}}`, nil, nil, ""), value: `{"a":1}`},
+		{name: "no fields", mapper: newTestMapper(t, "m", []string{"a"}, nil, ""), value: `{"a":1,"b":"text"}`},
+		{name: "invalid timestamp", mapper: newTestMapper(t, "m", nil, nil, "ts"), value: `{"a":1,"ts":"yesterday"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.mapper.point(nil, []byte(tc.value)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestAppendLine(t *testing.T) {
+	p := point{
+		measurement: "disk usage",
+		tags:        []tag{{"path", "/var,log"}, {"host", "a=b"}},
+		fields:      []field{{"used", int64(42)}, {"ratio", 0.25}, {"full", false}, {"note", `say "hi"`}},
+		time:        time.Unix(1714557600, 123456789),
+	}
+
+	var b strings.Builder
+	appendLine(&b, p, time.Millisecond)
+	want := `disk\ usage,path=/var\,log,host=a\=b used=42i,ratio=0.25,full=false,note="say \"hi\"" 1714557600123` + "\n"
+	if b.String() != want {
+		t.Errorf("got %q, want %q", b.String(), want)
+	}
+}
+
+func TestToSeries(t *testing.T) {
+	points := []point{
+		{measurement: "sensor", tags: []tag{{"vehicle-id", "1"}}, fields: []field{{"rpm", int64(10)}, {"model", "x"}}, time: time.UnixMilli(2000)},
+		{measurement: "sensor", tags: []tag{{"vehicle-id", "1"}}, fields: []field{{"rpm", 20.5}}, time: time.UnixMilli(1000)},
+		{measurement: "sensor", tags: []tag{{"vehicle-id", "2"}}, fields: []field{{"ok", true}}, time: time.UnixMilli(1000)},
+	}
+
+	want := []*timeSeries{
+		{
+			labels:  []label{{"__name__", "sensor_rpm"}, {"vehicle_id", "1"}},
+			samples: []sample{{20.5, 1000}, {10, 2000}},
+		},
+		{
+			labels:  []label{{"__name__", "sensor_ok"}, {"vehicle_id", "2"}},
+			samples: []sample{{1, 1000}},
+		},
+	}
+	if got := toSeries(points); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
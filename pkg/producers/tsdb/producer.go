@@ -0,0 +1,172 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	"github.com/jrnd-io/jr/pkg/producers/record"
+	"github.com/rs/zerolog/log"
+)
+
+// Producer maps records to points and writes them to InfluxDB, or to
+// Prometheus compatible stores with remote write
+type Producer struct {
+	// Format is "influxdb" or "prometheus"
+	Format string
+
+	configuration Config
+	timeout       time.Duration
+	mapper        *mapper
+	poster        *poster
+	writer        writer
+	batcher       *batcher.Batcher[point]
+}
+
+func (p *Producer) Initialize(configFile string) {
+	cfgBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config file")
+	}
+
+	config := Config{}
+	if err := json.Unmarshal(cfgBytes, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal config")
+	}
+
+	p.InitializeFromConfig(config)
+}
+
+func (p *Producer) InitializeFromConfig(config Config) {
+	var err error
+	p.configuration = config
+
+	if config.Measurement == "" {
+		log.Fatal().Msg("measurement is mandatory")
+	}
+	measurement, err := record.NewTemplate("measurement", config.Measurement)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse measurement template")
+	}
+	if config.TimestampUnit == "" {
+		p.configuration.TimestampUnit = "s"
+	}
+	unit, ok := timestampUnits[p.configuration.TimestampUnit]
+	if !ok {
+		log.Fatal().Str("timestamp_unit", config.TimestampUnit).Msg("timestamp_unit must be s, ms, us or ns")
+	}
+	p.mapper = &mapper{
+		measurement: measurement,
+		tags:        config.Tags,
+		fields:      config.Fields,
+		timestamp:   config.Timestamp,
+		unit:        unit,
+		now:         time.Now,
+	}
+
+	if config.Timeout == "" {
+		p.configuration.Timeout = DefaultTimeout
+	}
+	if p.timeout, err = time.ParseDuration(p.configuration.Timeout); err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse timeout")
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS.IsSet() {
+		if tlsConfig, err = config.TLS.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+
+	switch p.Format {
+	case InfluxDB:
+		if p.configuration.URL == "" {
+			p.configuration.URL = DefaultInfluxDBURL
+		}
+		p.poster = newPoster(p.configuration.URL, config.Headers, tlsConfig, p.timeout)
+		if p.writer, err = newInfluxDBWriter(p.poster, config.InfluxDB); err != nil {
+			log.Fatal().Err(err).Msg("Invalid InfluxDB configuration")
+		}
+	case Prometheus:
+		if p.configuration.URL == "" {
+			p.configuration.URL = DefaultPrometheusURL
+		}
+		p.poster = newPoster(p.configuration.URL, config.Headers, tlsConfig, p.timeout)
+		p.writer = newPrometheusWriter(p.poster, config.Prometheus)
+	default:
+		log.Fatal().Str("format", p.Format).Msg("format must be influxdb or prometheus")
+	}
+
+	if config.BatchSize <= 0 {
+		p.configuration.BatchSize = DefaultBatchSize
+	}
+	if config.MaxRetries == 0 {
+		p.configuration.MaxRetries = DefaultRetries
+	} else if config.MaxRetries < 0 {
+		p.configuration.MaxRetries = 0
+	}
+	if config.FlushInterval == "" {
+		p.configuration.FlushInterval = DefaultFlushInterval
+	}
+	interval, err := time.ParseDuration(p.configuration.FlushInterval)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to parse flush_interval")
+	}
+	p.batcher = batcher.New(p.write, point.size, batcher.Config{
+		MaxItems:   p.configuration.BatchSize,
+		MaxRetries: p.configuration.MaxRetries,
+		Interval:   interval,
+	})
+}
+
+func (p *Producer) Produce(ctx context.Context, k []byte, v []byte, _ any) {
+	pt, err := p.mapper.point(k, v)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to map record to a point")
+		return
+	}
+	p.batcher.Add(ctx, pt)
+}
+
+func (p *Producer) write(ctx context.Context, points []point) ([]point, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	err := p.writer.write(ctx, points)
+	var retryable retryableError
+	if errors.As(err, &retryable) {
+		log.Warn().Err(err).Msg("Write failed, retrying")
+		return points, nil
+	}
+	return nil, err
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	p.poster.close()
+	return err
+}
@@ -0,0 +1,252 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// fakeServer records the requests, failing the first one with failFirst
+type fakeServer struct {
+	lock      sync.Mutex
+	failFirst int
+	queries   []string
+	headers   []http.Header
+	bodies    [][]byte
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failFirst != 0 {
+		w.WriteHeader(f.failFirst)
+		f.failFirst = 0
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	f.queries = append(f.queries, r.URL.RequestURI())
+	f.headers = append(f.headers, r.Header)
+	f.bodies = append(f.bodies, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestInfluxDBProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeServer{failFirst: http.StatusServiceUnavailable}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := &Producer{Format: InfluxDB}
+	p.InitializeFromConfig(Config{
+		URL:         server.URL,
+		Measurement: "sensor",
+		Tags:        []string{"vehicle_id"},
+		Timestamp:   "ts",
+		BatchSize:   2,
+		InfluxDB:    InfluxDBConfig{Org: "jr", Bucket: "fleet", Token: "secret", Precision: "s"},
+	})
+	p.batcher.Backoff = time.Millisecond
+
+	for i := 0; i < 3; i++ {
+		p.Produce(ctx, nil, []byte(fmt.Sprintf(`{"vehicle_id":%d,"average_rpm":%d,"ts":%d}`, i, 2000+i, 1714557600+i)), nil)
+	}
+	p.Produce(ctx, nil, []byte(`not json`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	wantBodies := []string{
+		"sensor,vehicle_id=0 average_rpm=2000i 1714557600\nsensor,vehicle_id=1 average_rpm=2001i 1714557601\n",
+		"sensor,vehicle_id=2 average_rpm=2002i 1714557602\n",
+	}
+	var bodies []string
+	for _, b := range fake.bodies {
+		bodies = append(bodies, string(b))
+	}
+	if !reflect.DeepEqual(bodies, wantBodies) {
+		t.Errorf("got %q, want %q", bodies, wantBodies)
+	}
+	if want := "/api/v2/write?bucket=fleet&org=jr&precision=s"; fake.queries[0] != want {
+		t.Errorf("got query %s, want %s", fake.queries[0], want)
+	}
+	if got := fake.headers[0].Get("Authorization"); got != "Token secret" {
+		t.Errorf("got Authorization %q", got)
+	}
+}
+
+func TestPrometheusProducer(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := &Producer{Format: Prometheus}
+	p.InitializeFromConfig(Config{
+		URL:           server.URL + "/api/v1/push",
+		Measurement:   "sensor",
+		Tags:          []string{"vehicle_id"},
+		Timestamp:     "ts",
+		TimestampUnit: "ms",
+		Prometheus:    PrometheusConfig{TenantID: "team-a", Username: "jr", Password: "secret"},
+	})
+
+	p.Produce(ctx, nil, []byte(`{"vehicle_id":7,"average_rpm":2000,"engine_temperature":180.5,"ts":1714557600000}`), nil)
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fake.bodies) != 1 {
+		t.Fatalf("got %d requests, want 1", len(fake.bodies))
+	}
+	headers := fake.headers[0]
+	if headers.Get("Content-Encoding") != "snappy" || headers.Get("X-Scope-OrgID") != "team-a" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+	if user, password, ok := (&http.Request{Header: headers}).BasicAuth(); !ok || user != "jr" || password != "secret" {
+		t.Errorf("got basic auth %s:%s", user, password)
+	}
+
+	data, err := snappy.Decode(nil, fake.bodies[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"__name__=sensor_average_rpm vehicle_id=7 2000@1714557600000",
+		"__name__=sensor_engine_temperature vehicle_id=7 180.5@1714557600000",
+	}
+	if got := decodeWriteRequest(t, data); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// decodeWriteRequest decodes each series of a WriteRequest as its labels and samples
+func decodeWriteRequest(t *testing.T, data []byte) []string {
+	t.Helper()
+	var series []string
+	for _, ts := range decodeMessages(t, data, 1) {
+		var s string
+		for _, l := range decodeMessages(t, ts, 1) {
+			fields := decodeFields(t, l)
+			s += fmt.Sprintf("%s=%s ", fields[1], fields[2])
+		}
+		for _, sm := range decodeMessages(t, ts, 2) {
+			fields := decodeFields(t, sm)
+			s += fmt.Sprintf("%v@%d", math.Float64frombits(fields[1].(uint64)), fields[2])
+		}
+		series = append(series, s)
+	}
+	return series
+}
+
+func decodeMessages(t *testing.T, data []byte, number protowire.Number) [][]byte {
+	t.Helper()
+	var messages [][]byte
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		data = data[n:]
+		if typ != protowire.BytesType {
+			n = protowire.ConsumeFieldValue(num, typ, data)
+			data = data[n:]
+			continue
+		}
+		v, n := protowire.ConsumeBytes(data)
+		if num == number {
+			messages = append(messages, v)
+		}
+		data = data[n:]
+	}
+	return messages
+}
+
+func decodeFields(t *testing.T, data []byte) map[protowire.Number]any {
+	t.Helper()
+	fields := make(map[protowire.Number]any)
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		data = data[n:]
+		switch typ {
+		case protowire.BytesType:
+			v, m := protowire.ConsumeString(data)
+			fields[num], n = v, m
+		case protowire.Fixed64Type:
+			v, m := protowire.ConsumeFixed64(data)
+			fields[num], n = v, m
+		case protowire.VarintType:
+			v, m := protowire.ConsumeVarint(data)
+			fields[num], n = v, m
+		default:
+			t.Fatalf("unexpected wire type %d", typ)
+		}
+		if n < 0 {
+			t.Fatal(protowire.ParseError(n))
+		}
+		data = data[n:]
+	}
+	return fields
+}
+
+func TestProducerDefaultFlushInterval(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeServer{}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	p := &Producer{Format: InfluxDB}
+	p.InitializeFromConfig(Config{
+		URL:         server.URL,
+		Measurement: "sensor",
+		InfluxDB:    InfluxDBConfig{Org: "jr", Bucket: "fleet", Token: "secret"},
+	})
+	p.Produce(ctx, nil, []byte(`{"average_rpm":2000}`), nil)
+
+	// an incomplete batch is written without waiting for Close
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		fake.lock.Lock()
+		n := len(fake.bodies)
+		fake.lock.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("batch not written before close")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tsdb
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/snappy"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// prometheusWriter sends the points as remote write 1.0 requests, one series
+// for each numeric or boolean field
+type prometheusWriter struct {
+	poster  *poster
+	headers map[string]string
+}
+
+func newPrometheusWriter(p *poster, config PrometheusConfig) *prometheusWriter {
+	headers := map[string]string{
+		"Content-Type":                      "application/x-protobuf",
+		"Content-Encoding":                  "snappy",
+		"X-Prometheus-Remote-Write-Version": "0.1.0",
+	}
+	switch {
+	case config.BearerToken != "":
+		headers["Authorization"] = "Bearer " + config.BearerToken
+	case config.Username != "":
+		headers["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(config.Username+":"+config.Password))
+	}
+	if config.TenantID != "" {
+		headers["X-Scope-OrgID"] = config.TenantID
+	}
+	return &prometheusWriter{poster: p, headers: headers}
+}
+
+func (w *prometheusWriter) write(ctx context.Context, points []point) error {
+	series := toSeries(points)
+	if len(series) == 0 {
+		return nil
+	}
+	return w.poster.post(ctx, snappy.Encode(nil, marshalWriteRequest(series)), w.headers)
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	value     float64
+	timestamp int64
+}
+
+type timeSeries struct {
+	labels  []label
+	samples []sample
+}
+
+// toSeries groups the samples of the points by series, each with its labels
+// sorted by name and its samples in time order, as remote write requires
+func toSeries(points []point) []*timeSeries {
+	var series []*timeSeries
+	byLabels := make(map[string]*timeSeries)
+	for _, p := range points {
+		for _, f := range p.fields {
+			var value float64
+			switch v := f.value.(type) {
+			case int64:
+				value = float64(v)
+			case float64:
+				value = v
+			case bool:
+				if v {
+					value = 1
+				}
+			default:
+				continue
+			}
+
+			labels := make([]label, 0, len(p.tags)+1)
+			labels = append(labels, label{name: "__name__", value: metricName(p.measurement + "_" + f.key)})
+			for _, t := range p.tags {
+				labels = append(labels, label{name: labelName(t.key), value: t.value})
+			}
+			slices.SortFunc(labels, func(a, b label) int {
+				return strings.Compare(a.name, b.name)
+			})
+
+			var key strings.Builder
+			for _, l := range labels {
+				key.WriteString(l.name)
+				key.WriteByte(0)
+				key.WriteString(l.value)
+				key.WriteByte(0)
+			}
+			s, ok := byLabels[key.String()]
+			if !ok {
+				s = &timeSeries{labels: labels}
+				byLabels[key.String()] = s
+				series = append(series, s)
+			}
+			s.samples = append(s.samples, sample{value: value, timestamp: p.time.UnixMilli()})
+		}
+	}
+	for _, s := range series {
+		slices.SortStableFunc(s.samples, func(a, b sample) int {
+			return cmp.Compare(a.timestamp, b.timestamp)
+		})
+	}
+	return series
+}
+
+// metricName replaces the characters not allowed in a metric name with underscores
+func metricName(name string) string {
+	return sanitize(name, true)
+}
+
+func labelName(name string) string {
+	return sanitize(name, false)
+}
+
+func sanitize(name string, colons bool) string {
+	b := []byte(name)
+	for i, c := range b {
+		valid := c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+			(colons && c == ':') || (i > 0 && c >= '0' && c <= '9')
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// marshalWriteRequest encodes the series as a prometheus.WriteRequest message:
+//
+//	message WriteRequest { repeated TimeSeries timeseries = 1; }
+//	message TimeSeries { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label { string name = 1; string value = 2; }
+//	message Sample { double value = 1; int64 timestamp = 2; }
+func marshalWriteRequest(series []*timeSeries) []byte {
+	var request []byte
+	for _, s := range series {
+		var ts []byte
+		for _, l := range s.labels {
+			var m []byte
+			m = protowire.AppendTag(m, 1, protowire.BytesType)
+			m = protowire.AppendString(m, l.name)
+			m = protowire.AppendTag(m, 2, protowire.BytesType)
+			m = protowire.AppendString(m, l.value)
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, m)
+		}
+		for _, sm := range s.samples {
+			var m []byte
+			m = protowire.AppendTag(m, 1, protowire.Fixed64Type)
+			m = protowire.AppendFixed64(m, math.Float64bits(sm.value))
+			m = protowire.AppendTag(m, 2, protowire.VarintType)
+			m = protowire.AppendVarint(m, uint64(sm.timestamp))
+			ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, m)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, ts)
+	}
+	return request
+}
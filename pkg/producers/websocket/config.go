@@ -0,0 +1,65 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package websocket
+
+import jrhttp "github.com/jrnd-io/jr/pkg/producers/http"
+
+const (
+	DefaultTimeout       = "10s"
+	DefaultMaxRetries    = 5
+	DefaultBackoff       = "500ms"
+	DefaultPongTimeout   = "10s"
+	DefaultFlushInterval = "1s"
+)
+
+type Reconnect struct {
+	// MaxRetries is the number of times a frame is sent again on a new
+	// connection, waiting Backoff before the first retry and doubling it after each one
+	MaxRetries int    `json:"max_retries"`
+	Backoff    string `json:"backoff"`
+}
+
+type Keepalive struct {
+	// PingInterval enables the pings; the connection is closed when a pong
+	// doesn't arrive within PongTimeout
+	PingInterval string `json:"ping_interval"`
+	PongTimeout  string `json:"pong_timeout"`
+}
+
+type Config struct {
+	URL string `json:"url"`
+	// MessageType is "text", the default, or "binary"
+	MessageType string `json:"message_type"`
+	// BatchSize greater than 1 sends up to BatchSize records in one frame, as a JSON array,
+	// at least every FlushInterval, 1s by default
+	BatchSize     int    `json:"batch_size"`
+	FlushInterval string `json:"flush_interval"`
+	// HandshakeTimeout and WriteTimeout are the deadlines of the handshake and of each frame
+	HandshakeTimeout string    `json:"handshake_timeout"`
+	WriteTimeout     string    `json:"write_timeout"`
+	Reconnect        Reconnect `json:"reconnect"`
+	Keepalive        Keepalive `json:"keepalive"`
+	// Headers, TLS and Authentication are the same as in the http producer,
+	// and are sent with the handshake
+	Headers        jrhttp.Headers        `json:"headers"`
+	TLS            jrhttp.TLS            `json:"tls"`
+	Authentication jrhttp.Authentication `json:"authentication"`
+}
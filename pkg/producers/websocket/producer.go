@@ -0,0 +1,271 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/jrnd-io/jr/pkg/producers/batcher"
+	jrhttp "github.com/jrnd-io/jr/pkg/producers/http"
+	"github.com/jrnd-io/jr/pkg/producers/tlsconfig"
+	"github.com/rs/zerolog/log"
+)
+
+// connection is a websocket connection with its reader, which handles the
+// control frames, and its pinger
+type connection struct {
+	ws   *websocket.Conn
+	done chan struct{}
+}
+
+func (c *connection) alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Producer sends records as frames on a websocket connection, connecting
+// again when the connection is lost
+type Producer struct {
+	configuration Config
+
+	dialer       *websocket.Dialer
+	header       http.Header
+	messageType  int
+	writeTimeout time.Duration
+	pingInterval time.Duration
+	pongTimeout  time.Duration
+
+	conn    *connection
+	batcher *batcher.Batcher[[]byte]
+}
+
+func (p *Producer) Initialize(ctx context.Context, configFile string) {
+	cfgBytes, err := os.ReadFile(configFile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to read config file")
+	}
+
+	config := Config{}
+	if err := json.Unmarshal(cfgBytes, &config); err != nil {
+		log.Fatal().Err(err).Msg("Failed to unmarshal config")
+	}
+
+	p.InitializeFromConfig(ctx, config)
+}
+
+func (p *Producer) InitializeFromConfig(ctx context.Context, config Config) {
+	p.configuration = config
+	if config.URL == "" {
+		log.Fatal().Msg("url is mandatory")
+	}
+
+	switch config.MessageType {
+	case "", "text":
+		p.messageType = websocket.TextMessage
+	case "binary":
+		p.messageType = websocket.BinaryMessage
+	default:
+		log.Fatal().Str("message_type", config.MessageType).Msg("message_type must be text or binary")
+	}
+
+	handshakeTimeout := parseDuration("handshake_timeout", config.HandshakeTimeout, DefaultTimeout)
+	p.writeTimeout = parseDuration("write_timeout", config.WriteTimeout, DefaultTimeout)
+	p.pingInterval = parseDuration("ping_interval", config.Keepalive.PingInterval, "0s")
+	p.pongTimeout = parseDuration("pong_timeout", config.Keepalive.PongTimeout, DefaultPongTimeout)
+
+	p.dialer = &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: handshakeTimeout,
+	}
+	tlsConfig := tlsconfig.Config{
+		InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+		CertFile:           config.TLS.CertFile,
+		KeyFile:            config.TLS.KeyFile,
+		RootCAFile:         config.TLS.RootCAFile,
+	}
+	if tlsConfig.IsSet() || tlsConfig.KeyFile != "" {
+		var err error
+		if p.dialer.TLSClientConfig, err = tlsConfig.Load(); err != nil {
+			log.Fatal().Err(err).Msg("Failed to load TLS configuration")
+		}
+	}
+
+	p.header = http.Header{}
+	for name, value := range config.Headers {
+		p.header.Set(name, value)
+	}
+	auth := config.Authentication
+	switch auth.Type {
+	case jrhttp.BasicAuth:
+		credentials := base64.StdEncoding.EncodeToString([]byte(auth.Basic.Username + ":" + auth.Basic.Password))
+		p.header.Set("Authorization", "Basic "+credentials)
+	case jrhttp.BearerAuth:
+		p.header.Set("Authorization", "Bearer "+auth.Bearer.Token)
+	case jrhttp.APIKeyAuth:
+		p.header.Set(auth.APIKey.Header, auth.APIKey.Value)
+	case jrhttp.DigestAuth:
+		log.Fatal().Msg("Digest authentication is not supported by the websocket producer")
+	default:
+
+	}
+
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+	maxRetries := config.Reconnect.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = DefaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+	p.batcher = batcher.New(p.send, func(v []byte) int { return len(v) }, batcher.Config{
+		MaxItems:   batchSize,
+		MaxRetries: maxRetries,
+		Interval:   parseDuration("flush_interval", config.FlushInterval, DefaultFlushInterval),
+	})
+	p.batcher.Backoff = parseDuration("backoff", config.Reconnect.Backoff, DefaultBackoff)
+
+	if err := p.connect(ctx); err != nil {
+		log.Fatal().Err(err).Str("url", config.URL).Msg("Failed to connect")
+	}
+}
+
+func parseDuration(name string, value string, defaultValue string) time.Duration {
+	if value == "" {
+		value = defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatal().Err(err).Msgf("Failed to parse %s", name)
+	}
+	return d
+}
+
+func (p *Producer) connect(ctx context.Context) error {
+	ws, resp, err := p.dialer.DialContext(ctx, p.configuration.URL, p.header)
+	if resp != nil && resp.Body != nil {
+		resp.Body.Close()
+	}
+	if err != nil {
+		return err
+	}
+
+	c := &connection{ws: ws, done: make(chan struct{})}
+	if p.pingInterval > 0 {
+		wait := p.pingInterval + p.pongTimeout
+		_ = ws.SetReadDeadline(time.Now().Add(wait))
+		ws.SetPongHandler(func(string) error {
+			return ws.SetReadDeadline(time.Now().Add(wait))
+		})
+		go p.ping(c)
+	}
+	go p.read(c)
+	p.conn = c
+	return nil
+}
+
+// read discards the messages of the server, so that the control frames are
+// handled, until the connection fails or is closed
+func (p *Producer) read(c *connection) {
+	defer close(c.done)
+	for {
+		if _, _, err := c.ws.ReadMessage(); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) && !errors.Is(err, websocket.ErrCloseSent) {
+				log.Warn().Err(err).Str("url", p.configuration.URL).Msg("Connection lost")
+			}
+			c.ws.Close()
+			return
+		}
+	}
+}
+
+func (p *Producer) ping(c *connection) {
+	ticker := time.NewTicker(p.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+			if err := c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(p.writeTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (p *Producer) Produce(ctx context.Context, _ []byte, v []byte, _ any) {
+	p.batcher.Add(ctx, bytes.Clone(v))
+}
+
+// send writes the records in one frame, connecting again if the connection
+// was lost; on errors all the records are returned to be sent again
+func (p *Producer) send(ctx context.Context, values [][]byte) ([][]byte, error) {
+	if p.conn == nil || !p.conn.alive() {
+		if err := p.connect(ctx); err != nil {
+			log.Warn().Err(err).Str("url", p.configuration.URL).Msg("Failed to reconnect")
+			return values, nil
+		}
+	}
+
+	frame := values[0]
+	if p.configuration.BatchSize > 1 {
+		frame = append([]byte{'['}, bytes.Join(values, []byte{','})...)
+		frame = append(frame, ']')
+	}
+
+	_ = p.conn.ws.SetWriteDeadline(time.Now().Add(p.writeTimeout))
+	if err := p.conn.ws.WriteMessage(p.messageType, frame); err != nil {
+		log.Warn().Err(err).Str("url", p.configuration.URL).Msg("Failed to send frame")
+		p.conn.ws.Close()
+		return values, nil
+	}
+	return nil, nil
+}
+
+func (p *Producer) Close(ctx context.Context) error {
+	err := p.batcher.Close(ctx)
+	if p.conn != nil && p.conn.alive() {
+		closeMessage := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		if err := p.conn.ws.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(p.writeTimeout)); err == nil {
+			// wait for the close frame of the server
+			select {
+			case <-p.conn.done:
+			case <-time.After(p.writeTimeout):
+			}
+		}
+		p.conn.ws.Close()
+	}
+	return err
+}
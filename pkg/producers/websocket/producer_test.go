@@ -0,0 +1,221 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package websocket
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	jrhttp "github.com/jrnd-io/jr/pkg/producers/http"
+)
+
+type frame struct {
+	messageType int
+	data        string
+}
+
+// server records the frames of each connection, closing each connection
+// after closeAfter frames when set
+type server struct {
+	closeAfter int
+
+	lock          sync.Mutex
+	authorization []string
+	connections   [][]frame
+	pings         int
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	upgrader := websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	conn.SetPingHandler(func(data string) error {
+		s.lock.Lock()
+		s.pings++
+		s.lock.Unlock()
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	s.lock.Lock()
+	s.authorization = append(s.authorization, r.Header.Get("Authorization"))
+	s.connections = append(s.connections, nil)
+	i := len(s.connections) - 1
+	s.lock.Unlock()
+
+	for n := 1; ; n++ {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		s.connections[i] = append(s.connections[i], frame{messageType, string(data)})
+		s.lock.Unlock()
+		if n == s.closeAfter {
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		}
+	}
+}
+
+func (s *server) frames() [][]frame {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.connections
+}
+
+func startServer(t *testing.T, s *server) string {
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestProducer(t *testing.T) {
+	ctx := context.Background()
+
+	testCases := []struct {
+		name   string
+		config Config
+		values []string
+		want   []frame
+	}{
+		{
+			name:   "text",
+			config: Config{},
+			values: []string{`{"n":1}`, `{"n":2}`},
+			want:   []frame{{websocket.TextMessage, `{"n":1}`}, {websocket.TextMessage, `{"n":2}`}},
+		},
+		{
+			name:   "binary",
+			config: Config{MessageType: "binary"},
+			values: []string{`{"n":1}`},
+			want:   []frame{{websocket.BinaryMessage, `{"n":1}`}},
+		},
+		{
+			name:   "json array",
+			config: Config{BatchSize: 2},
+			values: []string{`{"n":1}`, `{"n":2}`, `{"n":3}`},
+			want:   []frame{{websocket.TextMessage, `[{"n":1},{"n":2}]`}, {websocket.TextMessage, `[{"n":3}]`}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &server{}
+			tc.config.URL = startServer(t, s)
+			tc.config.Authentication = jrhttp.Authentication{Type: jrhttp.BearerAuth, Bearer: jrhttp.Bearer{Token: "secret"}}
+
+			p := &Producer{}
+			p.InitializeFromConfig(ctx, tc.config)
+			for _, v := range tc.values {
+				p.Produce(ctx, nil, []byte(v), nil)
+			}
+			if err := p.Close(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.frames(); len(got) != 1 || !reflect.DeepEqual(got[0], tc.want) {
+				t.Errorf("got frames %v, want %v", got, tc.want)
+			}
+			if want := []string{"Bearer secret"}; !reflect.DeepEqual(s.authorization, want) {
+				t.Errorf("got authorization %v, want %v", s.authorization, want)
+			}
+		})
+	}
+}
+
+func TestProducerFlushInterval(t *testing.T) {
+	ctx := context.Background()
+	s := &server{}
+	url := startServer(t, s)
+
+	// an incomplete batch is sent after the default flush_interval, before Close
+	p := &Producer{}
+	p.InitializeFromConfig(ctx, Config{URL: url, BatchSize: 2})
+	p.Produce(ctx, nil, []byte(`{"n":1}`), nil)
+	want := [][]frame{{{websocket.TextMessage, `[{"n":1}]`}}}
+	for deadline := time.Now().Add(5 * time.Second); !reflect.DeepEqual(s.frames(), want); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("got frames %v, want %v", s.frames(), want)
+		}
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProducerReconnects(t *testing.T) {
+	ctx := context.Background()
+	s := &server{closeAfter: 1}
+	url := startServer(t, s)
+
+	p := &Producer{}
+	p.InitializeFromConfig(ctx, Config{URL: url, Reconnect: Reconnect{Backoff: "1ms"}})
+	for i, v := range []string{"a", "b"} {
+		conn := p.conn
+		p.Produce(ctx, nil, []byte(v), nil)
+		// wait for the close frame of the server
+		select {
+		case <-conn.done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("connection %d not closed", i)
+		}
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := [][]frame{{{websocket.TextMessage, "a"}}, {{websocket.TextMessage, "b"}}}
+	if got := s.frames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got frames %v, want %v", got, want)
+	}
+}
+
+func TestProducerPings(t *testing.T) {
+	ctx := context.Background()
+	s := &server{}
+	url := startServer(t, s)
+
+	p := &Producer{}
+	p.InitializeFromConfig(ctx, Config{URL: url, Keepalive: Keepalive{PingInterval: "10ms", PongTimeout: "1s"}})
+	time.Sleep(100 * time.Millisecond)
+	if !p.conn.alive() {
+		t.Error("connection closed with pongs")
+	}
+	if err := p.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.pings == 0 {
+		t.Error("no pings received")
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package schema maps the JSON values rendered by templates to the types described by Avro schemas.
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/hamba/avro/v2"
+)
+
+// LoadAvro parses an .avsc file
+func LoadAvro(path string) (avro.Schema, error) {
+	return avro.ParseFiles(path)
+}
+
+// AvroNative decodes a rendered JSON value and converts it to the generic representation
+// the Avro encoder expects for the given schema: records and maps become map[string]any,
+// arrays []any, and unions a single entry map keyed by the name of the matching branch.
+// Errors report the path of the offending field.
+func AvroNative(s avro.Schema, data []byte) (any, error) {
+	var v any
+	if err := DecodeJSON(data, &v); err != nil {
+		return nil, err
+	}
+	return toAvro(s, v, "")
+}
+
+func toAvro(s avro.Schema, v any, path string) (any, error) {
+	switch s.Type() {
+	case avro.Ref:
+		return toAvro(s.(*avro.RefSchema).Schema(), v, path)
+
+	case avro.Record:
+		return toAvroRecord(s.(*avro.RecordSchema), v, path)
+
+	case avro.Union:
+		return toAvroUnion(s.(*avro.UnionSchema), v, path)
+
+	case avro.Array:
+		items, ok := v.([]any)
+		if !ok {
+			return nil, typeError(path, s, v)
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			converted, err := toAvro(s.(*avro.ArraySchema).Items(), item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+
+	case avro.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, typeError(path, s, v)
+		}
+		out := make(map[string]any, len(m))
+		for key, value := range m {
+			converted, err := toAvro(s.(*avro.MapSchema).Values(), value, joinPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			out[key] = converted
+		}
+		return out, nil
+
+	case avro.Enum:
+		symbol, ok := v.(string)
+		if !ok {
+			return nil, typeError(path, s, v)
+		}
+		if symbolIndex(s.(*avro.EnumSchema), symbol) < 0 {
+			return nil, fmt.Errorf("%s: %q is not a symbol of enum %s", fieldName(path), symbol, s.(*avro.EnumSchema).FullName())
+		}
+		return symbol, nil
+
+	case avro.Fixed:
+		fixed := s.(*avro.FixedSchema)
+		if isDecimal(fixed) {
+			return toDecimal(v, path, s)
+		}
+		str, ok := v.(string)
+		if !ok || len(str) != fixed.Size() {
+			return nil, fmt.Errorf("%s: expected a string of %d bytes for fixed %s", fieldName(path), fixed.Size(), fixed.FullName())
+		}
+		array := reflect.New(reflect.ArrayOf(fixed.Size(), reflect.TypeOf(byte(0)))).Elem()
+		reflect.Copy(array, reflect.ValueOf([]byte(str)))
+		return array.Interface(), nil
+
+	case avro.Null:
+		if v != nil {
+			return nil, typeError(path, s, v)
+		}
+		return nil, nil
+
+	case avro.Boolean:
+		b, ok := v.(bool)
+		if !ok {
+			return nil, typeError(path, s, v)
+		}
+		return b, nil
+
+	case avro.String:
+		str, ok := v.(string)
+		if !ok {
+			return nil, typeError(path, s, v)
+		}
+		return str, nil
+
+	case avro.Bytes:
+		if isDecimal(s) {
+			return toDecimal(v, path, s)
+		}
+		str, ok := v.(string)
+		if !ok {
+			return nil, typeError(path, s, v)
+		}
+		return []byte(str), nil
+
+	case avro.Int:
+		if str, ok := v.(string); ok && logicalType(s) == avro.Date {
+			t, err := time.Parse(time.DateOnly, str)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fieldName(path), err)
+			}
+			return t, nil
+		}
+		i, err := toInt(v, path, s)
+		if err != nil {
+			return nil, err
+		}
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			return nil, fmt.Errorf("%s: %d overflows Avro int", fieldName(path), i)
+		}
+		return int(i), nil
+
+	case avro.Long:
+		lt := logicalType(s)
+		if str, ok := v.(string); ok && (lt == avro.TimestampMillis || lt == avro.TimestampMicros ||
+			lt == avro.LocalTimestampMillis || lt == avro.LocalTimestampMicros) {
+			t, err := ParseTimestamp(str)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", fieldName(path), err)
+			}
+			return t, nil
+		}
+		return toInt(v, path, s)
+
+	case avro.Float:
+		f, err := toFloat(v, path, s)
+		return float32(f), err
+
+	case avro.Double:
+		return toFloat(v, path, s)
+	}
+
+	return nil, fmt.Errorf("%s: unsupported Avro type %s", fieldName(path), s.Type())
+}
+
+func toAvroRecord(rs *avro.RecordSchema, v any, path string) (any, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, typeError(path, rs, v)
+	}
+	out := make(map[string]any, len(rs.Fields()))
+	for _, f := range rs.Fields() {
+		fieldPath := joinPath(path, f.Name())
+		value, found := m[f.Name()]
+		if !found {
+			// the encoder writes the default value of missing fields
+			if f.HasDefault() {
+				continue
+			}
+			if u, ok := f.Type().(*avro.UnionSchema); !ok || !u.Nullable() {
+				return nil, fmt.Errorf("%s: missing required field", fieldName(fieldPath))
+			}
+		}
+		converted, err := toAvro(f.Type(), value, fieldPath)
+		if err != nil {
+			return nil, err
+		}
+		out[f.Name()] = converted
+	}
+	return out, nil
+}
+
+func toAvroUnion(us *avro.UnionSchema, v any, path string) (any, error) {
+	var firstErr error
+	for _, branch := range us.Types() {
+		if branch.Type() == avro.Null {
+			if v == nil {
+				return map[string]any{}, nil
+			}
+			continue
+		}
+		converted, err := toAvro(branch, v, path)
+		if err == nil {
+			return map[string]any{unionBranchName(branch): converted}, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		return nil, typeError(path, us, v)
+	}
+	return nil, firstErr
+}
+
+func toInt(v any, path string, s avro.Schema) (int64, error) {
+	var n json.Number
+	switch value := v.(type) {
+	case json.Number:
+		n = value
+	case string:
+		n = json.Number(value)
+	default:
+		return 0, typeError(path, s, v)
+	}
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	f, err := n.Float64()
+	if err != nil || f != math.Trunc(f) {
+		return 0, typeError(path, s, v)
+	}
+	return int64(f), nil
+}
+
+func toFloat(v any, path string, s avro.Schema) (float64, error) {
+	switch value := v.(type) {
+	case json.Number:
+		return value.Float64()
+	case string:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, typeError(path, s, v)
+		}
+		return f, nil
+	default:
+		return 0, typeError(path, s, v)
+	}
+}
+
+func toDecimal(v any, path string, s avro.Schema) (*big.Rat, error) {
+	var str string
+	switch value := v.(type) {
+	case json.Number:
+		str = value.String()
+	case string:
+		str = value
+	default:
+		return nil, typeError(path, s, v)
+	}
+	r, ok := new(big.Rat).SetString(str)
+	if !ok {
+		return nil, typeError(path, s, v)
+	}
+	return r, nil
+}
+
+// ParseTimestamp parses the timestamp formats rendered by templates: RFC 3339, "2006-01-02 15:04:05" and dates
+func ParseTimestamp(str string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+		if t, err := time.Parse(layout, str); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a timestamp", str)
+}
+
+func symbolIndex(s *avro.EnumSchema, symbol string) int {
+	for i, sym := range s.Symbols() {
+		if sym == symbol {
+			return i
+		}
+	}
+	return -1
+}
+
+func logicalType(s avro.Schema) avro.LogicalType {
+	if lts, ok := s.(avro.LogicalTypeSchema); ok && lts.Logical() != nil {
+		return lts.Logical().Type()
+	}
+	return ""
+}
+
+func isDecimal(s avro.Schema) bool {
+	return logicalType(s) == avro.Decimal
+}
+
+// unionBranchName is the name the Avro encoder uses to select a union branch
+func unionBranchName(s avro.Schema) string {
+	if ref, ok := s.(*avro.RefSchema); ok {
+		s = ref.Schema()
+	}
+	if named, ok := s.(avro.NamedSchema); ok {
+		return named.FullName()
+	}
+	name := string(s.Type())
+	if lt := logicalType(s); lt != "" {
+		name += "." + string(lt)
+	}
+	return name
+}
+
+func typeError(path string, s avro.Schema, v any) error {
+	return fmt.Errorf("%s: expected %s, got %s", fieldName(path), s.Type(), jsonType(v))
+}
+
+func jsonType(v any) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number " + fmt.Sprint(value)
+	case string:
+		return strconv.Quote(value)
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func fieldName(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return fmt.Sprintf("field %q", path)
+}
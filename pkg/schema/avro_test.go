@@ -0,0 +1,106 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schema_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hamba/avro/v2"
+	"github.com/jrnd-io/jr/pkg/schema"
+)
+
+const orderSchema = `{
+  "type": "record",
+  "name": "Order",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "status", "type": {"type": "enum", "name": "Status", "symbols": ["NEW", "SHIPPED"]}},
+    {"name": "created_at", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "note", "type": ["null", "string"]},
+    {"name": "lines", "type": {"type": "array", "items": {
+      "type": "record",
+      "name": "Line",
+      "fields": [
+        {"name": "sku", "type": "string"},
+        {"name": "qty", "type": "int"}
+      ]
+    }}},
+    {"name": "tags", "type": {"type": "map", "values": "string"}, "default": {}}
+  ]
+}`
+
+func TestAvroNative(t *testing.T) {
+
+	s := avro.MustParse(orderSchema)
+
+	testCases := []struct {
+		name    string
+		value   string
+		wantErr string
+	}{
+		{
+			name:  "valid",
+			value: `{"id": "o-1", "status": "NEW", "created_at": 1714557600000, "note": "x", "lines": [{"sku": "A", "qty": 1}]}`,
+		},
+		{
+			name:  "timestamp_string_and_null_union",
+			value: `{"id": "o-1", "status": "NEW", "created_at": "2024-05-01T10:00:00Z", "note": null, "lines": []}`,
+		},
+		{
+			name:  "missing_nullable_and_default",
+			value: `{"id": "o-1", "status": "SHIPPED", "created_at": 1, "lines": []}`,
+		},
+		{
+			name:    "missing_required",
+			value:   `{"status": "NEW", "created_at": 1, "lines": []}`,
+			wantErr: `field "id": missing required field`,
+		},
+		{
+			name:    "unknown_symbol",
+			value:   `{"id": "o-1", "status": "LOST", "created_at": 1, "lines": []}`,
+			wantErr: `field "status": "LOST" is not a symbol`,
+		},
+		{
+			name:    "nested_type",
+			value:   `{"id": "o-1", "status": "NEW", "created_at": 1, "lines": [{"sku": "A", "qty": 1}, {"sku": "B", "qty": 1.5}]}`,
+			wantErr: `field "lines[1].qty": expected int, got number 1.5`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := schema.AvroNative(s, []byte(tc.value))
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := avro.Marshal(s, v); err != nil {
+				t.Errorf("cannot encode converted value: %v", err)
+			}
+		})
+	}
+}
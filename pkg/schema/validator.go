@@ -0,0 +1,129 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hamba/avro/v2"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+const (
+	ValidateJSON       = "json"
+	ValidateAvro       = "avro"
+	ValidateJSONSchema = "json-schema"
+)
+
+// Validator checks a rendered value, returning an error describing the offending field
+type Validator interface {
+	Validate(data []byte) error
+}
+
+// NewValidator creates a validator of the given kind: json only checks well-formedness,
+// avro and json-schema check the value against the schema in schemaFile
+func NewValidator(kind string, schemaFile string) (Validator, error) {
+	switch kind {
+	case ValidateJSON:
+		return jsonValidator{}, nil
+	case ValidateAvro:
+		if schemaFile == "" {
+			return nil, errors.New("avro validation requires a schema file")
+		}
+		s, err := LoadAvro(schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		return avroValidator{schema: s}, nil
+	case ValidateJSONSchema:
+		if schemaFile == "" {
+			return nil, errors.New("json-schema validation requires a schema file")
+		}
+		s, err := jsonschema.NewCompiler().Compile(schemaFile)
+		if err != nil {
+			return nil, err
+		}
+		return jsonSchemaValidator{schema: s}, nil
+	}
+	return nil, fmt.Errorf("unknown validation %q, must be one of %s, %s, %s", kind, ValidateJSON, ValidateAvro, ValidateJSONSchema)
+}
+
+type jsonValidator struct{}
+
+func (jsonValidator) Validate(data []byte) error {
+	var v any
+	return DecodeJSON(data, &v)
+}
+
+type avroValidator struct {
+	schema avro.Schema
+}
+
+func (a avroValidator) Validate(data []byte) error {
+	_, err := AvroNative(a.schema, data)
+	return err
+}
+
+type jsonSchemaValidator struct {
+	schema *jsonschema.Schema
+}
+
+func (j jsonSchemaValidator) Validate(data []byte) error {
+	var v any
+	if err := DecodeJSON(data, &v); err != nil {
+		return err
+	}
+	err := j.schema.Validate(v)
+
+	var ve *jsonschema.ValidationError
+	if errors.As(err, &ve) {
+		// the innermost cause names the offending field
+		for len(ve.Causes) > 0 {
+			ve = ve.Causes[0]
+		}
+		field := strings.TrimPrefix(strings.ReplaceAll(ve.InstanceLocation, "/", "."), ".")
+		return fmt.Errorf("%s: %s", fieldName(field), ve.Message)
+	}
+	return err
+}
+
+// DecodeJSON decodes a single JSON value, keeping numbers as json.Number and reporting the offset of syntax errors
+func DecodeJSON(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(v)
+
+	var se *json.SyntaxError
+	if errors.As(err, &se) {
+		return fmt.Errorf("invalid JSON at offset %d: %w", se.Offset, err)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if dec.More() {
+		return fmt.Errorf("invalid JSON at offset %d: unexpected data after the value", dec.InputOffset())
+	}
+	return nil
+}
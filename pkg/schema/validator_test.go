@@ -0,0 +1,98 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package schema_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jrnd-io/jr/pkg/schema"
+)
+
+const orderJSONSchema = `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "string"},
+    "lines": {"type": "array", "items": {
+      "type": "object",
+      "properties": {"qty": {"type": "integer", "minimum": 1}},
+      "required": ["qty"]
+    }}
+  },
+  "required": ["id"]
+}`
+
+func TestValidator(t *testing.T) {
+
+	dir := t.TempDir()
+	avsc := filepath.Join(dir, "order.avsc")
+	jsonSchema := filepath.Join(dir, "order.schema.json")
+	if err := os.WriteFile(avsc, []byte(orderSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonSchema, []byte(orderJSONSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name    string
+		kind    string
+		schema  string
+		value   string
+		wantErr string
+	}{
+		{name: "json_valid", kind: schema.ValidateJSON, value: `{"id": "o-1"}`},
+		{name: "json_missing_comma", kind: schema.ValidateJSON, value: `{"id": "o-1" "qty": 1}`, wantErr: "invalid JSON at offset 14"},
+		{name: "json_trailing_data", kind: schema.ValidateJSON, value: `{"id": "o-1"} {}`, wantErr: "unexpected data after the value"},
+		{name: "avro_valid", kind: schema.ValidateAvro, schema: avsc, value: `{"id": "o-1", "status": "NEW", "created_at": 1, "lines": []}`},
+		{name: "avro_invalid", kind: schema.ValidateAvro, schema: avsc, value: `{"id": 1, "status": "NEW", "created_at": 1, "lines": []}`, wantErr: `field "id": expected string`},
+		{name: "json_schema_valid", kind: schema.ValidateJSONSchema, schema: jsonSchema, value: `{"id": "o-1", "lines": [{"qty": 2}]}`},
+		{name: "json_schema_invalid", kind: schema.ValidateJSONSchema, schema: jsonSchema, value: `{"id": "o-1", "lines": [{"qty": 2}, {"qty": "2"}]}`, wantErr: `field "lines.1.qty"`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := schema.NewValidator(tc.kind, tc.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = v.Validate([]byte(tc.value))
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("expected error %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+
+	if _, err := schema.NewValidator(schema.ValidateAvro, ""); err == nil {
+		t.Error("expected an error without schema file")
+	}
+	if _, err := schema.NewValidator("xml", ""); err == nil {
+		t.Error("expected an error for an unknown validation")
+	}
+}
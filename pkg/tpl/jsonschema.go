@@ -0,0 +1,564 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxSchemaDepth       = 16
+	defaultMinLength     = 5
+	defaultMaxLength     = 15
+	defaultMinItems      = 1
+	defaultMaxItems      = 5
+	defaultNumberSpread  = 1000
+	templateIndent       = "  "
+	optionalFieldPrefix  = `{{if eq (bool) "true"}}`
+	optionalFieldPostfix = `{{end}}`
+)
+
+// jsonSchema is the subset of JSON Schema (draft-07 and 2020-12) used to generate templates
+type jsonSchema struct {
+	Ref              string                 `json:"$ref"`
+	Type             schemaType             `json:"type"`
+	Enum             []any                  `json:"enum"`
+	Const            any                    `json:"const"`
+	Format           string                 `json:"format"`
+	Pattern          string                 `json:"pattern"`
+	MinLength        *int                   `json:"minLength"`
+	MaxLength        *int                   `json:"maxLength"`
+	Minimum          *float64               `json:"minimum"`
+	Maximum          *float64               `json:"maximum"`
+	ExclusiveMinimum *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum *float64               `json:"exclusiveMaximum"`
+	MinItems         *int                   `json:"minItems"`
+	MaxItems         *int                   `json:"maxItems"`
+	Items            json.RawMessage        `json:"items"`
+	PrefixItems      []*jsonSchema          `json:"prefixItems"`
+	Properties       *schemaProperties      `json:"properties"`
+	Required         []string               `json:"required"`
+	AllOf            []*jsonSchema          `json:"allOf"`
+	AnyOf            []*jsonSchema          `json:"anyOf"`
+	OneOf            []*jsonSchema          `json:"oneOf"`
+	Definitions      map[string]*jsonSchema `json:"definitions"`
+	Defs             map[string]*jsonSchema `json:"$defs"`
+}
+
+// UnmarshalJSON accepts boolean schemas, treating them as an empty schema
+func (s *jsonSchema) UnmarshalJSON(b []byte) error {
+	trimmed := bytes.TrimSpace(b)
+	if bytes.Equal(trimmed, []byte("true")) || bytes.Equal(trimmed, []byte("false")) {
+		*s = jsonSchema{}
+		return nil
+	}
+	type plain jsonSchema
+	return json.Unmarshal(b, (*plain)(s))
+}
+
+// schemaType is the JSON Schema type keyword, which can be a string or a list of strings
+type schemaType string
+
+func (t *schemaType) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*t = schemaType(single)
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(b, &multiple); err != nil {
+		return err
+	}
+	for _, m := range multiple {
+		if m != "null" {
+			*t = schemaType(m)
+			return nil
+		}
+	}
+	if len(multiple) > 0 {
+		*t = schemaType(multiple[0])
+	}
+	return nil
+}
+
+// schemaProperties keeps object properties in the order they are declared in the schema
+type schemaProperties struct {
+	names   []string
+	schemas map[string]*jsonSchema
+}
+
+func (p *schemaProperties) UnmarshalJSON(b []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	p.schemas = make(map[string]*jsonSchema)
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("invalid property name %v", t)
+		}
+		s := &jsonSchema{}
+		if err := dec.Decode(s); err != nil {
+			return err
+		}
+		if _, exists := p.schemas[name]; !exists {
+			p.names = append(p.names, name)
+		}
+		p.schemas[name] = s
+	}
+	_, err := dec.Token()
+	return err
+}
+
+type schemaGenerator struct {
+	root *jsonSchema
+	buf  strings.Builder
+}
+
+// FromJSONSchema generates a JR template producing values valid for the given JSON Schema.
+// Optional object properties are emitted randomly, required ones are always present.
+func FromJSONSchema(schema []byte) (string, error) {
+	root := &jsonSchema{}
+	if err := json.Unmarshal(schema, root); err != nil {
+		return "", fmt.Errorf("invalid JSON schema: %w", err)
+	}
+
+	g := &schemaGenerator{root: root}
+	if err := g.generate(root, 0); err != nil {
+		return "", err
+	}
+	g.buf.WriteString("\n")
+	return g.buf.String(), nil
+}
+
+//gocyclo:ignore
+func (g *schemaGenerator) generate(s *jsonSchema, depth int) error {
+	if depth > maxSchemaDepth {
+		return fmt.Errorf("schema nesting deeper than %d levels, recursive $ref?", maxSchemaDepth)
+	}
+
+	s, err := g.resolve(s)
+	if err != nil {
+		return err
+	}
+
+	if s.Const != nil {
+		return g.writeLiteral(s.Const)
+	}
+	if len(s.Enum) > 0 {
+		return g.writeEnum(s.Enum)
+	}
+
+	switch g.typeOf(s) {
+	case "object":
+		return g.writeObject(s, depth)
+	case "array":
+		return g.writeArray(s, depth)
+	case "integer":
+		return g.writeInteger(s)
+	case "number":
+		return g.writeNumber(s)
+	case "boolean":
+		g.buf.WriteString("{{bool}}")
+	case "null":
+		g.buf.WriteString("null")
+	default:
+		g.writeString(s)
+	}
+	return nil
+}
+
+// resolve follows local $ref and flattens allOf/anyOf/oneOf into a single schema
+func (g *schemaGenerator) resolve(s *jsonSchema) (*jsonSchema, error) {
+	for i := 0; s.Ref != "" && i < maxSchemaDepth; i++ {
+		target, err := g.lookup(s.Ref)
+		if err != nil {
+			return nil, err
+		}
+		s = target
+	}
+
+	alternatives := s.AnyOf
+	if len(alternatives) == 0 {
+		alternatives = s.OneOf
+	}
+	for _, a := range alternatives {
+		resolved, err := g.resolve(a)
+		if err != nil {
+			return nil, err
+		}
+		if resolved.Type != "null" {
+			return resolved, nil
+		}
+	}
+
+	if len(s.AllOf) == 0 {
+		return s, nil
+	}
+
+	merged := *s
+	merged.AllOf = nil
+	merged.Properties = &schemaProperties{schemas: make(map[string]*jsonSchema)}
+	for _, part := range append([]*jsonSchema{s}, s.AllOf...) {
+		if part != s {
+			var err error
+			if part, err = g.resolve(part); err != nil {
+				return nil, err
+			}
+		}
+		if merged.Type == "" {
+			merged.Type = part.Type
+		}
+		if part.Properties != nil {
+			for _, name := range part.Properties.names {
+				if _, exists := merged.Properties.schemas[name]; !exists {
+					merged.Properties.names = append(merged.Properties.names, name)
+				}
+				merged.Properties.schemas[name] = part.Properties.schemas[name]
+			}
+		}
+		if part != s {
+			merged.Required = append(merged.Required, part.Required...)
+		}
+	}
+	return &merged, nil
+}
+
+func (g *schemaGenerator) lookup(ref string) (*jsonSchema, error) {
+	if ref == "#" {
+		return g.root, nil
+	}
+	for _, prefix := range []string{"#/definitions/", "#/$defs/"} {
+		name, found := strings.CutPrefix(ref, prefix)
+		if !found {
+			continue
+		}
+		if s, ok := g.root.Definitions[name]; ok {
+			return s, nil
+		}
+		if s, ok := g.root.Defs[name]; ok {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported or unknown $ref %q", ref)
+}
+
+func (g *schemaGenerator) typeOf(s *jsonSchema) string {
+	switch {
+	case s.Type != "":
+		return string(s.Type)
+	case s.Properties != nil:
+		return "object"
+	case s.Items != nil || s.PrefixItems != nil:
+		return "array"
+	case s.Minimum != nil || s.Maximum != nil || s.ExclusiveMinimum != nil || s.ExclusiveMaximum != nil:
+		return "number"
+	default:
+		return "string"
+	}
+}
+
+func (g *schemaGenerator) writeObject(s *jsonSchema, depth int) error {
+	if s.Properties == nil || len(s.Properties.names) == 0 {
+		g.buf.WriteString("{}")
+		return nil
+	}
+
+	required := make(map[string]bool, len(s.Required))
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	// the anchor is the first property always emitted: optional properties before it
+	// carry a trailing comma, the ones after it a leading comma
+	anchor := 0
+	for i, name := range s.Properties.names {
+		if required[name] {
+			anchor = i
+			break
+		}
+	}
+
+	indent := strings.Repeat(templateIndent, depth+1)
+	g.buf.WriteString("{\n")
+	for i, name := range s.Properties.names {
+		optional := !required[name] && i != anchor
+		if optional {
+			g.buf.WriteString(optionalFieldPrefix)
+		}
+		if i > anchor {
+			g.buf.WriteString(",\n")
+		}
+		g.buf.WriteString(indent)
+		g.buf.WriteString(strconv.Quote(name))
+		g.buf.WriteString(": ")
+		if err := g.generate(s.Properties.schemas[name], depth+1); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if i < anchor {
+			g.buf.WriteString(",\n")
+		}
+		if optional {
+			g.buf.WriteString(optionalFieldPostfix)
+		}
+	}
+	g.buf.WriteString("\n")
+	g.buf.WriteString(strings.Repeat(templateIndent, depth))
+	g.buf.WriteString("}")
+	return nil
+}
+
+func (g *schemaGenerator) writeArray(s *jsonSchema, depth int) error {
+	tuple := s.PrefixItems
+	var items *jsonSchema
+	if len(s.Items) > 0 {
+		if bytes.HasPrefix(bytes.TrimSpace(s.Items), []byte("[")) {
+			if err := json.Unmarshal(s.Items, &tuple); err != nil {
+				return err
+			}
+		} else {
+			items = &jsonSchema{}
+			if err := json.Unmarshal(s.Items, items); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(tuple) > 0 {
+		g.buf.WriteString("[")
+		for i, item := range tuple {
+			if i > 0 {
+				g.buf.WriteString(", ")
+			}
+			if err := g.generate(item, depth+1); err != nil {
+				return err
+			}
+		}
+		g.buf.WriteString("]")
+		return nil
+	}
+
+	if items == nil {
+		items = &jsonSchema{}
+	}
+
+	minItems, maxItems := bounds(s.MinItems, s.MaxItems, defaultMinItems, defaultMaxItems)
+	count := strconv.Itoa(minItems)
+	if maxItems > minItems {
+		count = fmt.Sprintf("(integer %d %d)", minItems, maxItems+1)
+	}
+	g.buf.WriteString("[{{range $i, $e := array ")
+	g.buf.WriteString(count)
+	g.buf.WriteString("}}{{if $i}}, {{end}}")
+	if err := g.generate(items, depth); err != nil {
+		return err
+	}
+	g.buf.WriteString("{{end}}]")
+	return nil
+}
+
+func (g *schemaGenerator) writeInteger(s *jsonSchema) error {
+	lower, upper := numericBounds(s)
+	low := int64(math.Ceil(lower))
+	if s.ExclusiveMinimum != nil && float64(low) == *s.ExclusiveMinimum {
+		low++
+	}
+	high := int64(math.Floor(upper))
+	if s.ExclusiveMaximum != nil && float64(high) == *s.ExclusiveMaximum {
+		high--
+	}
+	if low > high {
+		return fmt.Errorf("no integer between %v and %v", lower, upper)
+	}
+	if low == high {
+		g.buf.WriteString(strconv.FormatInt(low, 10))
+		return nil
+	}
+	fmt.Fprintf(&g.buf, "{{integer64 %d %d}}", low, high+1)
+	return nil
+}
+
+func (g *schemaGenerator) writeNumber(s *jsonSchema) error {
+	lower, upper := numericBounds(s)
+	// floating computes in float32: exclusive bounds move inward by two float32 steps,
+	// so that neither the value nor its printed form can round back to the bound
+	low, high := float32(lower), float32(upper)
+	if s.ExclusiveMinimum != nil {
+		low = math.Nextafter32(math.Nextafter32(low, math.MaxFloat32), math.MaxFloat32)
+	}
+	if s.ExclusiveMaximum != nil {
+		high = math.Nextafter32(math.Nextafter32(high, -math.MaxFloat32), -math.MaxFloat32)
+	}
+	if low > high {
+		return fmt.Errorf("no number between %v and %v", lower, upper)
+	}
+	fmt.Fprintf(&g.buf, "{{floating %s %s}}", formatFloat(float64(low)), formatFloat(float64(high)))
+	return nil
+}
+
+//gocyclo:ignore
+func (g *schemaGenerator) writeString(s *jsonSchema) {
+	if s.Pattern != "" {
+		fmt.Fprintf(&g.buf, `{{regex %s | json_string}}`, quoteTemplateString(s.Pattern))
+		return
+	}
+
+	switch s.Format {
+	case "email", "idn-email":
+		g.buf.WriteString(`"{{email_work}}"`)
+	case "uuid":
+		g.buf.WriteString(`"{{uuid}}"`)
+	case "date":
+		g.buf.WriteString(`"{{past 5}}"`)
+	case "date-time":
+		g.buf.WriteString(`"{{format_timestamp (unix_time_stamp_ms 365) "2006-01-02T15:04:05Z07:00"}}"`)
+	case "time":
+		g.buf.WriteString(`"{{format_timestamp (unix_time_stamp_ms 1) "15:04:05Z07:00"}}"`)
+	case "ipv4":
+		g.buf.WriteString(`"{{ip "10.0.0.0/8"}}"`)
+	case "ipv6":
+		g.buf.WriteString(`"{{ipv6}}"`)
+	case "hostname", "idn-hostname":
+		g.buf.WriteString(`"{{lower (random_string 3 10)}}.{{randoms "com|net|org|io"}}"`)
+	case "uri", "iri", "url":
+		g.buf.WriteString(`"https://www.{{lower (random_string 3 10)}}.{{randoms "com|net|org|io"}}/{{lower (random_string 3 10)}}"`)
+	default:
+		minLength, maxLength := bounds(s.MinLength, s.MaxLength, defaultMinLength, defaultMaxLength)
+		fmt.Fprintf(&g.buf, `"{{random_string %d %d}}"`, minLength, maxLength)
+	}
+}
+
+func (g *schemaGenerator) writeEnum(values []any) error {
+	allStrings := true
+	separatorFree := true
+	encoded := make([]string, len(values))
+	for i, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		encoded[i] = string(b)
+		if str, ok := v.(string); ok {
+			encoded[i] = encoded[i][1 : len(encoded[i])-1]
+			separatorFree = separatorFree && !strings.Contains(str, "|")
+		} else {
+			allStrings = false
+			separatorFree = separatorFree && !strings.Contains(encoded[i], "|")
+		}
+	}
+	if !allStrings {
+		// non string values are kept as raw JSON, strings keep their quotes
+		for i, v := range values {
+			if _, ok := v.(string); ok {
+				encoded[i] = `"` + encoded[i] + `"`
+			}
+		}
+	}
+
+	if len(encoded) == 1 {
+		return g.writeLiteral(values[0])
+	}
+
+	var expr string
+	if separatorFree {
+		expr = fmt.Sprintf("{{randoms %s}}", strconv.Quote(strings.Join(encoded, "|")))
+	} else {
+		expr = fmt.Sprintf(`{{random (split %s "\x1f")}}`, strconv.Quote(strings.Join(encoded, "\x1f")))
+	}
+	if allStrings {
+		expr = `"` + expr + `"`
+	}
+	g.buf.WriteString(expr)
+	return nil
+}
+
+func (g *schemaGenerator) writeLiteral(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	// literal braces would be interpreted as template actions
+	g.buf.WriteString(strings.ReplaceAll(string(b), "{{", `{{"{{"}}`))
+	return nil
+}
+
+func numericBounds(s *jsonSchema) (float64, float64) {
+	lower, upper := s.Minimum, s.Maximum
+	if s.ExclusiveMinimum != nil {
+		lower = s.ExclusiveMinimum
+	}
+	if s.ExclusiveMaximum != nil {
+		upper = s.ExclusiveMaximum
+	}
+	switch {
+	case lower == nil && upper == nil:
+		return 0, defaultNumberSpread
+	case lower == nil && *upper > 0:
+		return 0, *upper
+	case lower == nil:
+		return *upper - defaultNumberSpread, *upper
+	case upper == nil:
+		return *lower, *lower + defaultNumberSpread
+	default:
+		return *lower, *upper
+	}
+}
+
+func bounds(minValue *int, maxValue *int, defaultMin int, defaultMax int) (int, int) {
+	low, high := defaultMin, defaultMax
+	if minValue != nil {
+		low = *minValue
+	}
+	if maxValue != nil {
+		high = *maxValue
+		if minValue == nil && low > high {
+			low = high
+		}
+	} else if high < low {
+		high = low + defaultMax - defaultMin
+	}
+	return low, high
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
+
+func quoteTemplateString(s string) string {
+	if !strings.Contains(s, "`") {
+		return "`" + s + "`"
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,199 @@
+// Copyright © 2024 JR team
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package tpl_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jrnd-io/jr/pkg/constants"
+	"github.com/jrnd-io/jr/pkg/functions"
+	"github.com/jrnd-io/jr/pkg/tpl"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+func TestFromJSONSchema(t *testing.T) {
+	// data files for functions like email are in the repository templates dir
+	constants.JR_SYSTEM_DIR = "../.."
+
+	testCases := []struct {
+		name   string
+		schema string
+	}{
+		{
+			name: "scalars",
+			schema: `{
+				"$schema": "http://json-schema.org/draft-07/schema#",
+				"type": "object",
+				"properties": {
+					"id": {"type": "string", "format": "uuid"},
+					"age": {"type": "integer", "minimum": 18, "maximum": 99},
+					"score": {"type": "number", "exclusiveMinimum": 0, "maximum": 10},
+					"active": {"type": "boolean"},
+					"nickname": {"type": "string", "minLength": 3, "maxLength": 8},
+					"email": {"type": "string", "format": "email"},
+					"since": {"type": "string", "format": "date-time"},
+					"ip": {"type": "string", "format": "ipv4"},
+					"nothing": {"type": "null"}
+				},
+				"required": ["id", "age", "score", "active", "nickname", "email", "since", "ip", "nothing"],
+				"additionalProperties": false
+			}`,
+		},
+		{
+			name: "enum_and_pattern",
+			schema: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"properties": {
+					"color": {"enum": ["red", "green", "blue \"sky\""]},
+					"level": {"enum": [1, 2, "three", null]},
+					"code": {"type": "string", "pattern": "^[A-Z]{3}-\\d{4}$"},
+					"version": {"const": "v1"}
+				},
+				"required": ["color", "level", "code", "version"]
+			}`,
+		},
+		{
+			name: "exclusive_bounds",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"ratio": {"type": "number", "exclusiveMinimum": 1, "exclusiveMaximum": 1.000001},
+					"count": {"type": "integer", "exclusiveMinimum": 1, "exclusiveMaximum": 3}
+				},
+				"required": ["ratio", "count"]
+			}`,
+		},
+		{
+			name: "escaped_pattern",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"code": {"type": "string", "pattern": "^[é\\x01\"\\\\]{8}$"}
+				},
+				"required": ["code"]
+			}`,
+		},
+		{
+			name: "optional_fields",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"a": {"type": "string"},
+					"b": {"type": "integer"},
+					"c": {"type": "string"},
+					"d": {"type": ["number", "null"]}
+				},
+				"required": ["b"],
+				"additionalProperties": false
+			}`,
+		},
+		{
+			name: "no_required_fields",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"a": {"type": "string"},
+					"b": {"type": "integer"}
+				}
+			}`,
+		},
+		{
+			name: "nested_objects_and_arrays",
+			schema: `{
+				"$schema": "https://json-schema.org/draft/2020-12/schema",
+				"type": "object",
+				"$defs": {
+					"address": {
+						"type": "object",
+						"properties": {
+							"city": {"type": "string"},
+							"zip": {"type": "string", "pattern": "^[0-9]{5}$"}
+						},
+						"required": ["city", "zip"]
+					}
+				},
+				"properties": {
+					"home": {"$ref": "#/$defs/address"},
+					"tags": {"type": "array", "items": {"type": "string"}, "minItems": 1, "maxItems": 3},
+					"point": {"type": "array", "prefixItems": [{"type": "number"}, {"type": "number"}]},
+					"orders": {
+						"type": "array",
+						"minItems": 2,
+						"maxItems": 2,
+						"items": {
+							"type": "object",
+							"properties": {
+								"sku": {"type": "string"},
+								"qty": {"type": "integer", "minimum": 1, "maximum": 1}
+							},
+							"required": ["sku", "qty"]
+						}
+					}
+				},
+				"required": ["home", "tags", "point", "orders"]
+			}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			generated, err := tpl.FromJSONSchema([]byte(tc.schema))
+			if err != nil {
+				t.Fatalf("cannot generate template: %v", err)
+			}
+
+			compiler := jsonschema.NewCompiler()
+			if err := compiler.AddResource("schema.json", strings.NewReader(tc.schema)); err != nil {
+				t.Fatalf("invalid test schema: %v", err)
+			}
+			schema, err := compiler.Compile("schema.json")
+			if err != nil {
+				t.Fatalf("invalid test schema: %v", err)
+			}
+
+			template, err := tpl.NewTpl(tc.name, generated, functions.FunctionsMap(), nil)
+			if err != nil {
+				t.Fatalf("invalid template %s: %v", generated, err)
+			}
+
+			for i := 0; i < 50; i++ {
+				value := template.Execute()
+				var v any
+				if err := json.Unmarshal([]byte(value), &v); err != nil {
+					t.Fatalf("invalid JSON %s: %v", value, err)
+				}
+				if err := schema.Validate(v); err != nil {
+					t.Fatalf("%s does not match schema: %v", value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFromJSONSchemaUnknownRef(t *testing.T) {
+	_, err := tpl.FromJSONSchema([]byte(`{"type": "object", "properties": {"a": {"$ref": "#/$defs/missing"}}}`))
+	if err == nil {
+		t.Error("expected an error for an unknown $ref")
+	}
+}